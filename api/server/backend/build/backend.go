@@ -2,33 +2,81 @@ package build
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/dockerfile"
+	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
 
-// ImageComponent provides an interface for working with images
+// ImageComponent provides an interface for working with images and build
+// caches
 type ImageComponent interface {
 	SquashImage(from string, to string) (string, error)
 	TagImageWithReference(image.ID, reference.Named) error
+	BuildCachePrune(ctx context.Context, pruneFilters filters.Args) (*types.BuildCachePruneReport, error)
+	BuildCacheExport(refs []string, source string) (*types.BuildCacheManifest, error)
+	BuildCacheImport(m *types.BuildCacheManifest) error
+	BuildCacheSave(refs []string, outStream io.Writer) error
+	BuildCacheLoad(inTar io.ReadCloser) error
 }
 
 // Backend provides build functionality to the API router
 type Backend struct {
 	manager        *dockerfile.BuildManager
 	imageComponent ImageComponent
+	sessions       *remotecontext.SessionStore
 }
 
 // NewBackend creates a new build backend from components
-func NewBackend(components ImageComponent, builderBackend builder.Backend) *Backend {
-	manager := dockerfile.NewBuildManager(builderBackend)
-	return &Backend{imageComponent: components, manager: manager}
+func NewBackend(components ImageComponent, builderBackend builder.Backend, sessions *remotecontext.SessionStore) *Backend {
+	manager := dockerfile.NewBuildManager(builderBackend, sessions)
+	return &Backend{imageComponent: components, manager: manager, sessions: sessions}
+}
+
+// SessionInspect returns the files of the build context of a session
+func (b *Backend) SessionInspect(id string) (*types.BuildSession, error) {
+	return b.sessions.Get(id)
+}
+
+// SessionRemove removes the build context of a session
+func (b *Backend) SessionRemove(id string) error {
+	return b.sessions.Remove(id)
+}
+
+// CachePrune removes the build caches that are not in use
+func (b *Backend) CachePrune(ctx context.Context, pruneFilters filters.Args) (*types.BuildCachePruneReport, error) {
+	return b.imageComponent.BuildCachePrune(ctx, pruneFilters)
+}
+
+// CacheExport returns the build cache manifest of the steps that created
+// the given images
+func (b *Backend) CacheExport(refs []string, source string) (*types.BuildCacheManifest, error) {
+	return b.imageComponent.BuildCacheExport(refs, source)
+}
+
+// CacheImport adds the entries of a build cache manifest to the build cache
+func (b *Backend) CacheImport(m *types.BuildCacheManifest) error {
+	return b.imageComponent.BuildCacheImport(m)
+}
+
+// CacheSave writes the images of the steps that created the given images
+// to a tarball
+func (b *Backend) CacheSave(refs []string, outStream io.Writer) error {
+	return b.imageComponent.BuildCacheSave(refs, outStream)
+}
+
+// CacheLoad loads the images of a tarball written by CacheSave
+func (b *Backend) CacheLoad(inTar io.ReadCloser) error {
+	return b.imageComponent.BuildCacheLoad(inTar)
 }
 
 // Build builds an image from a Source
@@ -1,7 +1,11 @@
 package build
 
 import (
+	"io"
+
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/filters"
 	"golang.org/x/net/context"
 )
 
@@ -10,6 +14,25 @@ type Backend interface {
 	// Build a Docker image returning the id of the image
 	// TODO: make this return a reference instead of string
 	Build(context.Context, backend.BuildConfig) (string, error)
+
+	// SessionInspect returns the build context kept for a build session
+	SessionInspect(id string) (*types.BuildSession, error)
+	// SessionRemove removes the build context kept for a build session
+	SessionRemove(id string) error
+
+	// CachePrune removes the build caches that are not in use
+	CachePrune(ctx context.Context, pruneFilters filters.Args) (*types.BuildCachePruneReport, error)
+	// CacheExport returns the build cache manifest of the steps that
+	// created the given images
+	CacheExport(refs []string, source string) (*types.BuildCacheManifest, error)
+	// CacheImport adds the entries of a build cache manifest to the build
+	// cache
+	CacheImport(m *types.BuildCacheManifest) error
+	// CacheSave writes the images of the steps that created the given
+	// images to a tarball
+	CacheSave(refs []string, outStream io.Writer) error
+	// CacheLoad loads the images of a tarball written by CacheSave
+	CacheLoad(inTar io.ReadCloser) error
 }
 
 type experimentalProvider interface {
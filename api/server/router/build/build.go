@@ -24,5 +24,10 @@ func (r *buildRouter) Routes() []router.Route {
 func (r *buildRouter) initRoutes() {
 	r.routes = []router.Route{
 		router.NewPostRoute("/build", r.postBuild, router.WithCancel),
+		router.NewGetRoute("/build/sessions/{id:.*}", r.getBuildSession),
+		router.NewDeleteRoute("/build/sessions/{id:.*}", r.deleteBuildSession),
+		router.NewPostRoute("/build/prune", r.postPrune, router.WithCancel),
+		router.NewGetRoute("/build/cache/export", r.getCacheExport),
+		router.NewPostRoute("/build/cache/import", r.postCacheImport),
 	}
 }
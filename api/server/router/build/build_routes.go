@@ -13,11 +13,13 @@ import (
 	"sync"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api"
 	apierrors "github.com/docker/docker/api/errors"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
@@ -60,6 +62,8 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 	options.Squash = httputils.BoolValue(r, "squash")
 	options.Target = r.FormValue("target")
 	options.RemoteContext = r.FormValue("remote")
+	options.SessionID = r.FormValue("session")
+	options.StructuredProgress = httputils.BoolValue(r, "structuredprogress")
 
 	if r.Form.Get("shmsize") != "" {
 		shmSize, err := strconv.ParseInt(r.Form.Get("shmsize"), 10, 64)
@@ -199,6 +203,98 @@ func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *
 	return nil
 }
 
+func (br *buildRouter) getBuildSession(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	session, err := br.backend.SessionInspect(vars["id"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, session)
+}
+
+func (br *buildRouter) deleteBuildSession(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := br.backend.SessionRemove(vars["id"]); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (br *buildRouter) postPrune(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	pruneFilters, err := filters.FromParam(r.Form.Get("filters"))
+	if err != nil {
+		return err
+	}
+
+	pruneReport, err := br.backend.CachePrune(ctx, pruneFilters)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, pruneReport)
+}
+
+func (br *buildRouter) getCacheExport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	if len(r.Form["image"]) == 0 {
+		return apierrors.NewBadRequestError(errors.New("at least one image is required"))
+	}
+
+	switch r.Form.Get("format") {
+	case "", "json":
+	case "tar":
+		if r.Form.Get("source") != "" {
+			return apierrors.NewBadRequestError(errors.New("the source cannot be set when exporting a tarball"))
+		}
+		w.Header().Set("Content-Type", "application/x-tar")
+
+		output := ioutils.NewWriteFlusher(w)
+		defer output.Close()
+		if err := br.backend.CacheSave(r.Form["image"], output); err != nil {
+			if !output.Flushed() {
+				return err
+			}
+			output.Write(streamformatter.FormatError(err))
+		}
+		return nil
+	default:
+		return apierrors.NewBadRequestError(fmt.Errorf("invalid format %q: must be \"json\" or \"tar\"", r.Form.Get("format")))
+	}
+
+	manifest, err := br.backend.CacheExport(r.Form["image"], r.Form.Get("source"))
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, manifest)
+}
+
+func (br *buildRouter) postCacheImport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if api.MatchesContentType(r.Header.Get("Content-Type"), "application/x-tar") {
+		if err := br.backend.CacheLoad(r.Body); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var manifest types.BuildCacheManifest
+	if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+		return apierrors.NewBadRequestError(err)
+	}
+	if err := br.backend.CacheImport(&manifest); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func getAuthConfigs(header http.Header) map[string]types.AuthConfig {
 	authConfigs := map[string]types.AuthConfig{}
 	authConfigsEncoded := header.Get("X-Registry-Config")
@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/errors"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/versions"
@@ -82,6 +83,12 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 		return err
 	}
 
+	// the exit code frame can't be told apart from the output of a tty
+	exitCodeTrailer := execStartCheck.ExitCodeTrailer && !execStartCheck.Detach
+	if exitCodeTrailer && execStartCheck.Tty {
+		return errors.NewBadRequestError(fmt.Errorf("the exit code trailer is not supported with a tty"))
+	}
+
 	if !execStartCheck.Detach {
 		var err error
 		// Setting up the streaming http interface.
@@ -119,6 +126,16 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 		}
 		stdout.Write([]byte(err.Error() + "\r\n"))
 		logrus.Errorf("Error running exec in container: %v", err)
+		return nil
+	}
+
+	if exitCodeTrailer {
+		ec, err := s.backend.ContainerExecInspect(execName)
+		if err != nil || ec.ExitCode == nil {
+			logrus.Debugf("No exit code to send for exec %s: %v", execName, err)
+			return nil
+		}
+		stdcopy.NewStdWriter(outStream, stdcopy.ExitCode).Write([]byte(strconv.Itoa(*ec.ExitCode)))
 	}
 	return nil
 }
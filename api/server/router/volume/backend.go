@@ -1,6 +1,8 @@
 package volume
 
 import (
+	"io"
+
 	"golang.org/x/net/context"
 
 	// TODO return types need to be refactored into pkg
@@ -14,6 +16,9 @@ type Backend interface {
 	Volumes(filter string) ([]*types.Volume, []string, error)
 	VolumeInspect(name string) (*types.Volume, error)
 	VolumeCreate(name, driverName string, opts, labels map[string]string) (*types.Volume, error)
+	VolumeCreateFrom(from, name, driverName string, opts, labels map[string]string) (*types.Volume, error)
 	VolumeRm(name string, force bool) error
 	VolumesPrune(ctx context.Context, pruneFilters filters.Args) (*types.VolumesPruneReport, error)
+	VolumeExport(name string, out io.Writer) error
+	VolumeImport(name string, force bool, content io.Reader) error
 }
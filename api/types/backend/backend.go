@@ -90,6 +90,7 @@ type ExecProcessConfig struct {
 	Arguments  []string `json:"arguments"`
 	Privileged *bool    `json:"privileged,omitempty"`
 	User       string   `json:"user,omitempty"`
+	WorkingDir string   `json:"workingDir,omitempty"`
 }
 
 // ContainerCommitConfig is a wrapper around
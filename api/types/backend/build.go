@@ -23,6 +23,12 @@ type BuildConfig struct {
 	Options        *types.ImageBuildOptions
 }
 
+// ImageCacheOptions are the options supported by MakeImageCache
+type ImageCacheOptions struct {
+	CacheFrom  []string
+	AuthConfig map[string]types.AuthConfig
+}
+
 // GetImageAndLayerOptions are the options supported by GetImageAndReleasableLayer
 type GetImageAndLayerOptions struct {
 	ForcePull  bool
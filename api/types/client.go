@@ -178,6 +178,12 @@ type ImageBuildOptions struct {
 	SecurityOpt []string
 	ExtraHosts  []string // List of extra hosts
 	Target      string
+	// SessionID identifies a build session. When set, Context is a diff
+	// applied to the context of the previous build of the session.
+	SessionID string
+	// StructuredProgress makes the builder emit a BuildStep aux message
+	// when a step starts and ends.
+	StructuredProgress bool
 }
 
 // ImageBuildResponse holds information
@@ -243,6 +249,13 @@ type ImageRemoveOptions struct {
 	PruneChildren bool
 }
 
+// ImageSaveOptions holds parameters to save images with.
+type ImageSaveOptions struct {
+	// Format is the format of the archive, "docker", the default, or "oci"
+	// for an OCI image layout
+	Format string
+}
+
 // ImageSearchOptions holds parameters to search images with.
 type ImageSearchOptions struct {
 	RegistryAuth  string
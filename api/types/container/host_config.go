@@ -229,6 +229,10 @@ type DeviceMapping struct {
 type RestartPolicy struct {
 	Name              string
 	MaximumRetryCount int
+	// UnhealthyThreshold is the number of consecutive unhealthy health
+	// check results after which the container is restarted, regardless of
+	// Name. 0 disables restarting unhealthy containers.
+	UnhealthyThreshold int `json:",omitempty"`
 }
 
 // IsNone indicates whether the container has the "no" restart policy.
@@ -258,7 +262,7 @@ func (rp *RestartPolicy) IsUnlessStopped() bool {
 
 // IsSame compares two RestartPolicy to see if they are the same
 func (rp *RestartPolicy) IsSame(tp *RestartPolicy) bool {
-	return rp.Name == tp.Name && rp.MaximumRetryCount == tp.MaximumRetryCount
+	return rp.Name == tp.Name && rp.MaximumRetryCount == tp.MaximumRetryCount && rp.UnhealthyThreshold == tp.UnhealthyThreshold
 }
 
 // LogMode is a type to define the available modes for logging
@@ -351,6 +355,7 @@ type HostConfig struct {
 	IpcMode         IpcMode           // IPC namespace to use for the container
 	Cgroup          CgroupSpec        // Cgroup to use for the container
 	Links           []string          // List of links (in the name:alias form)
+	WaitHealthy     bool              `json:",omitempty"` // Wait for the linked containers to be healthy when starting
 	OomScoreAdj     int               // Container preference for OOM-killing
 	PidMode         PidMode           // PID namespace to use for the container
 	Privileged      bool              // Is the container in privileged mode
@@ -19,6 +19,9 @@ const (
 	NodeEventType = "node"
 	// SecretEventType is the event type that secrets generate
 	SecretEventType = "secret"
+	// AuthzEventType is the event type that the decisions of the
+	// authorization policy generate
+	AuthzEventType = "authz"
 )
 
 // Actor describes something that generates events,
@@ -0,0 +1,25 @@
+package image
+
+// ----------------------------------------------------------------------------
+// DO NOT EDIT THIS FILE
+// This file was generated by `swagger generate operation`
+//
+// See hack/generate-swagger-api.sh
+// ----------------------------------------------------------------------------
+
+// DiffResponseItem diff response item
+// swagger:model DiffResponseItem
+type DiffResponseItem struct {
+
+	// Kind of change
+	// Required: true
+	Kind uint8 `json:"Kind"`
+
+	// Path to file that has changed
+	// Required: true
+	Path string `json:"Path"`
+
+	// Size of the file in bytes, in the image for added and modified files, and in the base image for deleted ones. Only regular files have a size.
+	// Required: true
+	Size int64 `json:"Size"`
+}
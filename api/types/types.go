@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -47,6 +48,13 @@ type ImageInspect struct {
 	RootFS          RootFS
 }
 
+// DiskQuota holds the size limit of a container's writable layer, set with
+// the size storage option, and the space used against it.
+type DiskQuota struct {
+	Limit int64
+	Used  int64
+}
+
 // Container contains response of Engine API:
 // GET "/containers/json"
 type Container struct {
@@ -57,8 +65,9 @@ type Container struct {
 	Command    string
 	Created    int64
 	Ports      []Port
-	SizeRw     int64 `json:",omitempty"`
-	SizeRootFs int64 `json:",omitempty"`
+	SizeRw     int64      `json:",omitempty"`
+	SizeRootFs int64      `json:",omitempty"`
+	DiskQuota  *DiskQuota `json:",omitempty"`
 	Labels     map[string]string
 	State      string
 	Status     string
@@ -75,6 +84,22 @@ type CopyConfig struct {
 	Resource string
 }
 
+// BuildSession describes the build context kept by the daemon for a build
+// session.
+type BuildSession struct {
+	ID       string
+	LastUsed time.Time
+	Files    []BuildSessionFile
+}
+
+// BuildSessionFile describes a file of the build context of a build session.
+type BuildSessionFile struct {
+	Path    string
+	Mode    os.FileMode
+	Size    int64
+	ModTime time.Time
+}
+
 // ContainerPathStat is used to encode the header from
 // GET "/containers/{name:.*}/archive"
 // "Name" is the file or directory name.
@@ -249,6 +274,10 @@ type ExecStartCheck struct {
 	Detach bool
 	// Check if there's a tty
 	Tty bool
+	// ExitCodeTrailer requests the exit code of the exec to be sent in a
+	// stdcopy.ExitCode frame at the end of the stream. It is only
+	// supported without a tty.
+	ExitCodeTrailer bool
 }
 
 // HealthcheckResult stores information about a single run of a healthcheck probe
@@ -479,6 +508,26 @@ type Runtime struct {
 	Args []string `json:"runtimeArgs,omitempty"`
 }
 
+// EventSink describes an endpoint the daemon forwards its events to
+type EventSink struct {
+	// Type is the type of the sink, either "webhook" or "unix"
+	Type string `json:"type"`
+	// Address is the URL of a webhook, or the path of the unix socket the
+	// events are streamed on
+	Address string `json:"address"`
+	// Filters selects the events forwarded to the sink, like the filters of
+	// the events endpoint
+	Filters map[string][]string `json:"filters,omitempty"`
+	// BatchSize is the maximum number of events sent to a webhook at once
+	BatchSize int `json:"batch-size,omitempty"`
+	// BatchTimeout is how long events are batched for before being sent to
+	// a webhook, as a duration string
+	BatchTimeout string `json:"batch-timeout,omitempty"`
+	// MaxRetries is the number of times sending events to a webhook is
+	// retried before they are dropped
+	MaxRetries *int `json:"max-retries,omitempty"`
+}
+
 // DiskUsage contains response of Engine API:
 // GET "/system/df"
 type DiskUsage struct {
@@ -509,6 +558,13 @@ type ImagesPruneReport struct {
 	SpaceReclaimed uint64
 }
 
+// BuildCachePruneReport contains the response for Engine API:
+// POST "/build/prune"
+type BuildCachePruneReport struct {
+	CachesDeleted  []string
+	SpaceReclaimed uint64
+}
+
 // NetworksPruneReport contains the response for Engine API:
 // POST "/networks/prune"
 type NetworksPruneReport struct {
@@ -552,3 +608,72 @@ type PushResult struct {
 type BuildResult struct {
 	ID string
 }
+
+// BuildCacheManifest records the images resulting from build steps, so that
+// the build cache can be reused by another daemon. It is the response for
+// Engine API: GET "/build/cache/export", and the body of POST
+// "/build/cache/import".
+type BuildCacheManifest struct {
+	Entries []BuildCacheManifestEntry
+}
+
+// BuildCacheManifestEntry is the image resulting from a build step.
+type BuildCacheManifestEntry struct {
+	// Parent is the ID of the image the step was applied to, empty for
+	// `FROM scratch`
+	Parent string `json:",omitempty"`
+	// Config is the configuration of the resulting image, the ID of the
+	// image is its digest
+	Config json.RawMessage
+	// Source is a reference of an image, in a registry, containing the
+	// layers of the resulting image. The layers that are not available are
+	// pulled from its repository when the entry is used.
+	Source string `json:",omitempty"`
+	// Layers are the blobs, in the repository of Source, of the layers of
+	// the resulting image, from the bottom-most one
+	Layers []BuildCacheLayer `json:",omitempty"`
+}
+
+// BuildCacheLayer is the blob of a layer of the image resulting from a build
+// step.
+type BuildCacheLayer struct {
+	// MediaType is the media type of the blob, empty for gzip compressed
+	// layers
+	MediaType string `json:",omitempty"`
+	Digest    string
+}
+
+// Status of a BuildStep
+const (
+	BuildStepStarted  = "started"
+	BuildStepFinished = "finished"
+	BuildStepFailed   = "failed"
+)
+
+// Cache results of a BuildStep
+const (
+	BuildCacheHit  = "hit"
+	BuildCacheMiss = "miss"
+)
+
+// BuildStep is a progress record of a build step. A record is emitted, as
+// an aux message, when a step starts and when it ends if the
+// StructuredProgress option of the build is set.
+type BuildStep struct {
+	// Step is the number of the step, starting at 1
+	Step  int
+	Total int
+	// Instruction is the Dockerfile instruction of the step
+	Instruction string
+	// Vertex is the digest identifying the instruction and the image it
+	// is applied to
+	Vertex string
+	Status string
+	// Cache is the result of the cache lookup of the step, empty if the
+	// step does not use the build cache
+	Cache    string        `json:",omitempty"`
+	Duration time.Duration `json:",omitempty"`
+	// ImageID is the image resulting from the step
+	ImageID string `json:",omitempty"`
+	Error   string `json:",omitempty"`
+}
@@ -19,6 +19,9 @@ type VolumesCreateBody struct {
 	// Required: true
 	DriverOpts map[string]string `json:"DriverOpts"`
 
+	// Name of an existing volume to copy the content of into the new volume. The volume is created with the driver of the copied volume if `Driver` is not specified.
+	From string `json:"From,omitempty"`
+
 	// User-defined key/value metadata.
 	// Required: true
 	Labels map[string]string `json:"Labels"`
@@ -46,7 +46,14 @@ type Backend interface {
 	// specified by a container object.
 	// TODO: extract in the builder instead of passing `decompress`
 	// TODO: use containerd/fs.changestream instead as a source
-	CopyOnBuild(containerID string, destPath string, srcRoot string, srcPath string, decompress bool) error
+	// chownStr is an optional user[:group] to own the copied files, resolved
+	// against the container's own passwd and group files.
+	CopyOnBuild(containerID string, destPath string, srcRoot string, srcPath string, decompress bool, chownStr string) error
+
+	// BuildCacheDir returns the path of the persistent build cache
+	// directory with the given ID, creating it if needed. The directory is
+	// not pruned until the returned function is called.
+	BuildCacheDir(id string) (string, func(), error)
 
 	ImageCacheBuilder
 }
@@ -67,7 +74,7 @@ type ExecBackend interface {
 	// ContainerKill stops the container execution abruptly.
 	ContainerKill(containerID string, sig uint64) error
 	// ContainerStart starts a new container
-	ContainerStart(containerID string, hostConfig *container.HostConfig, checkpoint string, checkpointDir string) error
+	ContainerStart(ctx context.Context, containerID string, hostConfig *container.HostConfig, checkpoint string, checkpointDir string) error
 	// ContainerWait stops processing until the given container is stopped.
 	ContainerWait(ctx context.Context, name string, condition containerpkg.WaitCondition) (<-chan containerpkg.StateStatus, error)
 }
@@ -80,8 +87,9 @@ type Result struct {
 
 // ImageCacheBuilder represents a generator for stateful image cache.
 type ImageCacheBuilder interface {
-	// MakeImageCache creates a stateful image cache.
-	MakeImageCache(cacheFrom []string) ImageCache
+	// MakeImageCache creates a stateful image cache. The context cancels
+	// the pulls done by the cache to use imported build cache entries.
+	MakeImageCache(ctx context.Context, opts backend.ImageCacheOptions) ImageCache
 }
 
 // ImageCache abstracts an image cache.
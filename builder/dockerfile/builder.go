@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api/types"
@@ -17,6 +18,7 @@ import (
 	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/stringid"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"golang.org/x/sync/syncmap"
@@ -39,13 +41,15 @@ var validCommitCommands = map[string]bool{
 type BuildManager struct {
 	backend   builder.Backend
 	pathCache pathCache // TODO: make this persistent
+	sessions  *remotecontext.SessionStore
 }
 
 // NewBuildManager creates a BuildManager
-func NewBuildManager(b builder.Backend) *BuildManager {
+func NewBuildManager(b builder.Backend, sessions *remotecontext.SessionStore) *BuildManager {
 	return &BuildManager{
 		backend:   b,
 		pathCache: &syncmap.Map{},
+		sessions:  sessions,
 	}
 }
 
@@ -56,7 +60,16 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 		config.Options.Dockerfile = builder.DefaultDockerfileName
 	}
 
-	source, dockerfile, err := remotecontext.Detect(config)
+	var (
+		source     builder.Source
+		dockerfile *parser.Result
+		err        error
+	)
+	if config.Options.SessionID != "" {
+		source, dockerfile, err = bm.sessions.Detect(config)
+	} else {
+		source, dockerfile, err = remotecontext.Detect(config)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +118,9 @@ type Builder struct {
 	pathCache        pathCache
 	containerManager *containerManager
 	imageProber      ImageProber
+
+	// cacheResult is the result of the cache lookup of the current step
+	cacheResult string
 }
 
 // newBuilder creates a new Dockerfile builder from an optional dockerfile and a Options.
@@ -113,6 +129,7 @@ func newBuilder(clientCtx context.Context, options builderOptions) *Builder {
 	if config == nil {
 		config = new(types.ImageBuildOptions)
 	}
+	cacheOptions := backend.ImageCacheOptions{CacheFrom: config.CacheFrom, AuthConfig: config.AuthConfigs}
 	b := &Builder{
 		clientCtx:        clientCtx,
 		options:          config,
@@ -125,7 +142,7 @@ func newBuilder(clientCtx context.Context, options builderOptions) *Builder {
 		buildStages:      newBuildStages(),
 		imageSources:     newImageSources(clientCtx, options),
 		pathCache:        options.PathCache,
-		imageProber:      newImageProber(options.Backend, config.CacheFrom, config.NoCache),
+		imageProber:      newImageProber(clientCtx, options.Backend, cacheOptions, config.NoCache),
 		containerManager: newContainerManager(options.Backend),
 	}
 	return b
@@ -169,11 +186,58 @@ func emitImageID(aux *streamformatter.AuxFormatter, state *dispatchState) error
 	return aux.Emit(types.BuildResult{ID: state.imageID})
 }
 
+// stepProgress is the progress of the build step being dispatched.
+type stepProgress struct {
+	types.BuildStep
+	start time.Time
+}
+
+// startStep emits the progress record of the start of a step, if the
+// client asked for structured progress.
+func (b *Builder) startStep(i, total int, n *parser.Node, state *dispatchState) (*stepProgress, error) {
+	if !b.options.StructuredProgress || b.Aux == nil {
+		return nil, nil
+	}
+	b.cacheResult = ""
+
+	// the image a FROM instruction is applied to is not relevant
+	parent := state.imageID
+	if n.Value == command.From {
+		parent = ""
+	}
+	step := &stepProgress{
+		BuildStep: types.BuildStep{
+			Step:        i + 1,
+			Total:       total,
+			Instruction: n.Original,
+			Vertex:      digest.FromString(parent + "\n" + n.Original).String(),
+			Status:      types.BuildStepStarted,
+		},
+		start: time.Now(),
+	}
+	return step, b.Aux.Emit(step.BuildStep)
+}
+
+// endStep emits the progress record of the end of a step.
+func (b *Builder) endStep(step *stepProgress, imageID string, stepErr error) error {
+	if step == nil {
+		return nil
+	}
+	step.Status = types.BuildStepFinished
+	step.Cache = b.cacheResult
+	step.Duration = time.Since(step.start)
+	step.ImageID = imageID
+	if stepErr != nil {
+		step.Status = types.BuildStepFailed
+		step.Error = stepErr.Error()
+	}
+	return b.Aux.Emit(step.BuildStep)
+}
+
 func (b *Builder) dispatchDockerfileWithCancellation(dockerfile *parser.Result, source builder.Source) (*dispatchState, error) {
 	shlex := NewShellLex(dockerfile.EscapeToken)
 	state := newDispatchState()
 	total := len(dockerfile.AST.Children)
-	var err error
 	for i, n := range dockerfile.AST.Children {
 		select {
 		case <-b.clientCtx.Done():
@@ -205,12 +269,20 @@ func (b *Builder) dispatchDockerfileWithCancellation(dockerfile *parser.Result,
 			shlex:   shlex,
 			source:  source,
 		}
+		step, err := b.startStep(i, total, n, state)
+		if err != nil {
+			return nil, err
+		}
 		if state, err = b.dispatch(opts); err != nil {
+			b.endStep(step, "", err)
 			if b.options.ForceRemove {
 				b.containerManager.RemoveAll(b.Stdout)
 			}
 			return nil, err
 		}
+		if err := b.endStep(step, state.imageID, nil); err != nil {
+			return nil, err
+		}
 
 		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(state.imageID))
 		if b.options.Remove {
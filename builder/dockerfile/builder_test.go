@@ -1,11 +1,22 @@
 package dockerfile
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestAddNodesForLabelOption(t *testing.T) {
@@ -32,3 +43,57 @@ func TestAddNodesForLabelOption(t *testing.T) {
 		assert.Equal(t, expected[i], v.Original)
 	}
 }
+
+func TestStructuredProgress(t *testing.T) {
+	result, err := parser.Parse(strings.NewReader("FROM busybox\nLABEL foo=bar"))
+	require.NoError(t, err)
+
+	mockBackend := &MockBackend{
+		makeImageCacheFunc: func(_ []string) builder.ImageCache {
+			return &mockImageCache{getCacheFunc: func(parentID string, cfg *container.Config) (string, error) {
+				return "cachedid", nil
+			}}
+		},
+	}
+	out := new(bytes.Buffer)
+	b := newBuilderWithMockBackend()
+	b.docker = mockBackend
+	b.imageProber = newImageProber(context.Background(), mockBackend, backend.ImageCacheOptions{}, false)
+	b.disableCommit = false
+	b.options.StructuredProgress = true
+	b.Aux = &streamformatter.AuxFormatter{Writer: out}
+
+	_, err = b.dispatchDockerfileWithCancellation(result, nil)
+	require.NoError(t, err)
+
+	var steps []types.BuildStep
+	dec := json.NewDecoder(out)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		var step types.BuildStep
+		require.NoError(t, json.Unmarshal(*msg.Aux, &step))
+		if step.Status != "" {
+			steps = append(steps, step)
+		}
+	}
+
+	require.Len(t, steps, 4)
+	assert.Equal(t, types.BuildStepStarted, steps[0].Status)
+	assert.Equal(t, "FROM busybox", steps[0].Instruction)
+	assert.Equal(t, types.BuildStepFinished, steps[1].Status)
+	assert.Equal(t, "", steps[1].Cache)
+	assert.Equal(t, "theid", steps[1].ImageID)
+	assert.Equal(t, steps[0].Vertex, steps[1].Vertex)
+
+	assert.Equal(t, 2, steps[3].Step)
+	assert.Equal(t, 2, steps[3].Total)
+	assert.Equal(t, types.BuildStepFinished, steps[3].Status)
+	assert.Equal(t, types.BuildCacheHit, steps[3].Cache)
+	assert.Equal(t, "cachedid", steps[3].ImageID)
+	assert.NotEqual(t, steps[1].Vertex, steps[3].Vertex)
+}
@@ -134,7 +134,7 @@ func label(req dispatchRequest) error {
 	return req.builder.commit(req.state, commitStr)
 }
 
-// ADD foo /path
+// ADD [--chown=user:group] foo /path
 //
 // Add the file 'foo' to '/path'. Tarball and Remote URL (git, http) handling
 // exist here. If you do not wish to have this automatic handling, use COPY.
@@ -144,6 +144,7 @@ func add(req dispatchRequest) error {
 		return errAtLeastTwoArguments("ADD")
 	}
 
+	flChown := req.flags.AddString("chown", "")
 	if err := req.flags.Parse(); err != nil {
 		return err
 	}
@@ -155,12 +156,13 @@ func add(req dispatchRequest) error {
 	if err != nil {
 		return err
 	}
+	copyInstruction.chownStr = flChown.Value
 	copyInstruction.allowLocalDecompression = true
 
 	return req.builder.performCopy(req.state, copyInstruction)
 }
 
-// COPY foo /path
+// COPY [--chown=user:group] foo /path
 //
 // Same as 'ADD' but without the tar and remote url handling.
 //
@@ -170,6 +172,7 @@ func dispatchCopy(req dispatchRequest) error {
 	}
 
 	flFrom := req.flags.AddString("from", "")
+	flChown := req.flags.AddString("chown", "")
 	if err := req.flags.Parse(); err != nil {
 		return err
 	}
@@ -185,6 +188,7 @@ func dispatchCopy(req dispatchRequest) error {
 	if err != nil {
 		return err
 	}
+	copyInstruction.chownStr = flChown.Value
 
 	return req.builder.performCopy(req.state, copyInstruction)
 }
@@ -424,9 +428,14 @@ func run(req dispatchRequest) error {
 		return errors.New("Please provide a source image with `from` prior to run")
 	}
 
+	flMounts := req.flags.AddStrings("mount")
 	if err := req.flags.Parse(); err != nil {
 		return err
 	}
+	runMounts, err := parseRunMounts(flMounts.StringValues)
+	if err != nil {
+		return err
+	}
 
 	stateRunConfig := req.state.runConfig
 	args := handleJSONArgs(req.args, req.attributes)
@@ -440,6 +449,9 @@ func run(req dispatchRequest) error {
 	if len(buildArgs) > 0 {
 		saveCmd = prependEnvOnCmd(req.builder.buildArgs, buildArgs, cmdFromArgs)
 	}
+	if len(runMounts) > 0 {
+		saveCmd = prependMountsOnCmd(runMounts, saveCmd)
+	}
 
 	runConfigForCacheProbe := copyRunConfig(stateRunConfig,
 		withCmd(saveCmd),
@@ -457,8 +469,15 @@ func run(req dispatchRequest) error {
 	// set config as already being escaped, this prevents double escaping on windows
 	runConfig.ArgsEscaped = true
 
+	mounts, releaseMounts, err := req.builder.cacheMounts(runMounts)
+	if err != nil {
+		return err
+	}
+
 	logrus.Debugf("[BUILDER] Command to be executed: %v", runConfig.Cmd)
-	cID, err := req.builder.create(runConfig)
+	cID, err := req.builder.create(runConfig, mounts)
+	// the build caches are not pruned once mounted by the container
+	releaseMounts()
 	if err != nil {
 		return err
 	}
@@ -503,6 +522,20 @@ func prependEnvOnCmd(buildArgs *buildArgs, buildArgVars []string, cmd strslice.S
 	return strslice.StrSlice(append(tmpEnv, cmd...))
 }
 
+// Derive the command to use for probeCache() and to commit in this container
+// when build caches are mounted. The normalized spec of each mount is
+// prepended as a "|mount=<spec>" argument, so that changing the mounts of a
+// RUN command invalidates its cache. Like the build-time env vars, these
+// arguments start with a vertical bar and cannot conflict with a command,
+// nor with the "|#" argument of the env vars.
+func prependMountsOnCmd(runMounts []runMount, cmd strslice.StrSlice) strslice.StrSlice {
+	var tmpMounts []string
+	for _, m := range runMounts {
+		tmpMounts = append(tmpMounts, "|mount="+m.String())
+	}
+	return strslice.StrSlice(append(tmpMounts, cmd...))
+}
+
 // CMD foo
 //
 // Set the default command to run in the container (which may be empty).
@@ -602,8 +635,23 @@ func healthcheck(req dispatchRequest) error {
 			}
 
 			healthcheck.Test = strslice.StrSlice(append([]string{typ}, cmdSlice...))
+		case "HTTP", "TCP":
+			argSlice := handleJSONArgs(args, req.attributes)
+			if len(argSlice) != 1 || len(strings.Fields(argSlice[0])) != 1 {
+				return fmt.Errorf("HEALTHCHECK %s requires exactly one argument", typ)
+			}
+			if typ == "HTTP" && !strings.HasPrefix(argSlice[0], "/") && !strings.HasPrefix(argSlice[0], ":") {
+				return fmt.Errorf("HEALTHCHECK HTTP requires a path, optionally prefixed with :<port>, not %q", argSlice[0])
+			}
+			if typ == "TCP" {
+				if port, err := strconv.Atoi(argSlice[0]); err != nil || port < 1 || port > 65535 {
+					return fmt.Errorf("HEALTHCHECK TCP requires a port number, not %q", argSlice[0])
+				}
+			}
+
+			healthcheck.Test = strslice.StrSlice{typ, argSlice[0]}
 		default:
-			return fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD)", typ)
+			return fmt.Errorf("Unknown type %#v in HEALTHCHECK (try CMD, HTTP or TCP)", typ)
 		}
 
 		interval, err := parseOptInterval(flInterval)
@@ -63,7 +63,7 @@ func newBuilderWithMockBackend() *Builder {
 			Backend: mockBackend,
 		}),
 		buildStages:      newBuildStages(),
-		imageProber:      newImageProber(mockBackend, nil, false),
+		imageProber:      newImageProber(ctx, mockBackend, backend.ImageCacheOptions{}, false),
 		containerManager: newContainerManager(mockBackend),
 	}
 	return b
@@ -340,6 +340,31 @@ func TestHealthcheckCmd(t *testing.T) {
 	assert.Equal(t, expectedTest, req.state.runConfig.Healthcheck.Test)
 }
 
+func TestHealthcheckHTTPAndTCP(t *testing.T) {
+	b := newBuilderWithMockBackend()
+
+	req := defaultDispatchReq(b, "HTTP", ":8080/health")
+	require.NoError(t, healthcheck(req))
+	require.NotNil(t, req.state.runConfig.Healthcheck)
+	assert.Equal(t, []string{"HTTP", ":8080/health"}, req.state.runConfig.Healthcheck.Test)
+
+	req = defaultDispatchReq(b, "TCP", "6379")
+	require.NoError(t, healthcheck(req))
+	require.NotNil(t, req.state.runConfig.Healthcheck)
+	assert.Equal(t, []string{"TCP", "6379"}, req.state.runConfig.Healthcheck.Test)
+
+	for _, args := range [][]string{
+		{"HTTP"},
+		{"HTTP", "health"},
+		{"HTTP", "/a /b"},
+		{"TCP", "redis"},
+		{"TCP", "70000"},
+	} {
+		err := healthcheck(defaultDispatchReq(b, args...))
+		assert.Error(t, err, "%v", args)
+	}
+}
+
 func TestEntrypoint(t *testing.T) {
 	b := newBuilderWithMockBackend()
 	entrypointCmd := "/usr/sbin/nginx"
@@ -462,6 +487,15 @@ func TestPrependEnvOnCmd(t *testing.T) {
 	assert.Equal(t, expected, cmdWithEnv)
 }
 
+func TestPrependMountsOnCmd(t *testing.T) {
+	runMounts := []runMount{{id: "apt", target: "/var/cache/apt"}}
+	cmd := []string{"|1", "one=two", "foo", "bar"}
+	cmdWithMounts := prependMountsOnCmd(runMounts, cmd)
+	expected := strslice.StrSlice([]string{
+		"|mount=type=cache,id=apt,target=/var/cache/apt", "|1", "one=two", "foo", "bar"})
+	assert.Equal(t, expected, cmdWithMounts)
+}
+
 func TestRunWithBuildArgs(t *testing.T) {
 	b := newBuilderWithMockBackend()
 	b.buildArgs.argsFromOptions["HTTP_PROXY"] = strPtr("FOO")
@@ -486,7 +520,7 @@ func TestRunWithBuildArgs(t *testing.T) {
 	mockBackend.makeImageCacheFunc = func(_ []string) builder.ImageCache {
 		return imageCache
 	}
-	b.imageProber = newImageProber(mockBackend, nil, false)
+	b.imageProber = newImageProber(context.Background(), mockBackend, backend.ImageCacheOptions{}, false)
 	mockBackend.getImageFunc = func(_ string) (builder.Image, builder.ReleaseableLayer, error) {
 		return &mockImage{
 			id:     "abcdef",
@@ -2,8 +2,10 @@ package dockerfile
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/builder"
+	"golang.org/x/net/context"
 )
 
 // ImageProber exposes an Image cache to the Builder. It supports resetting a
@@ -19,13 +21,13 @@ type imageProber struct {
 	cacheBusted bool
 }
 
-func newImageProber(cacheBuilder builder.ImageCacheBuilder, cacheFrom []string, noCache bool) ImageProber {
+func newImageProber(ctx context.Context, cacheBuilder builder.ImageCacheBuilder, opts backend.ImageCacheOptions, noCache bool) ImageProber {
 	if noCache {
 		return &nopProber{}
 	}
 
 	reset := func() builder.ImageCache {
-		return cacheBuilder.MakeImageCache(cacheFrom)
+		return cacheBuilder.MakeImageCache(ctx, opts)
 	}
 	return &imageProber{cache: reset(), reset: reset}
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
 )
@@ -29,7 +30,7 @@ func (b *Builder) commit(dispatchState *dispatchState, comment string) error {
 	if err != nil || hit {
 		return err
 	}
-	id, err := b.create(runConfigWithCommentCmd)
+	id, err := b.create(runConfigWithCommentCmd, nil)
 	if err != nil {
 		return err
 	}
@@ -67,10 +68,17 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 func (b *Builder) performCopy(state *dispatchState, inst copyInstruction) error {
 	srcHash := getSourceHashFromInfos(inst.infos)
 
+	// The ownership is part of the comment so that a --chown change
+	// invalidates the build cache for this step.
+	cmdName := inst.cmdName
+	if inst.chownStr != "" {
+		cmdName += " --chown=" + inst.chownStr
+	}
+
 	// TODO: should this have been using origPaths instead of srcHash in the comment?
 	runConfigWithCommentCmd := copyRunConfig(
 		state.runConfig,
-		withCmdCommentString(fmt.Sprintf("%s %s in %s ", inst.cmdName, srcHash, inst.dest)))
+		withCmdCommentString(fmt.Sprintf("%s %s in %s ", cmdName, srcHash, inst.dest)))
 	containerID, err := b.probeAndCreate(state, runConfigWithCommentCmd)
 	if err != nil || containerID == "" {
 		return err
@@ -84,7 +92,7 @@ func (b *Builder) performCopy(state *dispatchState, inst copyInstruction) error
 	}
 
 	for _, info := range inst.infos {
-		if err := b.docker.CopyOnBuild(containerID, dest, info.root, info.path, inst.allowLocalDecompression); err != nil {
+		if err := b.docker.CopyOnBuild(containerID, dest, info.root, info.path, inst.allowLocalDecompression, inst.chownStr); err != nil {
 			return err
 		}
 	}
@@ -177,9 +185,13 @@ func getShell(c *container.Config) []string {
 func (b *Builder) probeCache(dispatchState *dispatchState, runConfig *container.Config) (bool, error) {
 	cachedID, err := b.imageProber.Probe(dispatchState.imageID, runConfig)
 	if cachedID == "" || err != nil {
+		if err == nil {
+			b.cacheResult = types.BuildCacheMiss
+		}
 		return false, err
 	}
 	fmt.Fprint(b.Stdout, " ---> Using cache\n")
+	b.cacheResult = types.BuildCacheHit
 
 	dispatchState.imageID = string(cachedID)
 	b.buildStages.update(dispatchState.imageID, runConfig)
@@ -198,8 +210,9 @@ func (b *Builder) probeAndCreate(dispatchState *dispatchState, runConfig *contai
 	return container.ID, err
 }
 
-func (b *Builder) create(runConfig *container.Config) (string, error) {
+func (b *Builder) create(runConfig *container.Config, mounts []mount.Mount) (string, error) {
 	hostConfig := hostConfigFromOptions(b.options)
+	hostConfig.Mounts = mounts
 	container, err := b.containerManager.Create(runConfig, hostConfig)
 	if err != nil {
 		return "", err
@@ -236,3 +249,76 @@ func hostConfigFromOptions(options *types.ImageBuildOptions) *container.HostConf
 		ExtraHosts: options.ExtraHosts,
 	}
 }
+
+// runMount is a build cache mount given with
+// "RUN --mount=type=cache,target=<path>[,id=<id>]".
+type runMount struct {
+	id     string
+	target string
+}
+
+// String returns the normalized spec of the mount.
+func (m runMount) String() string {
+	return fmt.Sprintf("type=cache,id=%s,target=%s", m.id, m.target)
+}
+
+// parseRunMounts parses the values of the --mount flag of RUN. The ID of a
+// cache defaults to its target.
+func parseRunMounts(values []string) ([]runMount, error) {
+	var runMounts []runMount
+	for _, value := range values {
+		var typ, target, id string
+		for _, field := range strings.Split(value, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid field '%s' in --mount, it must be a key=value pair", field)
+			}
+			switch strings.ToLower(parts[0]) {
+			case "type":
+				typ = parts[1]
+			case "target", "dst", "destination":
+				target = parts[1]
+			case "id":
+				id = parts[1]
+			default:
+				return nil, errors.Errorf("unexpected key '%s' in --mount", parts[0])
+			}
+		}
+		if typ != "cache" {
+			return nil, errors.Errorf("unsupported mount type '%s', only 'cache' is supported", typ)
+		}
+		if target == "" {
+			return nil, errors.New("the target of a cache mount is required")
+		}
+		if id == "" {
+			id = target
+		}
+		runMounts = append(runMounts, runMount{id: id, target: target})
+	}
+	return runMounts, nil
+}
+
+// cacheMounts returns the mounts of the build cache directories of the given
+// run mounts. The directories are not pruned until the returned function is
+// called, once they are mounted by a container.
+func (b *Builder) cacheMounts(runMounts []runMount) ([]mount.Mount, func(), error) {
+	var (
+		mounts   []mount.Mount
+		releases []func()
+	)
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, m := range runMounts {
+		source, r, err := b.docker.BuildCacheDir(m.id)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		releases = append(releases, r)
+		mounts = append(mounts, mount.Mount{Type: mount.TypeBind, Source: source, Target: m.target})
+	}
+	return mounts, release, nil
+}
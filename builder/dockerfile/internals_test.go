@@ -7,6 +7,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/pkg/archive"
@@ -128,3 +129,34 @@ func TestCopyRunConfig(t *testing.T) {
 	}
 
 }
+
+func TestCacheMounts(t *testing.T) {
+	b := newBuilderWithMockBackend()
+
+	runMounts, err := parseRunMounts([]string{
+		"type=cache,target=/root/.m2",
+		"type=cache,dst=/var/cache/apt,id=apt",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []runMount{{id: "/root/.m2", target: "/root/.m2"}, {id: "apt", target: "/var/cache/apt"}}, runMounts)
+	assert.Equal(t, "type=cache,id=apt,target=/var/cache/apt", runMounts[1].String())
+
+	mounts, release, err := b.cacheMounts(runMounts)
+	require.NoError(t, err)
+	release()
+	expected := []mount.Mount{
+		{Type: mount.TypeBind, Source: "/cache//root/.m2", Target: "/root/.m2"},
+		{Type: mount.TypeBind, Source: "/cache/apt", Target: "/var/cache/apt"},
+	}
+	assert.Equal(t, expected, mounts)
+
+	for _, value := range []string{
+		"type=bind,target=/foo",
+		"type=cache",
+		"type=cache,target",
+		"type=cache,target=/foo,readonly=true",
+	} {
+		_, err := parseRunMounts([]string{value})
+		assert.Error(t, err, value)
+	}
+}
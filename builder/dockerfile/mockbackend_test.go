@@ -34,6 +34,10 @@ func (m *MockBackend) ContainerRm(name string, config *types.ContainerRmConfig)
 	return nil
 }
 
+func (m *MockBackend) BuildCacheDir(id string) (string, func(), error) {
+	return "/cache/" + id, func() {}, nil
+}
+
 func (m *MockBackend) Commit(cID string, cfg *backend.ContainerCommitConfig) (string, error) {
 	if m.commitFunc != nil {
 		return m.commitFunc(cID, cfg)
@@ -45,7 +49,7 @@ func (m *MockBackend) ContainerKill(containerID string, sig uint64) error {
 	return nil
 }
 
-func (m *MockBackend) ContainerStart(containerID string, hostConfig *container.HostConfig, checkpoint string, checkpointDir string) error {
+func (m *MockBackend) ContainerStart(ctx context.Context, containerID string, hostConfig *container.HostConfig, checkpoint string, checkpointDir string) error {
 	return nil
 }
 
@@ -57,7 +61,7 @@ func (m *MockBackend) ContainerCreateWorkdir(containerID string) error {
 	return nil
 }
 
-func (m *MockBackend) CopyOnBuild(containerID string, destPath string, srcRoot string, srcPath string, decompress bool) error {
+func (m *MockBackend) CopyOnBuild(containerID string, destPath string, srcRoot string, srcPath string, decompress bool, chownStr string) error {
 	return nil
 }
 
@@ -69,9 +73,9 @@ func (m *MockBackend) GetImageAndReleasableLayer(ctx context.Context, refOrID st
 	return &mockImage{id: "theid"}, &mockLayer{}, nil
 }
 
-func (m *MockBackend) MakeImageCache(cacheFrom []string) builder.ImageCache {
+func (m *MockBackend) MakeImageCache(ctx context.Context, opts backend.ImageCacheOptions) builder.ImageCache {
 	if m.makeImageCacheFunc != nil {
-		return m.makeImageCacheFunc(cacheFrom)
+		return m.makeImageCacheFunc(opts.CacheFrom)
 	}
 	return nil
 }
@@ -0,0 +1,326 @@
+package remotecontext
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/dockerfile/parser"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/tarsum"
+	"github.com/pkg/errors"
+)
+
+// sessionTTL is the time after which the context of a session that is not
+// used anymore is removed.
+const sessionTTL = 24 * time.Hour
+
+// SessionStore keeps the build context of build sessions between builds.
+// The context sent for a build of a session is a diff, in the format of
+// image layers, that is applied on top of the context of the previous build
+// of the session. Clients only have to send the files that changed, and
+// whiteout files for the files that were removed.
+type SessionStore struct {
+	root     string
+	mu       sync.Mutex
+	sessions map[string]*buildSession
+}
+
+type buildSession struct {
+	id   string
+	root string
+	// sums are the tarsums of the files of the context, updated with the
+	// files of every diff.
+	sums     map[string]string
+	lastUsed time.Time
+	// users is the number of builds using or waiting for the session,
+	// protected by the lock of the store.
+	users int
+	// mu is held by the build using the session.
+	mu sync.Mutex
+	// deleted is set, with mu held, when the session is removed
+	deleted bool
+}
+
+// NewSessionStore creates a SessionStore keeping the contexts in root. The
+// contexts of a previous instance of the store are removed.
+func NewSessionStore(root string) (*SessionStore, error) {
+	if err := os.RemoveAll(root); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &SessionStore{root: root, sessions: make(map[string]*buildSession)}, nil
+}
+
+// Detect applies the context diff of the build configuration to the context
+// of its session, and returns the context and the dockerfile. Builds of the
+// same session are serialized, the session is released when the returned
+// context is closed.
+func (s *SessionStore) Detect(config backend.BuildConfig) (builder.Source, *parser.Result, error) {
+	defer config.Source.Close()
+	if config.Options.RemoteContext != "" {
+		return nil, nil, errors.New("a remote context cannot be used with a build session")
+	}
+
+	sess, err := s.acquire(config.Options.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sess.apply(config.Source); err != nil {
+		s.release(sess)
+		return nil, nil, err
+	}
+
+	c := &sessionContext{session: sess, store: s, removed: make(map[string]string)}
+	source, dockerfile, err := withDockerfileFromContext(c, config.Options.Dockerfile)
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return source, dockerfile, nil
+}
+
+// Get returns the files of the context of a session.
+func (s *SessionStore) Get(id string) (*types.BuildSession, error) {
+	sess, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(sess)
+
+	info := &types.BuildSession{ID: sess.id, LastUsed: sess.lastUsed, Files: []types.BuildSessionFile{}}
+	err = filepath.Walk(sess.root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := Rel(sess.root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info.Files = append(info.Files, types.BuildSessionFile{
+			Path:    filepath.ToSlash(rel),
+			Mode:    fi.Mode(),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Remove removes the context of a session.
+func (s *SessionStore) Remove(id string) error {
+	sess, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	// the lock of the session was taken, no build is using it. Its context
+	// is moved aside before the session is dropped from the store, so that
+	// the context of a new session with the same ID is not removed with it.
+	s.mu.Lock()
+	tmp, err := ioutil.TempDir(s.root, ".removed-")
+	if err == nil {
+		if err = os.Rename(filepath.Join(s.root, id), filepath.Join(tmp, id)); err != nil {
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		s.mu.Unlock()
+		s.release(sess)
+		return err
+	}
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	sess.deleted = true
+	sess.mu.Unlock()
+	return os.RemoveAll(tmp)
+}
+
+// get returns an existing session, locked.
+func (s *SessionStore) get(id string) (*buildSession, error) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("build session %s not found", id)
+	}
+	sess.users++
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	return sess, nil
+}
+
+// acquire returns the session with the given ID, creating it if needed, and
+// locks it.
+func (s *SessionStore) acquire(id string) (*buildSession, error) {
+	if !api.RestrictedNamePattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid build session ID %q, only %s are allowed", id, api.RestrictedNameChars)
+	}
+
+	for {
+		sess, err := s.acquireOnce(id)
+		if err != nil || !sess.deleted {
+			return sess, err
+		}
+		// the session was removed while waiting for it
+		sess.mu.Unlock()
+	}
+}
+
+func (s *SessionStore) acquireOnce(id string) (*buildSession, error) {
+	s.mu.Lock()
+	s.prune(time.Now())
+	sess, ok := s.sessions[id]
+	if !ok {
+		sess = &buildSession{
+			id:   id,
+			root: filepath.Join(s.root, id, "context"),
+			sums: make(map[string]string),
+		}
+		if err := os.MkdirAll(sess.root, 0755); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		s.sessions[id] = sess
+	}
+	sess.users++
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	return sess, nil
+}
+
+// release unlocks a session.
+func (s *SessionStore) release(sess *buildSession) {
+	s.mu.Lock()
+	sess.users--
+	sess.lastUsed = time.Now()
+	s.mu.Unlock()
+	sess.mu.Unlock()
+}
+
+// prune removes the sessions that were not used for sessionTTL. It is
+// called with the lock of the store held.
+func (s *SessionStore) prune(now time.Time) {
+	for id, sess := range s.sessions {
+		if sess.users == 0 && now.Sub(sess.lastUsed) > sessionTTL {
+			delete(s.sessions, id)
+			os.RemoveAll(filepath.Join(s.root, id))
+		}
+	}
+}
+
+// apply applies a context diff to the context of the session, and updates
+// the sums of its files.
+func (sess *buildSession) apply(diff io.Reader) error {
+	decompressed, err := archive.DecompressStream(diff)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	ts, err := tarsum.NewTarSum(decompressed, true, tarsum.Version1)
+	if err != nil {
+		return err
+	}
+	if _, err := chrootarchive.ApplyUncompressedLayer(sess.root, ts, &archive.TarOptions{}); err != nil {
+		return errors.Wrap(err, "failed to apply the build context diff")
+	}
+
+	// the files removed by whiteouts, or replaced by a directory, are
+	// not in the context anymore
+	for p := range sess.sums {
+		if _, err := os.Lstat(filepath.Join(sess.root, filepath.FromSlash(p))); err != nil {
+			delete(sess.sums, p)
+		}
+	}
+	for _, fi := range ts.GetSums() {
+		if _, err := os.Lstat(filepath.Join(sess.root, filepath.FromSlash(fi.Name()))); err == nil {
+			sess.sums[fi.Name()] = fi.Sum()
+		}
+	}
+	return nil
+}
+
+// sessionContext is the context of a build session. Files removed from it
+// by the builder are moved aside and restored when it is closed, so that
+// the context of the session is unchanged for the next build.
+type sessionContext struct {
+	session *buildSession
+	store   *SessionStore
+	// removed maps the files moved aside to their temporary location
+	removed map[string]string
+	order   []string
+	closed  bool
+}
+
+func (c *sessionContext) Root() string {
+	return c.session.root
+}
+
+func (c *sessionContext) Hash(path string) (string, error) {
+	cleanpath, fullpath, err := normalize(path, c.session.root)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := Rel(c.session.root, fullpath)
+	if err != nil {
+		return "", convertPathError(err, cleanpath)
+	}
+
+	if sum, ok := c.session.sums[filepath.ToSlash(rel)]; ok {
+		return sum, nil
+	}
+	return path, nil
+}
+
+func (c *sessionContext) Remove(path string) error {
+	cleanpath, fullpath, err := normalize(path, c.session.root)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.removed[cleanpath]; ok {
+		return nil
+	}
+	tmp := filepath.Join(filepath.Dir(c.session.root), fmt.Sprintf("removed-%d", len(c.order)))
+	if err := os.Rename(fullpath, tmp); err != nil {
+		return err
+	}
+	c.removed[cleanpath] = tmp
+	c.order = append(c.order, cleanpath)
+	return nil
+}
+
+func (c *sessionContext) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	var err error
+	for i := len(c.order) - 1; i >= 0; i-- {
+		p := c.order[i]
+		if e := os.Rename(c.removed[p], filepath.Join(c.session.root, p)); e != nil && err == nil {
+			err = e
+		}
+	}
+	c.store.release(c.session)
+	return err
+}
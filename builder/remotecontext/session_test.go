@@ -0,0 +1,181 @@
+package remotecontext
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/builder"
+)
+
+func makeContextDiff(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Unix(1500000000, 0)}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func buildWithSession(t *testing.T, s *SessionStore, files map[string]string) builder.Source {
+	source, _, err := s.Detect(backend.BuildConfig{
+		Source:  ioutil.NopCloser(makeContextDiff(t, files)),
+		Options: &types.ImageBuildOptions{SessionID: "session", Dockerfile: builder.DefaultDockerfileName},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestSessionStoreAppliesDiffs(t *testing.T) {
+	root, err := ioutil.TempDir("", "build-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := buildWithSession(t, s, map[string]string{
+		"Dockerfile":    "FROM busybox\n",
+		".dockerignore": "Dockerfile\n",
+		"a.txt":         "a",
+		"dir/b.txt":     "b",
+	})
+	if _, err := StatAt(source, "Dockerfile"); !os.IsNotExist(err) {
+		t.Fatalf("the ignored Dockerfile should not be in the context, got %v", err)
+	}
+	sumA, err := source.Hash("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the Dockerfile is sent in the first diff only, and b.txt is removed
+	source = buildWithSession(t, s, map[string]string{
+		"a.txt":         "changed",
+		"dir/.wh.b.txt": "",
+	})
+	if _, err := StatAt(source, "dir/b.txt"); !os.IsNotExist(err) {
+		t.Fatalf("dir/b.txt should have been removed, got %v", err)
+	}
+	newSumA, err := source.Hash("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newSumA == sumA {
+		t.Fatal("the sum of a.txt should have been updated")
+	}
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := s.Get("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range session.Files {
+		paths = append(paths, f.Path)
+	}
+	expected := []string{".dockerignore", "Dockerfile", "a.txt", "dir"}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Fatalf("expected files %v, got %v", expected, paths)
+		}
+	}
+
+	if err := s.Remove("session"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("session"); err == nil {
+		t.Fatal("expected an error for a removed session")
+	}
+	if _, err := os.Stat(filepath.Join(root, "session")); !os.IsNotExist(err) {
+		t.Fatalf("the context of the session should have been removed, got %v", err)
+	}
+}
+
+func TestSessionStoreInvalidID(t *testing.T) {
+	root, err := ioutil.TempDir("", "build-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = s.Detect(backend.BuildConfig{
+		Source:  ioutil.NopCloser(makeContextDiff(t, nil)),
+		Options: &types.ImageBuildOptions{SessionID: "../escape", Dockerfile: builder.DefaultDockerfileName},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an invalid session ID")
+	}
+}
+
+func TestSessionStoreRemoveThenReuseID(t *testing.T) {
+	root, err := ioutil.TempDir("", "build-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := buildWithSession(t, s, map[string]string{"Dockerfile": "FROM busybox\n", "a.txt": "a"})
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("session"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new session with the same ID starts from an empty context
+	source = buildWithSession(t, s, map[string]string{"Dockerfile": "FROM busybox\n"})
+	if _, err := StatAt(source, "a.txt"); !os.IsNotExist(err) {
+		t.Fatalf("a.txt should not be in the context of the new session, got %v", err)
+	}
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StatAt(source, "Dockerfile"); err != nil {
+		t.Fatalf("the context of the new session should be kept, got %v", err)
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "session" {
+		t.Fatalf("expected only the context of the new session in %s, got %v", root, entries)
+	}
+}
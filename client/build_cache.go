@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// BuildCacheExport returns the build cache manifest of the steps that
+// created the given images. Source is recorded in the manifest as the image,
+// in a registry, the layers of the steps are pulled from when the cache is
+// used.
+func (cli *Client) BuildCacheExport(ctx context.Context, images []string, source string) (types.BuildCacheManifest, error) {
+	var manifest types.BuildCacheManifest
+	if err := cli.NewVersionError("1.30", "build cache export"); err != nil {
+		return manifest, err
+	}
+
+	query := url.Values{"image": images}
+	if source != "" {
+		query.Set("source", source)
+	}
+	resp, err := cli.get(ctx, "/build/cache/export", query, nil)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.NewDecoder(resp.body).Decode(&manifest)
+	ensureReaderClosed(resp)
+	return manifest, err
+}
+
+// BuildCacheImport adds the entries of a build cache manifest to the build
+// cache of the daemon.
+func (cli *Client) BuildCacheImport(ctx context.Context, manifest types.BuildCacheManifest) error {
+	if err := cli.NewVersionError("1.30", "build cache import"); err != nil {
+		return err
+	}
+	resp, err := cli.post(ctx, "/build/cache/import", nil, manifest, nil)
+	ensureReaderClosed(resp)
+	return err
+}
+
+// BuildCacheSave retrieves the images of the steps that created the given
+// images as a tarball, that BuildCacheLoad loads on another daemon. It's up
+// to the caller to store the tarball and close the stream.
+func (cli *Client) BuildCacheSave(ctx context.Context, images []string) (io.ReadCloser, error) {
+	if err := cli.NewVersionError("1.30", "build cache save"); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"image": images, "format": []string{"tar"}}
+	resp, err := cli.get(ctx, "/build/cache/export", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
+
+// BuildCacheLoad loads a tarball created by BuildCacheSave in the build
+// cache of the daemon.
+func (cli *Client) BuildCacheLoad(ctx context.Context, input io.Reader) error {
+	if err := cli.NewVersionError("1.30", "build cache load"); err != nil {
+		return err
+	}
+	headers := map[string][]string{"Content-Type": {"application/x-tar"}}
+	resp, err := cli.postRaw(ctx, "/build/cache/import", nil, input, headers)
+	ensureReaderClosed(resp)
+	return err
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+func TestBuildCacheExport(t *testing.T) {
+	expectedURL := "/v1.30/build/cache/export"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "GET" {
+				return nil, fmt.Errorf("expected GET method, got %s", req.Method)
+			}
+			query := req.URL.Query()
+			if images := query["image"]; !reflect.DeepEqual(images, []string{"app:1", "app:2"}) {
+				return nil, fmt.Errorf("image not set in URL query properly, got %v", images)
+			}
+			if source := query.Get("source"); source != "example.com/app:cache" {
+				return nil, fmt.Errorf("source not set in URL query properly, got %s", source)
+			}
+			content, err := json.Marshal(types.BuildCacheManifest{
+				Entries: []types.BuildCacheManifestEntry{{Parent: "sha256:abc", Config: json.RawMessage("{}")}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	manifest, err := client.BuildCacheExport(context.Background(), []string{"app:1", "app:2"}, "example.com/app:cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Entries) != 1 || manifest.Entries[0].Parent != "sha256:abc" {
+		t.Fatalf("unexpected manifest %v", manifest)
+	}
+}
+
+func TestBuildCacheImport(t *testing.T) {
+	expectedURL := "/v1.30/build/cache/import"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			var manifest types.BuildCacheManifest
+			if err := json.NewDecoder(req.Body).Decode(&manifest); err != nil {
+				return nil, err
+			}
+			if len(manifest.Entries) != 1 || manifest.Entries[0].Source != "example.com/app:cache" {
+				return nil, fmt.Errorf("unexpected manifest %v", manifest)
+			}
+			return &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	err := client.BuildCacheImport(context.Background(), types.BuildCacheManifest{
+		Entries: []types.BuildCacheManifestEntry{{Config: json.RawMessage("{}"), Source: "example.com/app:cache"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildCacheSave(t *testing.T) {
+	expectedURL := "/v1.30/build/cache/export"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			query := req.URL.Query()
+			if images := query["image"]; !reflect.DeepEqual(images, []string{"app:1"}) {
+				return nil, fmt.Errorf("image not set in URL query properly, got %v", images)
+			}
+			if format := query.Get("format"); format != "tar" {
+				return nil, fmt.Errorf("format not set in URL query properly, got %s", format)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("tarball"))),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	rc, err := client.BuildCacheSave(context.Background(), []string{"app:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "tarball" {
+		t.Fatalf("expected tarball, got %q", content)
+	}
+}
+
+func TestBuildCacheLoad(t *testing.T) {
+	expectedURL := "/v1.30/build/cache/import"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if contentType := req.Header.Get("Content-Type"); contentType != "application/x-tar" {
+				return nil, fmt.Errorf("expected Content-Type application/x-tar, got %s", contentType)
+			}
+			content, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			if string(content) != "tarball" {
+				return nil, fmt.Errorf("expected tarball, got %q", content)
+			}
+			return &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	if err := client.BuildCacheLoad(context.Background(), strings.NewReader("tarball")); err != nil {
+		t.Fatal(err)
+	}
+}
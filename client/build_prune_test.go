@@ -0,0 +1,65 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestBuildCachePruneError(t *testing.T) {
+	client := &Client{
+		client:  newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+		version: "1.30",
+	}
+
+	_, err := client.BuildCachePrune(context.Background(), filters.NewArgs())
+	assert.EqualError(t, err, "Error response from daemon: Server error")
+}
+
+func TestBuildCachePrune(t *testing.T) {
+	expectedURL := "/v1.30/build/prune"
+
+	untilFilters := filters.NewArgs()
+	untilFilters.Add("until", "24h")
+
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			actual := req.URL.Query().Get("filters")
+			if expected := `{"until":{"24h":true}}`; actual != expected {
+				return nil, fmt.Errorf("filters not set in URL query properly. Expected '%s', got %s", expected, actual)
+			}
+			content, err := json.Marshal(types.BuildCachePruneReport{
+				CachesDeleted:  []string{"/root/.m2"},
+				SpaceReclaimed: 42,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	report, err := client.BuildCachePrune(context.Background(), untilFilters)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/root/.m2"}, report.CachesDeleted)
+	assert.Equal(t, uint64(42), report.SpaceReclaimed)
+}
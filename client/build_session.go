@@ -0,0 +1,30 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+// BuildSessionInspect returns the files of the build context the daemon keeps
+// for a build session. Builds of the session only have to send the files
+// that differ from them.
+func (cli *Client) BuildSessionInspect(ctx context.Context, sessionID string) (types.BuildSession, error) {
+	var session types.BuildSession
+	resp, err := cli.get(ctx, "/build/sessions/"+sessionID, nil, nil)
+	if err != nil {
+		return session, err
+	}
+	err = json.NewDecoder(resp.body).Decode(&session)
+	ensureReaderClosed(resp)
+	return session, err
+}
+
+// BuildSessionRemove removes the build context the daemon keeps for a build
+// session.
+func (cli *Client) BuildSessionRemove(ctx context.Context, sessionID string) error {
+	resp, err := cli.delete(ctx, "/build/sessions/"+sessionID, nil, nil)
+	ensureReaderClosed(resp)
+	return err
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+func TestBuildSessionInspect(t *testing.T) {
+	expectedURL := "/build/sessions/session_id"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "GET" {
+				return nil, fmt.Errorf("expected GET method, got %s", req.Method)
+			}
+			content, err := json.Marshal(types.BuildSession{
+				ID:    "session_id",
+				Files: []types.BuildSessionFile{{Path: "Dockerfile", Size: 42}},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+	}
+
+	session, err := client.BuildSessionInspect(context.Background(), "session_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ID != "session_id" || len(session.Files) != 1 || session.Files[0].Path != "Dockerfile" {
+		t.Fatalf("unexpected session %v", session)
+	}
+}
+
+func TestBuildSessionRemove(t *testing.T) {
+	expectedURL := "/build/sessions/session_id"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "DELETE" {
+				return nil, fmt.Errorf("expected DELETE method, got %s", req.Method)
+			}
+			return &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		}),
+	}
+
+	if err := client.BuildSessionRemove(context.Background(), "session_id"); err != nil {
+		t.Fatal(err)
+	}
+}
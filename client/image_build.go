@@ -96,6 +96,15 @@ func (cli *Client) imageBuildOptionsToQuery(options types.ImageBuildOptions) (ur
 	query.Set("shmsize", strconv.FormatInt(options.ShmSize, 10))
 	query.Set("dockerfile", options.Dockerfile)
 	query.Set("target", options.Target)
+	if options.SessionID != "" {
+		query.Set("session", options.SessionID)
+	}
+	if options.StructuredProgress {
+		if err := cli.NewVersionError("1.30", "structured progress"); err != nil {
+			return query, err
+		}
+		query.Set("structuredprogress", "1")
+	}
 
 	ulimitsJSON, err := json.Marshal(options.Ulimits)
 	if err != nil {
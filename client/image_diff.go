@@ -0,0 +1,27 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types/image"
+	"golang.org/x/net/context"
+)
+
+// ImageDiff returns the changes between the root filesystem of baseImage and
+// the one of image.
+func (cli *Client) ImageDiff(ctx context.Context, imageID, baseImage string) ([]image.DiffResponseItem, error) {
+	var diff []image.DiffResponseItem
+
+	query := url.Values{}
+	query.Set("base", baseImage)
+
+	serverResp, err := cli.get(ctx, "/images/"+imageID+"/diff", query, nil)
+	if err != nil {
+		return diff, err
+	}
+
+	err = json.NewDecoder(serverResp.body).Decode(&diff)
+	ensureReaderClosed(serverResp)
+	return diff, err
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"golang.org/x/net/context"
+)
+
+func TestImageDiffError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.ImageDiff(context.Background(), "nothing", "base")
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server error, got %v", err)
+	}
+}
+
+func TestImageDiff(t *testing.T) {
+	expectedURL := "/images/image_id/diff"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(r.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			if base := r.URL.Query().Get("base"); base != "base_id" {
+				return nil, fmt.Errorf("base not set in URL query properly. Expected 'base_id', got %s", base)
+			}
+			b, err := json.Marshal([]image.DiffResponseItem{
+				{
+					Kind: 0,
+					Path: "/path/1",
+					Size: 10,
+				},
+				{
+					Kind: 1,
+					Path: "/path/2",
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	diff, err := client.ImageDiff(context.Background(), "image_id", "base_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 2 {
+		t.Fatalf("expected 2 changes, got %v", diff)
+	}
+	if diff[0].Size != 10 {
+		t.Fatalf("expected a size of 10, got %d", diff[0].Size)
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"io"
+	"net/url"
+
+	"golang.org/x/net/context"
+)
+
+// VolumeExport retrieves the content of a volume as a tar archive, and
+// returns it as an io.ReadCloser. It's up to the caller to close the stream.
+func (cli *Client) VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error) {
+	if err := cli.NewVersionError("1.30", "volume export"); err != nil {
+		return nil, err
+	}
+	resp, err := cli.get(ctx, "/volumes/"+volumeID+"/export", url.Values{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
+
+// VolumeImport extracts a tar archive into a volume. Volumes used by
+// containers are only imported into when force is set.
+func (cli *Client) VolumeImport(ctx context.Context, volumeID string, content io.Reader, force bool) error {
+	if err := cli.NewVersionError("1.30", "volume import"); err != nil {
+		return err
+	}
+	query := url.Values{}
+	if force {
+		query.Set("force", "1")
+	}
+	headers := map[string][]string{"Content-Type": {"application/x-tar"}}
+	resp, err := cli.postRaw(ctx, "/volumes/"+volumeID+"/import", query, content, headers)
+	ensureReaderClosed(resp)
+	return err
+}
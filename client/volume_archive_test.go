@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestVolumeExport(t *testing.T) {
+	expectedURL := "/v1.30/volumes/volume_id/export"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != expectedURL {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "GET" {
+				return nil, fmt.Errorf("expected GET method, got %s", req.Method)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("archive"))),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	body, err := client.VolumeExport(context.Background(), "volume_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "archive" {
+		t.Fatalf("expected archive content, got %q", content)
+	}
+}
+
+func TestVolumeImport(t *testing.T) {
+	expectedURL := "/v1.30/volumes/volume_id/import"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			if force := req.URL.Query().Get("force"); force != "1" {
+				return nil, fmt.Errorf("force not set in URL query properly, got %s", force)
+			}
+			content, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			if string(content) != "archive" {
+				return nil, fmt.Errorf("expected archive content, got %q", content)
+			}
+			return &http.Response{
+				StatusCode: http.StatusNoContent,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+			}, nil
+		}),
+		version: "1.30",
+	}
+
+	if err := client.VolumeImport(context.Background(), "volume_id", strings.NewReader("archive"), true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVolumeImportError(t *testing.T) {
+	client := &Client{
+		client:  newMockClient(errorMock(http.StatusConflict, "volume is in use")),
+		version: "1.30",
+	}
+
+	err := client.VolumeImport(context.Background(), "volume_id", strings.NewReader("archive"), false)
+	if err == nil || err.Error() != "Error response from daemon: volume is in use" {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
@@ -73,3 +73,15 @@ func TestVolumeCreate(t *testing.T) {
 		t.Fatalf("expected volume.Mountpoint to be 'mountpoint', got %s", volume.Mountpoint)
 	}
 }
+
+func TestVolumeCreateFromUnsupportedVersion(t *testing.T) {
+	client := &Client{
+		client:  newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+		version: "1.29",
+	}
+
+	_, err := client.VolumeCreate(context.Background(), volumetypes.VolumesCreateBody{Name: "copy", From: "golden"})
+	if err == nil || !strings.Contains(err.Error(), "volume create from") {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+}
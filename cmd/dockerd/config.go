@@ -21,6 +21,7 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) {
 
 	flags.Var(opts.NewNamedListOptsRef("storage-opts", &conf.GraphOptions, nil), "storage-opt", "Storage driver options")
 	flags.Var(opts.NewNamedListOptsRef("authorization-plugins", &conf.AuthorizationPlugins, nil), "authorization-plugin", "Authorization plugins to load")
+	flags.StringVar(&conf.AuthorizationPolicy, "authorization-policy", "", "Authorization policy file to load")
 	flags.Var(opts.NewNamedListOptsRef("exec-opts", &conf.ExecOptions, nil), "exec-opt", "Runtime execution options")
 	flags.StringVarP(&conf.Pidfile, "pidfile", "p", defaultPidFile, "Path to use for daemon PID file")
 	flags.StringVarP(&conf.Root, "graph", "g", defaultDataRoot, "Root of the Docker runtime")
@@ -48,12 +49,19 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) {
 	flags.StringVar(&conf.CorsHeaders, "api-cors-header", "", "Set CORS headers in the Engine API")
 	flags.IntVar(&maxConcurrentDownloads, "max-concurrent-downloads", config.DefaultMaxConcurrentDownloads, "Set the max concurrent downloads for each pull")
 	flags.IntVar(&maxConcurrentUploads, "max-concurrent-uploads", config.DefaultMaxConcurrentUploads, "Set the max concurrent uploads for each push")
+	flags.StringVar(&conf.PushCompression, "push-compression", "gzip", "Compression of pushed layers (gzip, zstd)")
 	flags.IntVar(&conf.ShutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Set the default shutdown timeout")
+	flags.BoolVar(&conf.EventsJournal, "events-journal", false, "Keep the events on disk")
+	flags.StringVar(&conf.EventsJournalMaxAge, "events-journal-max-age", "", "Maximum age of the events kept on disk")
+	conf.EventsJournalMaxSize = opts.MemBytes(config.DefaultEventsJournalMaxSize)
+	flags.Var(&conf.EventsJournalMaxSize, "events-journal-max-size", "Maximum size of the events kept on disk")
+	flags.Var(opts.NewNamedEventSinkOpt("event-sinks", &conf.EventSinks), "event-sink", "Forward events to a webhook or a unix socket")
 
 	flags.StringVar(&conf.SwarmDefaultAdvertiseAddr, "swarm-default-advertise-addr", "", "Set default address or interface for swarm advertised address")
 	flags.BoolVar(&conf.Experimental, "experimental", false, "Enable experimental features")
 
 	flags.StringVar(&conf.MetricsAddress, "metrics-addr", "", "Set default address and port to serve the metrics api on")
+	flags.Var(opts.NewNamedListOptsRef("metrics-container-labels", &conf.MetricsContainerLabels, nil), "metrics-container-label", "Label per-container metrics with a container attribute (id, name, image) or label")
 
 	// "--deprecated-key-path" is to allow configuration of the key used
 	// for the daemon ID and the deprecated image signing. It was never
@@ -6,4 +6,5 @@ import (
 )
 
 func attachExperimentalFlags(conf *config.Config, cmd *pflag.FlagSet) {
+	cmd.BoolVar(&conf.LazyPull, "lazy-pull", false, "Fetch the content of pulled stargz layers when it is read")
 }
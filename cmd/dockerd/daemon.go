@@ -26,6 +26,7 @@ import (
 	swarmrouter "github.com/docker/docker/api/server/router/swarm"
 	systemrouter "github.com/docker/docker/api/server/router/system"
 	"github.com/docker/docker/api/server/router/volume"
+	"github.com/docker/docker/builder/remotecontext"
 	"github.com/docker/docker/cli/debug"
 	cliflags "github.com/docker/docker/cli/flags"
 	"github.com/docker/docker/daemon"
@@ -227,6 +228,10 @@ func (cli *DaemonCli) start(opts daemonOptions) (err error) {
 		}
 	}
 
+	if cli.Config.LazyPull && !d.HasExperimental() {
+		return fmt.Errorf("lazy-pull is only supported when experimental is enabled")
+	}
+
 	name, _ := os.Hostname()
 
 	// Use a buffered channel to pass changes from store watch API to daemon
@@ -266,7 +271,12 @@ func (cli *DaemonCli) start(opts daemonOptions) (err error) {
 
 	cli.d = d
 
-	initRouter(api, d, c)
+	buildSessions, err := remotecontext.NewSessionStore(filepath.Join(cli.Config.Root, "builder", "sessions"))
+	if err != nil {
+		return fmt.Errorf("Error creating the build session store: %v", err)
+	}
+
+	initRouter(api, d, c, buildSessions)
 
 	// process cluster change notifications
 	watchCtx, cancel := context.WithCancel(context.Background())
@@ -435,7 +445,7 @@ func loadDaemonCliConfig(opts daemonOptions) (*config.Config, error) {
 	return conf, nil
 }
 
-func initRouter(s *apiserver.Server, d *daemon.Daemon, c *cluster.Cluster) {
+func initRouter(s *apiserver.Server, d *daemon.Daemon, c *cluster.Cluster, buildSessions *remotecontext.SessionStore) {
 	decoder := runconfig.ContainerDecoder{}
 
 	routers := []router.Router{
@@ -445,7 +455,7 @@ func initRouter(s *apiserver.Server, d *daemon.Daemon, c *cluster.Cluster) {
 		image.NewRouter(d, decoder),
 		systemrouter.NewRouter(d, c),
 		volume.NewRouter(d),
-		build.NewRouter(buildbackend.NewBackend(d, d), d),
+		build.NewRouter(buildbackend.NewBackend(d, d, buildSessions), d),
 		swarmrouter.NewRouter(c),
 		pluginrouter.NewRouter(d.PluginManager()),
 		distributionrouter.NewRouter(d),
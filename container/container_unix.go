@@ -192,7 +192,14 @@ func (container *Container) CopyImagePathContent(v volume.Volume, destination st
 	if err := label.Relabel(path, container.MountLabel, true); err != nil && err != unix.ENOTSUP {
 		return err
 	}
-	return copyExistingContents(rootfs, path)
+	// the ownership and the mode set when the volume was created are kept
+	keepOwnership := false
+	if kv, ok := v.(interface {
+		KeepOwnership() bool
+	}); ok {
+		keepOwnership = kv.KeepOwnership()
+	}
+	return copyExistingContents(rootfs, path, keepOwnership)
 }
 
 // ShmResourcePath returns path to shm
@@ -364,7 +371,7 @@ func (container *Container) UpdateContainer(hostConfig *containertypes.HostConfi
 
 	// update HostConfig of container
 	if hostConfig.RestartPolicy.Name != "" {
-		if container.HostConfig.AutoRemove && !hostConfig.RestartPolicy.IsNone() {
+		if container.HostConfig.AutoRemove && (!hostConfig.RestartPolicy.IsNone() || hostConfig.RestartPolicy.UnhealthyThreshold != 0) {
 			return fmt.Errorf("Restart policy cannot be updated because AutoRemove is enabled for the container")
 		}
 		container.HostConfig.RestartPolicy = hostConfig.RestartPolicy
@@ -412,8 +419,9 @@ func (container *Container) DetachAndUnmount(volumeEventLog func(name, action st
 }
 
 // copyExistingContents copies from the source to the destination and
-// ensures the ownership is appropriately set.
-func copyExistingContents(source, destination string) error {
+// ensures the ownership is appropriately set, unless the ownership of the
+// destination is kept.
+func copyExistingContents(source, destination string, keepOwnership bool) error {
 	volList, err := ioutil.ReadDir(source)
 	if err != nil {
 		return err
@@ -430,6 +438,9 @@ func copyExistingContents(source, destination string) error {
 			}
 		}
 	}
+	if keepOwnership {
+		return nil
+	}
 	return copyOwnership(source, destination)
 }
 
@@ -182,7 +182,7 @@ func (container *Container) UpdateContainer(hostConfig *containertypes.HostConfi
 	}
 	// update HostConfig of container
 	if hostConfig.RestartPolicy.Name != "" {
-		if container.HostConfig.AutoRemove && !hostConfig.RestartPolicy.IsNone() {
+		if container.HostConfig.AutoRemove && (!hostConfig.RestartPolicy.IsNone() || hostConfig.RestartPolicy.UnhealthyThreshold != 0) {
 			return fmt.Errorf("Restart policy cannot be updated because AutoRemove is enabled for the container")
 		}
 		container.HostConfig.RestartPolicy = hostConfig.RestartPolicy
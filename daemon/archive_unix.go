@@ -5,8 +5,14 @@ package daemon
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/opencontainers/runc/libcontainer/user"
+	"github.com/pkg/errors"
 )
 
 // checkIfPathIsInAVolume checks if the path is in a volume. If it is, it
@@ -62,3 +68,83 @@ func fixPermissions(source, destination string, uid, gid int, destExisted bool)
 func (daemon *Daemon) isOnlineFSOperationPermitted(container *container.Container) error {
 	return nil
 }
+
+// parseChownFlag resolves the user[:group] value of a COPY or ADD --chown
+// flag to host IDs. Names are looked up in the passwd and group files of the
+// container's root filesystem, and the resulting IDs are translated through
+// the daemon's user namespace mappings. A user without a group also uses the
+// user's ID as the group ID.
+func parseChownFlag(chown, ctrRootPath string, uidMaps, gidMaps []idtools.IDMap) (int, int, error) {
+	parts := strings.SplitN(chown, ":", 2)
+	userStr, grpStr := parts[0], parts[0]
+	if len(parts) > 1 {
+		grpStr = parts[1]
+	}
+	if userStr == "" || grpStr == "" {
+		return 0, 0, errors.Errorf("invalid --chown value %q", chown)
+	}
+
+	passwdPath, err := symlink.FollowSymlinkInScope(filepath.Join(ctrRootPath, "etc", "passwd"), ctrRootPath)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "can't resolve /etc/passwd path in container rootfs")
+	}
+	groupPath, err := symlink.FollowSymlinkInScope(filepath.Join(ctrRootPath, "etc", "group"), ctrRootPath)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "can't resolve /etc/group path in container rootfs")
+	}
+
+	uid, err := lookupUser(userStr, passwdPath)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "can't find uid for user "+userStr)
+	}
+	gid := uid
+	if len(parts) > 1 {
+		if gid, err = lookupGroup(grpStr, groupPath); err != nil {
+			return 0, 0, errors.Wrap(err, "can't find gid for group "+grpStr)
+		}
+	}
+
+	hostUID, err := idtools.ToHost(uid, uidMaps)
+	if err != nil {
+		return 0, 0, err
+	}
+	hostGID, err := idtools.ToHost(gid, gidMaps)
+	if err != nil {
+		return 0, 0, err
+	}
+	return hostUID, hostGID, nil
+}
+
+func lookupUser(userStr, passwdPath string) (int, error) {
+	// a numeric uid does not need to be looked up
+	if uid, err := strconv.Atoi(userStr); err == nil {
+		return uid, nil
+	}
+	users, err := user.ParsePasswdFileFilter(passwdPath, func(u user.User) bool {
+		return u.Name == userStr
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, errors.New("no such user: " + userStr)
+	}
+	return users[0].Uid, nil
+}
+
+func lookupGroup(groupStr, groupPath string) (int, error) {
+	// a numeric gid does not need to be looked up
+	if gid, err := strconv.Atoi(groupStr); err == nil {
+		return gid, nil
+	}
+	groups, err := user.ParseGroupFileFilter(groupPath, func(g user.Group) bool {
+		return g.Name == groupStr
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(groups) == 0 {
+		return 0, errors.New("no such group: " + groupStr)
+	}
+	return groups[0].Gid, nil
+}
@@ -0,0 +1,58 @@
+// +build !windows
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChownFlag(t *testing.T) {
+	root, err := ioutil.TempDir("", "chown-flag-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0755))
+	passwd := "root:x:0:0::/root:/bin/sh\nunicorn:x:1001:1002::/home/unicorn:/bin/sh\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "etc", "passwd"), []byte(passwd), 0644))
+	group := "root:x:0:\nrainbow:x:1003:\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "etc", "group"), []byte(group), 0644))
+
+	remapped := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+
+	tests := []struct {
+		chown    string
+		uidMaps  []idtools.IDMap
+		gidMaps  []idtools.IDMap
+		uid, gid int
+		invalid  bool
+	}{
+		{chown: "1001", uid: 1001, gid: 1001},
+		{chown: "1001:1003", uid: 1001, gid: 1003},
+		{chown: "unicorn", uid: 1001, gid: 1001},
+		{chown: "unicorn:rainbow", uid: 1001, gid: 1003},
+		{chown: "unicorn:0", uid: 1001, gid: 0},
+		{chown: "unicorn:rainbow", uidMaps: remapped, gidMaps: remapped, uid: 101001, gid: 101003},
+		{chown: "nobody", invalid: true},
+		{chown: "unicorn:nogroup", invalid: true},
+		{chown: ":rainbow", invalid: true},
+		{chown: "unicorn:", invalid: true},
+	}
+
+	for _, test := range tests {
+		uid, gid, err := parseChownFlag(test.chown, root, test.uidMaps, test.gidMaps)
+		if test.invalid {
+			assert.Error(t, err, test.chown)
+			continue
+		}
+		require.NoError(t, err, test.chown)
+		assert.Equal(t, test.uid, uid, test.chown)
+		assert.Equal(t, test.gid, gid, test.chown)
+	}
+}
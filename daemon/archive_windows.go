@@ -5,6 +5,7 @@ import (
 
 	containertypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/idtools"
 )
 
 // checkIfPathIsInAVolume checks if the path is in a volume. If it is, it
@@ -22,6 +23,10 @@ func fixPermissions(source, destination string, uid, gid int, destExisted bool)
 	return nil
 }
 
+func parseChownFlag(chown, ctrRootPath string, uidMaps, gidMaps []idtools.IDMap) (int, int, error) {
+	return 0, 0, errors.New("the --chown flag is not supported on Windows")
+}
+
 // isOnlineFSOperationPermitted returns an error if an online filesystem operation
 // is not permitted (such as stat or for copying). Running Hyper-V containers
 // cannot have their file-system interrogated from the host as the filter is
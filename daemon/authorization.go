@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"strconv"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/pkg/authorization"
+)
+
+// setAuthorizationPolicy loads the authorization policy file and sets it on
+// the authorization middleware. An empty file name removes the policy.
+func (daemon *Daemon) setAuthorizationPolicy(file string) error {
+	m := daemon.configStore.AuthzMiddleware
+	if m == nil {
+		// the daemon doesn't serve the API
+		return nil
+	}
+
+	var policy *authorization.Policy
+	if file != "" {
+		var err error
+		policy, err = authorization.LoadPolicy(file)
+		if err != nil {
+			return err
+		}
+	}
+	m.SetAuditor(daemon.logAuthzDecision)
+	m.SetPolicy(policy)
+	return nil
+}
+
+// logAuthzDecision generates an event recording a decision of the
+// authorization policy.
+func (daemon *Daemon) logAuthzDecision(d authorization.Decision) {
+	action := authorization.PolicyDeny
+	if d.Allow {
+		action = authorization.PolicyAllow
+	}
+	rule := "default"
+	if d.Rule >= 0 {
+		rule = strconv.Itoa(d.Rule)
+	}
+	actor := events.Actor{
+		ID: d.User,
+		Attributes: map[string]string{
+			"method": d.Method,
+			"path":   d.Path,
+			"rule":   rule,
+		},
+	}
+	daemon.EventsService.Log(action, events.AuthzEventType, actor)
+}
@@ -82,6 +82,20 @@ func (daemon *Daemon) pullForBuilder(ctx context.Context, name string, authConfi
 	}
 	ref = reference.TagNameOnly(ref)
 
+	pullRegistryAuth, err := daemon.resolveAuthConfig(ref, authConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := daemon.pullImageWithReference(ctx, ref, nil, pullRegistryAuth, output); err != nil {
+		return nil, err
+	}
+	return daemon.GetImage(name)
+}
+
+// resolveAuthConfig returns the auth config, among the ones of a build
+// request, of the registry of ref.
+func (daemon *Daemon) resolveAuthConfig(ref reference.Named, authConfigs map[string]types.AuthConfig) (*types.AuthConfig, error) {
 	pullRegistryAuth := &types.AuthConfig{}
 	if len(authConfigs) > 0 {
 		// The request came with a full auth config, use it
@@ -93,11 +107,7 @@ func (daemon *Daemon) pullForBuilder(ctx context.Context, name string, authConfi
 		resolvedConfig := registry.ResolveAuthConfig(authConfigs, repoInfo.Index)
 		pullRegistryAuth = &resolvedConfig
 	}
-
-	if err := daemon.pullImageWithReference(ctx, ref, nil, pullRegistryAuth, output); err != nil {
-		return nil, err
-	}
-	return daemon.GetImage(name)
+	return pullRegistryAuth, nil
 }
 
 // GetImageAndReleasableLayer returns an image and releaseable layer for a reference or ID.
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/docker/docker/pkg/idtools"
+)
+
+// buildCacheRoot returns the directory holding the build caches.
+func (daemon *Daemon) buildCacheRoot() string {
+	return filepath.Join(daemon.root, "buildcache")
+}
+
+// BuildCacheDir returns the directory of the build cache with the given id,
+// creating it if needed. Build caches are mounted by `RUN --mount=type=cache`
+// and persist across builds until they are pruned. The cache is leased, so
+// that it is not pruned before the container mounting it is created, until
+// the returned function is called.
+func (daemon *Daemon) BuildCacheDir(id string) (string, func(), error) {
+	daemon.buildCacheLock.Lock()
+	defer daemon.buildCacheLock.Unlock()
+
+	sum := sha256.Sum256([]byte(id))
+	dir := filepath.Join(daemon.buildCacheRoot(), hex.EncodeToString(sum[:]))
+	rootUID, rootGID := daemon.GetRemappedUIDGID()
+	if err := idtools.MkdirAllAs(filepath.Join(dir, "data"), 0755, rootUID, rootGID); err != nil {
+		return "", nil, err
+	}
+	// the modification time of the id file records when the cache was
+	// last used
+	if err := ioutil.WriteFile(filepath.Join(dir, "id"), []byte(id), 0600); err != nil {
+		return "", nil, err
+	}
+
+	if daemon.buildCacheLeases == nil {
+		daemon.buildCacheLeases = make(map[string]int)
+	}
+	daemon.buildCacheLeases[dir]++
+	var once sync.Once
+	release := func() {
+		once.Do(func() { daemon.releaseBuildCacheDir(dir) })
+	}
+	return filepath.Join(dir, "data"), release, nil
+}
+
+func (daemon *Daemon) releaseBuildCacheDir(dir string) {
+	daemon.buildCacheLock.Lock()
+	defer daemon.buildCacheLock.Unlock()
+
+	daemon.buildCacheLeases[dir]--
+	if daemon.buildCacheLeases[dir] == 0 {
+		delete(daemon.buildCacheLeases, dir)
+	}
+}
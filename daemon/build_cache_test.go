@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/container"
+	"golang.org/x/net/context"
+)
+
+func TestBuildCachePruneSkipsLeasedCaches(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-daemon-build-cache-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := &Daemon{root: tmp, containers: container.NewMemoryStore()}
+	dir, release, err := d.BuildCacheDir("apt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the build container mounting the cache is not created yet
+	rep, err := d.BuildCachePrune(context.Background(), filters.NewArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.CachesDeleted) != 0 {
+		t.Fatalf("expected the leased cache not to be pruned, got %v", rep.CachesDeleted)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// releasing twice doesn't release the leases of other builds
+	_, releaseOther, err := d.BuildCacheDir("apt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release()
+	rep, err = d.BuildCachePrune(context.Background(), filters.NewArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.CachesDeleted) != 0 {
+		t.Fatalf("expected the leased cache not to be pruned, got %v", rep.CachesDeleted)
+	}
+
+	releaseOther()
+	rep, err = d.BuildCachePrune(context.Background(), filters.NewArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.CachesDeleted) != 1 || rep.CachesDeleted[0] != "apt" {
+		t.Fatalf("expected the released cache to be pruned, got %v", rep.CachesDeleted)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed, got %v", dir, err)
+	}
+}
@@ -1,13 +1,139 @@
 package daemon
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/Sirupsen/logrus"
+	dist "github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	containertypes "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/distribution"
+	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/image"
 	"github.com/docker/docker/image/cache"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/progress"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
-// MakeImageCache creates a stateful image cache.
-func (daemon *Daemon) MakeImageCache(sourceRefs []string) builder.ImageCache {
+// MakeImageCache creates a stateful image cache. The imported build cache
+// entries are only used when the cache sources of the options name their
+// source. Their layers are pulled, when the entries match, with the auth
+// configs of the options and until ctx is canceled.
+func (daemon *Daemon) MakeImageCache(ctx context.Context, opts backend.ImageCacheOptions) builder.ImageCache {
+	imageCache := daemon.makeImageCache(opts.CacheFrom)
+	if len(opts.CacheFrom) == 0 {
+		return imageCache
+	}
+
+	m, err := daemon.getCacheManifest()
+	if err != nil {
+		logrus.Warnf("Could not load the build cache manifest, skipping: %v", err)
+		return imageCache
+	}
+	m = &types.BuildCacheManifest{Entries: cacheFromEntries(m.Entries, opts.CacheFrom)}
+	if len(m.Entries) == 0 {
+		return imageCache
+	}
+	manifestCache := cache.NewManifestCache(daemon.imageStore, m, func(entry types.BuildCacheManifestEntry) (func(), error) {
+		return daemon.pullCacheLayers(ctx, entry, opts.AuthConfig)
+	})
+	return imageCaches{imageCache, manifestCache}
+}
+
+// cacheFromEntries returns the build cache manifest entries whose source is
+// named by one of the cache sources, either as the same reference or, for a
+// name without a tag or a digest, as the same repository.
+func cacheFromEntries(entries []types.BuildCacheManifestEntry, cacheFrom []string) []types.BuildCacheManifestEntry {
+	var refs []reference.Named
+	for _, s := range cacheFrom {
+		// the cache sources may also be image IDs
+		ref, err := reference.ParseNormalizedNamed(s)
+		if err != nil {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+
+	var matched []types.BuildCacheManifestEntry
+	for _, entry := range entries {
+		if entry.Source == "" {
+			continue
+		}
+		source, err := reference.ParseNormalizedNamed(entry.Source)
+		if err != nil {
+			continue
+		}
+		for _, ref := range refs {
+			if ref.String() == source.String() || (reference.IsNameOnly(ref) && ref.Name() == source.Name()) {
+				matched = append(matched, entry)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// pullCacheLayers pulls the layers of the image of a build cache manifest
+// entry that are not in the layer store, from the repository of its source.
+// The returned function releases the layers.
+func (daemon *Daemon) pullCacheLayers(ctx context.Context, entry types.BuildCacheManifestEntry, authConfigs map[string]types.AuthConfig) (func(), error) {
+	if entry.Source == "" {
+		return nil, errors.New("the layers of the image are not available, and the entry has no source")
+	}
+	img, err := image.NewFromJSON(entry.Config)
+	if err != nil {
+		return nil, err
+	}
+	if img.RootFS == nil || len(img.RootFS.DiffIDs) != len(entry.Layers) {
+		return nil, errors.New("the layer blobs of the image are not recorded in the entry")
+	}
+	var layers []dist.Descriptor
+	for _, l := range entry.Layers {
+		dgst, err := digest.Parse(l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		mediaType := l.MediaType
+		if mediaType == "" {
+			mediaType = schema2.MediaTypeLayer
+		}
+		layers = append(layers, dist.Descriptor{MediaType: mediaType, Digest: dgst})
+	}
+
+	ref, err := reference.ParseNormalizedNamed(entry.Source)
+	if err != nil {
+		return nil, err
+	}
+	authConfig, err := daemon.resolveAuthConfig(ref, authConfigs)
+	if err != nil {
+		return nil, err
+	}
+	imagePullConfig := &distribution.ImagePullConfig{
+		Config: distribution.Config{
+			AuthConfig:      authConfig,
+			ProgressOutput:  progress.DiscardOutput(),
+			RegistryService: daemon.RegistryService,
+			MetadataStore:   daemon.distributionMetadataStore,
+		},
+		DownloadManager:    daemon.downloadManager,
+		DownloadStagingDir: daemon.downloadStagingDir,
+	}
+	return distribution.PullLayers(ctx, ref, layers, img.RootFS.DiffIDs, imagePullConfig)
+}
+
+func (daemon *Daemon) makeImageCache(sourceRefs []string) builder.ImageCache {
 	if len(sourceRefs) == 0 {
 		return cache.NewLocal(daemon.imageStore)
 	}
@@ -25,3 +151,209 @@ func (daemon *Daemon) MakeImageCache(sourceRefs []string) builder.ImageCache {
 
 	return cache
 }
+
+// imageCaches looks up the caches in order, until one of them has a match.
+type imageCaches []builder.ImageCache
+
+func (caches imageCaches) GetCache(parentID string, cfg *containertypes.Config) (string, error) {
+	for _, c := range caches {
+		imgID, err := c.GetCache(parentID, cfg)
+		if err != nil || imgID != "" {
+			return imgID, err
+		}
+	}
+	return "", nil
+}
+
+// BuildCacheExport returns the build cache manifest of the steps that created
+// the given images. Source is recorded in the entries as the image, in a
+// registry, containing their layers, along with the blobs of the layers in
+// its repository. The images must have been pushed to it.
+func (daemon *Daemon) BuildCacheExport(refs []string, source string) (*types.BuildCacheManifest, error) {
+	ids, err := daemon.getImageIDs(refs)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		return cache.ExportManifest(daemon.imageStore, ids, "", nil)
+	}
+
+	ref, err := reference.ParseNormalizedNamed(source)
+	if err != nil {
+		return nil, err
+	}
+	v2MetadataService := metadata.NewV2MetadataService(daemon.distributionMetadataStore)
+	return cache.ExportManifest(daemon.imageStore, ids, source, func(img *image.Image) ([]types.BuildCacheLayer, error) {
+		var layers []types.BuildCacheLayer
+	diffIDs:
+		for _, diffID := range img.RootFS.DiffIDs {
+			v2Metadata, err := v2MetadataService.GetMetadata(diffID)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			for _, m := range v2Metadata {
+				if m.SourceRepository == ref.Name() {
+					layers = append(layers, types.BuildCacheLayer{MediaType: m.MediaType, Digest: m.Digest.String()})
+					continue diffIDs
+				}
+			}
+			return nil, errors.Errorf("layer %s of image %s was not pushed to %s", diffID, img.ID(), reference.FamiliarName(ref))
+		}
+		return layers, nil
+	})
+}
+
+// BuildCacheSave writes the images of the steps that created the given
+// images to outStream, in the tarball format of `docker save`. Loading the
+// tarball restores their parent chains, so that they are found by the build
+// cache.
+func (daemon *Daemon) BuildCacheSave(refs []string, outStream io.Writer) error {
+	ids, err := daemon.getImageIDs(refs)
+	if err != nil {
+		return err
+	}
+	m, err := cache.ExportManifest(daemon.imageStore, ids, "", nil)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range m.Entries {
+		names = append(names, digest.FromBytes(entry.Config).String())
+	}
+	return daemon.ExportImage(names, "", outStream)
+}
+
+// BuildCacheLoad loads the images of a tarball written by BuildCacheSave.
+func (daemon *Daemon) BuildCacheLoad(inTar io.ReadCloser) error {
+	return daemon.LoadImage(inTar, ioutil.Discard, true)
+}
+
+func (daemon *Daemon) getImageIDs(refs []string) ([]image.ID, error) {
+	var ids []image.ID
+	for _, ref := range refs {
+		img, err := daemon.GetImage(ref)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, img.ID())
+	}
+	return ids, nil
+}
+
+// BuildCacheImport adds the entries of a build cache manifest to the ones
+// used by the builder.
+func (daemon *Daemon) BuildCacheImport(m *types.BuildCacheManifest) error {
+	for _, entry := range m.Entries {
+		if _, err := image.NewFromJSON(entry.Config); err != nil {
+			return errors.Wrap(err, "invalid image config in the build cache manifest")
+		}
+		if entry.Parent != "" {
+			if _, err := digest.Parse(entry.Parent); err != nil {
+				return errors.Wrapf(err, "invalid parent image ID %q in the build cache manifest", entry.Parent)
+			}
+		}
+		for _, l := range entry.Layers {
+			if _, err := digest.Parse(l.Digest); err != nil {
+				return errors.Wrapf(err, "invalid layer digest %q in the build cache manifest", l.Digest)
+			}
+		}
+	}
+
+	daemon.cacheManifestLock.Lock()
+	defer daemon.cacheManifestLock.Unlock()
+
+	current, err := daemon.loadCacheManifest()
+	if err != nil {
+		return err
+	}
+	imported := &types.BuildCacheManifest{Entries: append([]types.BuildCacheManifestEntry{}, current.Entries...)}
+	seen := make(map[digest.Digest]bool)
+	for _, entry := range current.Entries {
+		seen[digest.FromBytes(entry.Config)] = true
+	}
+	for _, entry := range m.Entries {
+		dgst := digest.FromBytes(entry.Config)
+		if !seen[dgst] {
+			seen[dgst] = true
+			imported.Entries = append(imported.Entries, entry)
+		}
+	}
+
+	return daemon.saveCacheManifest(imported)
+}
+
+// pruneCacheManifest removes the imported build cache manifest entries of
+// the images created before until, or all of them if until is zero. It
+// returns the IDs of the images of the removed entries.
+func (daemon *Daemon) pruneCacheManifest(until time.Time) ([]string, error) {
+	daemon.cacheManifestLock.Lock()
+	defer daemon.cacheManifestLock.Unlock()
+
+	current, err := daemon.loadCacheManifest()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	kept := &types.BuildCacheManifest{Entries: []types.BuildCacheManifestEntry{}}
+	for _, entry := range current.Entries {
+		if !until.IsZero() {
+			if img, err := image.NewFromJSON(entry.Config); err == nil && img.Created.After(until) {
+				kept.Entries = append(kept.Entries, entry)
+				continue
+			}
+		}
+		removed = append(removed, digest.FromBytes(entry.Config).String())
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, daemon.saveCacheManifest(kept)
+}
+
+// saveCacheManifest replaces the imported build cache manifest entries, it
+// is called with cacheManifestLock held.
+func (daemon *Daemon) saveCacheManifest(m *types.BuildCacheManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(daemon.cacheManifestPath()), 0700); err != nil {
+		return err
+	}
+	if err := ioutils.AtomicWriteFile(daemon.cacheManifestPath(), data, 0600); err != nil {
+		return err
+	}
+	daemon.cacheManifest = m
+	return nil
+}
+
+func (daemon *Daemon) cacheManifestPath() string {
+	return filepath.Join(daemon.root, "builder", "cache-manifest.json")
+}
+
+// getCacheManifest returns the imported build cache manifest entries.
+func (daemon *Daemon) getCacheManifest() (*types.BuildCacheManifest, error) {
+	daemon.cacheManifestLock.Lock()
+	defer daemon.cacheManifestLock.Unlock()
+	return daemon.loadCacheManifest()
+}
+
+// loadCacheManifest loads the imported build cache manifest entries, it is
+// called with cacheManifestLock held.
+func (daemon *Daemon) loadCacheManifest() (*types.BuildCacheManifest, error) {
+	if daemon.cacheManifest != nil {
+		return daemon.cacheManifest, nil
+	}
+	m := &types.BuildCacheManifest{}
+	data, err := ioutil.ReadFile(daemon.cacheManifestPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, err
+		}
+	}
+	daemon.cacheManifest = m
+	return m, nil
+}
@@ -0,0 +1,39 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestCacheFromEntries(t *testing.T) {
+	entries := []types.BuildCacheManifestEntry{
+		{Source: "registry.example.com/app:cache"},
+		{Source: "app:cache"},
+		{},
+	}
+	for _, tc := range []struct {
+		cacheFrom []string
+		expected  []string
+	}{
+		{cacheFrom: nil, expected: nil},
+		{cacheFrom: []string{"registry.example.com/app:cache"}, expected: []string{"registry.example.com/app:cache"}},
+		{cacheFrom: []string{"registry.example.com/app"}, expected: []string{"registry.example.com/app:cache"}},
+		{cacheFrom: []string{"registry.example.com/app:latest"}, expected: nil},
+		{cacheFrom: []string{"docker.io/library/app:cache"}, expected: []string{"app:cache"}},
+		{cacheFrom: []string{"sha256:4355a46b19d348dc2f57c046f8ef63d4538ebb936000f3c9ee954a27460dd865", "app"}, expected: []string{"app:cache"}},
+	} {
+		var sources []string
+		for _, entry := range cacheFromEntries(entries, tc.cacheFrom) {
+			sources = append(sources, entry.Source)
+		}
+		if len(sources) != len(tc.expected) {
+			t.Fatalf("cache from %v: expected entries of %v, got %v", tc.cacheFrom, tc.expected, sources)
+		}
+		for i := range sources {
+			if sources[i] != tc.expected[i] {
+				t.Fatalf("cache from %v: expected entries of %v, got %v", tc.cacheFrom, tc.expected, sources)
+			}
+		}
+	}
+}
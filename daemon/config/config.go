@@ -11,8 +11,10 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
 	daemondiscovery "github.com/docker/docker/daemon/discovery"
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/authorization"
@@ -36,6 +38,9 @@ const (
 	StockRuntimeName = "runc"
 	// DefaultShmSize is the default value for container's shm size
 	DefaultShmSize = int64(67108864)
+	// DefaultEventsJournalMaxSize is the default maximum size of the
+	// events journal
+	DefaultEventsJournalMaxSize = int64(100 * 1024 * 1024)
 	// DefaultNetworkMtu is the default value for network MTU
 	DefaultNetworkMtu = 1500
 	// DisableNetworkBridge is the default value of the option to disable network bridge
@@ -86,6 +91,7 @@ type CommonTLSOptions struct {
 type CommonConfig struct {
 	AuthzMiddleware      *authorization.Middleware `json:"-"`
 	AuthorizationPlugins []string                  `json:"authorization-plugins,omitempty"` // AuthorizationPlugins holds list of authorization plugins
+	AuthorizationPolicy  string                    `json:"authorization-policy,omitempty"`  // AuthorizationPolicy is the path to the authorization policy file
 	AutoRestart          bool                      `json:"-"`
 	Context              map[string][]string       `json:"-"`
 	DisableBridge        bool                      `json:"-"`
@@ -137,10 +143,33 @@ type CommonConfig struct {
 	// may take place at a time for each push.
 	MaxConcurrentUploads *int `json:"max-concurrent-uploads,omitempty"`
 
+	// PushCompression is the compression used for the layers of pushed
+	// images, either "gzip" or "zstd".
+	PushCompression string `json:"push-compression,omitempty"`
+
+	// LazyPull registers the layers of pulled images in the stargz format
+	// without downloading them, their content is fetched when it is read.
+	LazyPull bool `json:"lazy-pull,omitempty"`
+
 	// ShutdownTimeout is the timeout value (in seconds) the daemon will wait for the container
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
 
+	// EventsJournal enables keeping the events on disk, so that they can be
+	// replayed after the daemon restarts.
+	EventsJournal bool `json:"events-journal,omitempty"`
+
+	// EventsJournalMaxAge is the duration the events are kept in the journal
+	// for. The age is not limited if it is empty.
+	EventsJournalMaxAge string `json:"events-journal-max-age,omitempty"`
+
+	// EventsJournalMaxSize is the maximum size of the events journal, in
+	// bytes. The size is not limited if it is 0.
+	EventsJournalMaxSize opts.MemBytes `json:"events-journal-max-size,omitempty"`
+
+	// EventSinks are the endpoints the events are forwarded to.
+	EventSinks []types.EventSink `json:"event-sinks,omitempty"`
+
 	Debug     bool     `json:"debug,omitempty"`
 	Hosts     []string `json:"hosts,omitempty"`
 	LogLevel  string   `json:"log-level,omitempty"`
@@ -157,6 +186,10 @@ type CommonConfig struct {
 	// specified.
 	SwarmDefaultAdvertiseAddr string `json:"swarm-default-advertise-addr"`
 	MetricsAddress            string `json:"metrics-addr"`
+	// MetricsContainerLabels are the labels of the per-container metrics,
+	// container attributes (id, name, image) or container label keys.
+	// Per-container metrics are not exported if it is empty.
+	MetricsContainerLabels []string `json:"metrics-container-labels,omitempty"`
 
 	LogConfig
 	BridgeConfig // bridgeConfig holds bridge network specific configuration.
@@ -489,6 +522,25 @@ func Validate(config *Config) error {
 	if config.MaxConcurrentUploads != nil && *config.MaxConcurrentUploads < 0 {
 		return fmt.Errorf("invalid max concurrent uploads: %d", *config.MaxConcurrentUploads)
 	}
+	// validate PushCompression
+	switch config.PushCompression {
+	case "", "gzip", "zstd":
+	default:
+		return fmt.Errorf("invalid push compression: %s", config.PushCompression)
+	}
+
+	// lazy layers are served by the daemon, containers using them cannot
+	// keep running while it is stopped
+	if config.LazyPull && config.LiveRestoreEnabled {
+		return fmt.Errorf("lazy-pull cannot be enabled with live-restore")
+	}
+
+	// validate EventsJournalMaxAge
+	if config.EventsJournalMaxAge != "" {
+		if d, err := time.ParseDuration(config.EventsJournalMaxAge); err != nil || d < 0 {
+			return fmt.Errorf("invalid events journal max age: %s", config.EventsJournalMaxAge)
+		}
+	}
 
 	// validate that "default" runtime is not reset
 	if runtimes := config.GetAllRuntimes(); len(runtimes) > 0 {
@@ -263,6 +263,14 @@ func TestValidateConfigurationErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			config: &Config{
+				CommonConfig: CommonConfig{
+					LazyPull:           true,
+					LiveRestoreEnabled: true,
+				},
+			},
+		},
 	}
 	for _, tc := range testCases {
 		err := Validate(tc.config)
@@ -259,6 +259,10 @@ func (daemon *Daemon) verifyContainerSettings(hostConfig *containertypes.HostCon
 			if config.Healthcheck.StartPeriod != 0 && config.Healthcheck.StartPeriod < containertypes.MinimumDuration {
 				return nil, fmt.Errorf("StartPeriod in Healthcheck cannot be less than %s", containertypes.MinimumDuration)
 			}
+
+			if err := validateHealthcheckTest(config.Healthcheck.Test); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -266,7 +270,7 @@ func (daemon *Daemon) verifyContainerSettings(hostConfig *containertypes.HostCon
 		return nil, nil
 	}
 
-	if hostConfig.AutoRemove && !hostConfig.RestartPolicy.IsNone() {
+	if hostConfig.AutoRemove && (!hostConfig.RestartPolicy.IsNone() || hostConfig.RestartPolicy.UnhealthyThreshold != 0) {
 		return nil, fmt.Errorf("can't create 'AutoRemove' container with restart policy")
 	}
 
@@ -305,6 +309,9 @@ func (daemon *Daemon) verifyContainerSettings(hostConfig *containertypes.HostCon
 	default:
 		return nil, fmt.Errorf("invalid restart policy '%s'", p.Name)
 	}
+	if p.UnhealthyThreshold < 0 {
+		return nil, fmt.Errorf("unhealthy threshold cannot be negative")
+	}
 
 	// Now do platform-specific verification
 	return verifyPlatformContainerSettings(daemon, hostConfig, config, update)
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/libnetwork"
@@ -71,6 +72,11 @@ func (daemon *Daemon) getSize(containerID string) (int64, int64) {
 	return 0, 0
 }
 
+// getDiskQuota returns the size limit of the container's writable layer
+func (daemon *Daemon) getDiskQuota(containerID string) *types.DiskQuota {
+	return nil
+}
+
 func (daemon *Daemon) setupIpcDirs(container *container.Container) error {
 	return nil
 }
@@ -20,6 +20,8 @@ import (
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/volume"
+	volumestore "github.com/docker/docker/volume/store"
 	"github.com/opencontainers/selinux/go-selinux/label"
 )
 
@@ -258,6 +260,68 @@ func (daemon *Daemon) VolumeCreate(name, driverName string, opts, labels map[str
 	return apiV, nil
 }
 
+// VolumeCreateFrom creates a volume with the specified name, driver, and
+// opts, as a copy of the volume named from. The volume is created with the
+// driver of the copied volume if no driver is specified.
+// This is called directly from the Engine API
+func (daemon *Daemon) VolumeCreateFrom(from, name, driverName string, opts, labels map[string]string) (*types.Volume, error) {
+	src, err := daemon.volumes.Get(from)
+	if err != nil {
+		return nil, err
+	}
+	if driverName == "" {
+		driverName = src.DriverName()
+	}
+	if name == "" {
+		name = stringid.GenerateNonCryptoID()
+	}
+
+	ref := "clone-" + stringid.GenerateNonCryptoID()
+	src, err = daemon.volumes.GetWithRef(src.Name(), src.DriverName(), ref)
+	if err != nil {
+		return nil, err
+	}
+	defer daemon.volumes.Dereference(src, ref)
+
+	// the content of existing volumes is not replaced
+	v, err := daemon.volumes.CreateNewWithRef(name, driverName, ref, opts, labels)
+	if err != nil {
+		if volumestore.IsExists(err) {
+			return nil, apierrors.NewRequestConflictError(fmt.Errorf("volume %s already exists", name))
+		}
+		return nil, err
+	}
+	daemon.LogVolumeEvent(v.Name(), "create", map[string]string{"driver": v.DriverName()})
+	if err := daemon.copyVolume(src, v, ref); err != nil {
+		daemon.volumes.Dereference(v, ref)
+		if rmErr := daemon.volumeRm(v.Name()); rmErr != nil {
+			logrus.Errorf("failed to remove volume %s after failing to copy %s into it: %v", v.Name(), from, rmErr)
+		}
+		return nil, errors.Wrapf(err, "failed to copy volume %s", from)
+	}
+	daemon.volumes.Dereference(v, ref)
+
+	apiV := volumeToAPIType(v)
+	apiV.Mountpoint = v.Path()
+	return apiV, nil
+}
+
+// copyVolume copies the content of the src volume into dst. Both volumes are
+// mounted with the given ref while they are copied.
+func (daemon *Daemon) copyVolume(src, dst volume.Volume, ref string) error {
+	srcPath, err := mountVolume(src, ref)
+	if err != nil {
+		return err
+	}
+	defer src.Unmount(ref)
+	dstPath, err := mountVolume(dst, ref)
+	if err != nil {
+		return err
+	}
+	defer dst.Unmount(ref)
+	return copyVolumeData(srcPath, dstPath)
+}
+
 func (daemon *Daemon) mergeAndVerifyConfig(config *containertypes.Config, img *image.Image) error {
 	if img != nil && img.Config != nil {
 		if err := merge(config, img.Config); err != nil {
@@ -33,6 +33,7 @@ import (
 	_ "github.com/docker/docker/daemon/graphdriver/register"
 	"github.com/docker/docker/daemon/initlayer"
 	"github.com/docker/docker/daemon/stats"
+	"github.com/docker/docker/distribution"
 	dmetadata "github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/dockerversion"
@@ -70,6 +71,10 @@ var (
 	errSystemNotSupported = errors.New("The Docker daemon is not supported on this platform.")
 )
 
+// downloadStagingMaxAge is how long partial layer downloads are kept for
+// pulls to resume.
+const downloadStagingMaxAge = 7 * 24 * time.Hour
+
 // Daemon holds information about the Docker daemon.
 type Daemon struct {
 	ID                        string
@@ -79,6 +84,8 @@ type Daemon struct {
 	referenceStore            refstore.Store
 	downloadManager           *xfer.LayerDownloadManager
 	uploadManager             *xfer.LayerUploadManager
+	downloadStagingDir        string
+	imageDiffLayersDir        string
 	distributionMetadataStore dmetadata.Store
 	trustKey                  libtrust.PrivateKey
 	idIndex                   *truncindex.TruncIndex
@@ -116,6 +123,17 @@ type Daemon struct {
 
 	diskUsageRunning int32
 	pruneRunning     int32
+
+	// buildCacheLock protects the build cache directories and their leases
+	buildCacheLock sync.Mutex
+	// buildCacheLeases counts the leases of the build cache directories,
+	// which are not pruned while leased
+	buildCacheLeases map[string]int
+
+	// cacheManifest holds the imported build cache manifest entries,
+	// loaded on first use
+	cacheManifestLock sync.Mutex
+	cacheManifest     *types.BuildCacheManifest
 }
 
 // HasExperimental returns whether the experimental features of the daemon are enabled or not
@@ -643,6 +661,20 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 	logrus.Debugf("Max Concurrent Uploads: %d", *config.MaxConcurrentUploads)
 	d.uploadManager = xfer.NewLayerUploadManager(*config.MaxConcurrentUploads)
 
+	// Partial downloads are kept across restarts so that pulls can resume
+	// them, unless they were abandoned.
+	d.downloadStagingDir = filepath.Join(imageRoot, "downloads")
+	if err := distribution.PruneDownloadStagingDir(d.downloadStagingDir, downloadStagingMaxAge); err != nil {
+		logrus.Warnf("Failed to prune partial downloads: %v", err)
+	}
+
+	// The layers mounted to diff images are removed once the diff is done,
+	// unless the daemon exited in the meantime.
+	d.imageDiffLayersDir = filepath.Join(imageRoot, "diff-layers")
+	if err := d.cleanupImageDiffLayers(); err != nil {
+		logrus.Warnf("Failed to remove the layers of interrupted image diffs: %v", err)
+	}
+
 	ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
 	if err != nil {
 		return nil, err
@@ -654,7 +686,7 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 	}
 
 	// Configure the volumes driver
-	volStore, err := d.configureVolumes(rootUID, rootGID)
+	volStore, err := d.configureVolumes(uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -676,6 +708,18 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 	}
 
 	eventsService := events.New()
+	if config.EventsJournal {
+		// the age was validated with the configuration
+		maxAge, _ := time.ParseDuration(config.EventsJournalMaxAge)
+		journal, err := events.NewJournal(filepath.Join(config.Root, "events"), maxAge, config.EventsJournalMaxSize.Value())
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't open the events journal: %v", err)
+		}
+		eventsService.SetJournal(journal)
+	}
+	if err := eventsService.SetSinks(config.EventSinks); err != nil {
+		return nil, fmt.Errorf("Couldn't set up the event sinks: %v", err)
+	}
 
 	referenceStore, err := refstore.NewReferenceStore(filepath.Join(imageRoot, "repositories.json"))
 	if err != nil {
@@ -710,11 +754,17 @@ func NewDaemon(config *config.Config, registryService registry.Service, containe
 	d.trustKey = trustKey
 	d.idIndex = truncindex.NewTruncIndex([]string{})
 	d.statsCollector = d.newStatsCollector(1 * time.Second)
+	if err := containerMetricsCtr.configure(d, config.MetricsContainerLabels); err != nil {
+		return nil, err
+	}
 	d.defaultLogConfig = containertypes.LogConfig{
 		Type:   config.LogConfig.Type,
 		Config: config.LogConfig.Config,
 	}
 	d.EventsService = eventsService
+	if err := d.setAuthorizationPolicy(config.AuthorizationPolicy); err != nil {
+		return nil, err
+	}
 	d.volumes = volStore
 	d.root = config.Root
 	d.uidMaps = uidMaps
@@ -890,6 +940,12 @@ func (daemon *Daemon) Shutdown() error {
 		daemon.netController.Stop()
 	}
 
+	if daemon.EventsService != nil {
+		if err := daemon.EventsService.Close(); err != nil {
+			logrus.Errorf("Error closing the events journal: %v", err)
+		}
+	}
+
 	if err := daemon.cleanupMounts(); err != nil {
 		return err
 	}
@@ -1013,8 +1069,8 @@ func setDefaultMtu(conf *config.Config) {
 	conf.Mtu = config.DefaultNetworkMtu
 }
 
-func (daemon *Daemon) configureVolumes(rootUID, rootGID int) (*store.VolumeStore, error) {
-	volumesDriver, err := local.New(daemon.configStore.Root, rootUID, rootGID)
+func (daemon *Daemon) configureVolumes(uidMaps, gidMaps []idtools.IDMap) (*store.VolumeStore, error) {
+	volumesDriver, err := local.New(daemon.configStore.Root, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -277,7 +277,7 @@ func TestMigratePre17Volumes(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	drv, err := local.New(volumeRoot, 0, 0)
+	drv, err := local.New(volumeRoot, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
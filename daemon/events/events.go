@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Sirupsen/logrus"
 	eventtypes "github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/pkg/pubsub"
 )
@@ -18,6 +19,12 @@ type Events struct {
 	mu     sync.Mutex
 	events []eventtypes.Message
 	pub    *pubsub.Publisher
+	// journal keeps the events on disk, if not nil
+	journal *Journal
+
+	// sinksMu protects sinks, and serializes their updates
+	sinksMu sync.Mutex
+	sinks   []Sink
 }
 
 // New returns new *Events instance
@@ -28,6 +35,29 @@ func New() *Events {
 	}
 }
 
+// SetJournal sets the journal the events are written to. Subscriptions with
+// since or until replay the events from the journal instead of the ones kept
+// in memory.
+func (e *Events) SetJournal(j *Journal) {
+	e.mu.Lock()
+	e.journal = j
+	e.mu.Unlock()
+}
+
+// Close closes the sinks and the journal of the events, if any.
+func (e *Events) Close() error {
+	e.closeSinks()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.journal == nil {
+		return nil
+	}
+	err := e.journal.Close()
+	e.journal = nil
+	return err
+}
+
 // Subscribe adds new listener to events, returns slice of 64 stored
 // last events, a channel in which you can expect new events (in form
 // of interface{}, so you need type assertion), and a function to call
@@ -58,7 +88,23 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 		topic = func(m interface{}) bool { return ef.Include(m.(eventtypes.Message)) }
 	}
 
-	buffered := e.loadBufferedEvents(since, until, topic)
+	var buffered []eventtypes.Message
+	journal := e.journal
+	var journalUntil time.Time
+	if journal == nil {
+		buffered = e.loadBufferedEvents(since, until, topic)
+	} else if !since.IsZero() || !until.IsZero() {
+		// the events published from now on are sent on the channel, the
+		// journal is read up to the last event published before
+		last := time.Now()
+		if n := len(e.events); n > 0 {
+			last = time.Unix(0, e.events[n-1].TimeNano)
+		}
+		journalUntil = until
+		if journalUntil.IsZero() || last.Before(journalUntil) {
+			journalUntil = last
+		}
+	}
 
 	var ch chan interface{}
 	if topic != nil {
@@ -69,6 +115,19 @@ func (e *Events) SubscribeTopic(since, until time.Time, ef *Filter) ([]eventtype
 	}
 
 	e.mu.Unlock()
+
+	if journal != nil && (!since.IsZero() || !until.IsZero()) {
+		// the journal is read without holding the lock, reading it can take
+		// long, and publishing events must not wait for it
+		var err error
+		buffered, err = journal.Read(since, journalUntil, topic)
+		if err != nil {
+			logrus.Warnf("Error reading the events journal, only replaying the recent events: %v", err)
+			e.mu.Lock()
+			buffered = e.loadBufferedEvents(since, journalUntil, topic)
+			e.mu.Unlock()
+		}
+	}
 	return buffered, ch
 }
 
@@ -117,6 +176,11 @@ func (e *Events) PublishMessage(jm eventtypes.Message) {
 	} else {
 		e.events = append(e.events, jm)
 	}
+	if e.journal != nil {
+		if err := e.journal.Write(jm); err != nil {
+			logrus.Warnf("Error writing event to the events journal: %v", err)
+		}
+	}
 	e.mu.Unlock()
 	e.pub.Publish(jm)
 }
@@ -0,0 +1,297 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	eventtypes "github.com/docker/docker/api/types/events"
+)
+
+const (
+	segmentPrefix = "events-"
+	segmentSuffix = ".log"
+
+	// maxSegmentSize is the size at which segments are rotated when the size
+	// of the journal is not limited
+	maxSegmentSize = 16 * 1024 * 1024
+	// segmentsPerJournal is the number of segments a journal of limited size
+	// is split in, so that the oldest events are removed in small steps
+	segmentsPerJournal = 8
+	// pruneInterval is the interval at which the expired events are removed,
+	// when the age of the events is limited
+	pruneInterval = time.Minute
+)
+
+// segment is a file of the journal. Its events are all older than the
+// events of the next segment.
+type segment struct {
+	name string
+	// start is the time of the first event of the segment, in nanoseconds
+	start int64
+	// end is the time of the last event of the segment, in nanoseconds
+	end  int64
+	size int64
+}
+
+// Journal keeps the events on disk, as JSON messages appended to segment
+// files. The oldest segments are removed once they are older than the
+// maximum age, or when the journal grows past its maximum size.
+type Journal struct {
+	mu          sync.Mutex
+	root        string
+	maxAge      time.Duration
+	maxSize     int64
+	segmentSize int64
+	segments    []segment
+	// f is the last segment, events are appended to. It is nil until the
+	// first event is written.
+	f *os.File
+	// stop stops removing the expired events
+	stop chan struct{}
+}
+
+// NewJournal opens the journal in the root directory, creating it if
+// needed. A maxAge or maxSize of 0 means no limit.
+func NewJournal(root string, maxAge time.Duration, maxSize int64) (*Journal, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		root:        root,
+		maxAge:      maxAge,
+		maxSize:     maxSize,
+		segmentSize: maxSegmentSize,
+	}
+	if maxSize > 0 && maxSize/segmentsPerJournal < maxSegmentSize {
+		j.segmentSize = maxSize / segmentsPerJournal
+	}
+
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range files {
+		name := fi.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		start, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			logrus.Warnf("Ignoring unexpected file in events journal: %s", name)
+			continue
+		}
+		// the segment was last written when the daemon stopped
+		end := fi.ModTime().UnixNano()
+		if end < start {
+			end = start
+		}
+		j.segments = append(j.segments, segment{name: name, start: start, end: end, size: fi.Size()})
+	}
+	sort.Slice(j.segments, func(i, k int) bool { return j.segments[i].start < j.segments[k].start })
+
+	// events are written to a new segment, in case the last one ends with an
+	// entry that was not fully written
+	j.prune(time.Now())
+
+	if maxAge > 0 {
+		// the events expire even when no new events rotate the segments
+		j.stop = make(chan struct{})
+		go j.pruneLoop(j.stop)
+	}
+	return j, nil
+}
+
+func (j *Journal) pruneLoop(stop chan struct{}) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			j.mu.Lock()
+			j.prune(now)
+			j.mu.Unlock()
+		}
+	}
+}
+
+// Write appends the event to the journal.
+func (j *Journal) Write(m eventtypes.Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil || j.segments[len(j.segments)-1].size+int64(len(b)) > j.segmentSize {
+		if err := j.rotate(m.TimeNano); err != nil {
+			return err
+		}
+	}
+	n, err := j.f.Write(b)
+	last := &j.segments[len(j.segments)-1]
+	last.size += int64(n)
+	if m.TimeNano > last.end {
+		last.end = m.TimeNano
+	}
+	return err
+}
+
+// rotate starts a new segment with an event emitted at start.
+func (j *Journal) rotate(start int64) error {
+	j.prune(time.Now())
+	if n := len(j.segments); n > 0 && j.segments[n-1].start >= start {
+		// keep the segments ordered even if the clock goes backwards
+		start = j.segments[n-1].start + 1
+	}
+	name := fmt.Sprintf("%s%d%s", segmentPrefix, start, segmentSuffix)
+	f, err := os.OpenFile(filepath.Join(j.root, name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if j.f != nil {
+		if err := j.f.Close(); err != nil {
+			logrus.Warnf("Error closing events journal segment: %v", err)
+		}
+	}
+	j.f = f
+	j.segments = append(j.segments, segment{name: name, start: start, end: start})
+	return nil
+}
+
+// prune removes the segments past the retention limits. The last segment is
+// only removed once all its events are expired. It is called with the lock
+// held.
+func (j *Journal) prune(now time.Time) {
+	var size int64
+	for _, s := range j.segments {
+		size += s.size
+	}
+	for len(j.segments) > 0 {
+		expired := j.maxAge > 0 && j.segments[0].end < now.Add(-j.maxAge).UnixNano()
+		tooLarge := j.maxSize > 0 && size > j.maxSize && len(j.segments) > 1
+		if !expired && !tooLarge {
+			break
+		}
+		if len(j.segments) == 1 && j.f != nil {
+			// the next event starts a new segment
+			if err := j.f.Close(); err != nil {
+				logrus.Warnf("Error closing events journal segment: %v", err)
+			}
+			j.f = nil
+		}
+		if err := os.Remove(filepath.Join(j.root, j.segments[0].name)); err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("Error removing events journal segment: %v", err)
+			break
+		}
+		size -= j.segments[0].size
+		j.segments = j.segments[1:]
+	}
+}
+
+// Read returns the events of the journal emitted between since and until,
+// filtered with topic if it's not nil. A zero time means no bound. Expired
+// events are not returned, even if they were not removed yet.
+// The segments are read without holding the lock of the journal, so that
+// events are written while the journal is read.
+func (j *Journal) Read(since, until time.Time, topic func(interface{}) bool) ([]eventtypes.Message, error) {
+	var sinceNano, untilNano int64
+	if !since.IsZero() {
+		sinceNano = since.UnixNano()
+	}
+	if !until.IsZero() {
+		untilNano = until.UnixNano()
+	}
+	if j.maxAge > 0 {
+		if expiry := time.Now().Add(-j.maxAge).UnixNano(); sinceNano < expiry {
+			sinceNano = expiry
+		}
+	}
+
+	j.mu.Lock()
+	segments := make([]segment, len(j.segments))
+	copy(segments, j.segments)
+	j.mu.Unlock()
+
+	var messages []eventtypes.Message
+	for _, s := range segments {
+		if s.end < sinceNano {
+			continue
+		}
+		if untilNano > 0 && s.start > untilNano {
+			break
+		}
+		err := readSegment(filepath.Join(j.root, s.name), func(m eventtypes.Message) {
+			if m.TimeNano < sinceNano || (untilNano > 0 && m.TimeNano > untilNano) {
+				return
+			}
+			if topic == nil || topic(m) {
+				messages = append(messages, m)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func readSegment(path string, fn func(eventtypes.Message)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// an incomplete last line was not fully written, skip it
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var m eventtypes.Message
+		if err := json.Unmarshal(line, &m); err != nil {
+			logrus.Debugf("Skipping corrupted entry of events journal %s: %v", path, err)
+			continue
+		}
+		fn(m)
+	}
+}
+
+// Close closes the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stop != nil {
+		close(j.stop)
+		j.stop = nil
+	}
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	eventtypes "github.com/docker/docker/api/types/events"
+)
+
+func newTestMessage(id string, t time.Time) eventtypes.Message {
+	return eventtypes.Message{
+		Type:     eventtypes.ContainerEventType,
+		Action:   "start",
+		Actor:    eventtypes.Actor{ID: id},
+		Scope:    "local",
+		Time:     t.Unix(),
+		TimeNano: t.UnixNano(),
+	}
+}
+
+func expectIDs(t *testing.T, messages []eventtypes.Message, ids ...string) {
+	if len(messages) != len(ids) {
+		t.Fatalf("expected %d events, got %d: %v", len(ids), len(messages), messages)
+	}
+	for i, m := range messages {
+		if m.Actor.ID != ids[i] {
+			t.Fatalf("expected event %d to be %s, got %s", i, ids[i], m.Actor.ID)
+		}
+	}
+}
+
+func TestJournalReplayAfterReopen(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	start := time.Now().Add(-time.Hour)
+	j, err := NewJournal(root, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range []string{"a", "b", "c", "d"} {
+		if err := j.Write(newTestMessage(id, start.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New()
+	j, err = NewJournal(root, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetJournal(j)
+	defer e.Close()
+
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "e"})
+
+	buffered, l := e.SubscribeTopic(start.Add(time.Minute), time.Time{}, nil)
+	defer e.Evict(l)
+	expectIDs(t, buffered, "b", "c", "d", "e")
+
+	buffered, l2 := e.SubscribeTopic(start, start.Add(2*time.Minute), nil)
+	defer e.Evict(l2)
+	expectIDs(t, buffered, "a", "b", "c")
+}
+
+func TestJournalRetention(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	msg := newTestMessage("a", time.Now())
+	j, err := NewJournal(root, 0, 8*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	// write more than twice the size of the journal
+	start := time.Now()
+	var count int
+	for size := 0; size < 16*1024; size += int(j.segmentSize) / 4 {
+		msg.TimeNano = start.Add(time.Duration(count) * time.Second).UnixNano()
+		if err := j.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	var total int64
+	for _, s := range j.segments {
+		total += s.size
+	}
+	if total > 8*1024 {
+		t.Fatalf("expected the journal to be at most 8k, got %d", total)
+	}
+	messages, err := j.Read(time.Unix(0, 1), time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) == 0 || len(messages) >= count {
+		t.Fatalf("expected the oldest events to be removed, got %d of %d", len(messages), count)
+	}
+
+	// events older than the max age are removed, including the last segment
+	// once all its events are expired
+	j.maxAge = time.Minute
+	j.prune(start.Add(time.Duration(count)*time.Second + time.Hour))
+	if len(j.segments) != 0 || j.f != nil {
+		t.Fatalf("expected all the segments to be removed, got %d", len(j.segments))
+	}
+	msg.TimeNano = time.Now().UnixNano()
+	if err := j.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.segments) != 1 {
+		t.Fatalf("expected a new segment to be started, got %d", len(j.segments))
+	}
+}
+
+func TestJournalReadSkipsExpiredEvents(t *testing.T) {
+	root, err := ioutil.TempDir("", "events-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	j, err := NewJournal(root, time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	now := time.Now()
+	for _, m := range []eventtypes.Message{
+		newTestMessage("a", now.Add(-time.Hour)),
+		newTestMessage("b", now.Add(-time.Second)),
+	} {
+		if err := j.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	messages, err := j.Read(now.Add(-2*time.Hour), time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, messages, "b")
+}
@@ -0,0 +1,488 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+const (
+	// WebhookSink is the type of the sinks posting events to a URL
+	WebhookSink = "webhook"
+	// UnixSink is the type of the sinks streaming events on a unix socket
+	UnixSink = "unix"
+
+	defaultBatchSize    = 100
+	defaultBatchTimeout = time.Second
+	defaultMaxRetries   = 3
+	webhookQueueSize    = 100
+	webhookTimeout      = 10 * time.Second
+	retryDelay          = time.Second
+)
+
+// Sink forwards the events matching its filter to an external endpoint.
+type Sink interface {
+	// Close stops forwarding events and releases the resources of the sink.
+	Close() error
+}
+
+// sinkConfig is a validated sink configuration.
+type sinkConfig struct {
+	types.EventSink
+	filter       *Filter
+	batchSize    int
+	batchTimeout time.Duration
+	maxRetries   int
+}
+
+func parseSinkConfig(config types.EventSink) (*sinkConfig, error) {
+	c := &sinkConfig{
+		EventSink:    config,
+		batchSize:    defaultBatchSize,
+		batchTimeout: defaultBatchTimeout,
+		maxRetries:   defaultMaxRetries,
+	}
+
+	args := filters.NewArgs()
+	for k, values := range config.Filters {
+		for _, v := range values {
+			args.Add(k, v)
+		}
+	}
+	c.filter = NewFilter(args)
+
+	switch config.Type {
+	case WebhookSink:
+		u, err := url.Parse(config.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook address %s: %v", config.Address, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid webhook address %s: the scheme must be http or https", config.Address)
+		}
+	case UnixSink:
+		if config.Address == "" {
+			return nil, fmt.Errorf("the address of a unix event sink must be a socket path")
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("invalid event sink type %q", config.Type)
+	}
+
+	if config.BatchSize < 0 {
+		return nil, fmt.Errorf("invalid event sink batch size %d", config.BatchSize)
+	}
+	if config.BatchSize > 0 {
+		c.batchSize = config.BatchSize
+	}
+	if config.BatchTimeout != "" {
+		d, err := time.ParseDuration(config.BatchTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid event sink batch timeout %s", config.BatchTimeout)
+		}
+		c.batchTimeout = d
+	}
+	if config.MaxRetries != nil {
+		if *config.MaxRetries < 0 {
+			return nil, fmt.Errorf("invalid event sink max retries %d", *config.MaxRetries)
+		}
+		c.maxRetries = *config.MaxRetries
+	}
+	return c, nil
+}
+
+// SetSinks replaces the sinks the events are forwarded to. The new sinks are
+// all created before they replace the current ones, which are left untouched
+// on error. A unix sink listening on the same socket as a current one keeps
+// its listener and connected clients.
+func (e *Events) SetSinks(configs []types.EventSink) error {
+	var parsed []*sinkConfig
+	sockets := make(map[string]bool)
+	for _, config := range configs {
+		c, err := parseSinkConfig(config)
+		if err != nil {
+			return err
+		}
+		if c.Type == UnixSink {
+			if sockets[c.Address] {
+				return fmt.Errorf("duplicate event sink socket %s", c.Address)
+			}
+			sockets[c.Address] = true
+		}
+		parsed = append(parsed, c)
+	}
+
+	e.sinksMu.Lock()
+	defer e.sinksMu.Unlock()
+
+	current := make(map[string]*unixSink)
+	for _, s := range e.sinks {
+		if us, ok := s.(*unixSink); ok {
+			current[us.config.Address] = us
+		}
+	}
+
+	var (
+		sinks   []Sink
+		created []Sink
+		reused  = make(map[*unixSink]*sinkConfig)
+	)
+	for _, c := range parsed {
+		var (
+			s   Sink
+			err error
+		)
+		switch c.Type {
+		case WebhookSink:
+			s = newWebhookSink(e, c)
+		case UnixSink:
+			if us, ok := current[c.Address]; ok {
+				reused[us] = c
+				sinks = append(sinks, us)
+				continue
+			}
+			s, err = newUnixSink(e, c)
+		}
+		if err != nil {
+			for _, s := range created {
+				if err := s.Close(); err != nil {
+					logrus.Warnf("Error closing event sink: %v", err)
+				}
+			}
+			return err
+		}
+		created = append(created, s)
+		sinks = append(sinks, s)
+	}
+
+	for _, s := range e.sinks {
+		if us, ok := s.(*unixSink); ok && reused[us] != nil {
+			us.setConfig(reused[us])
+			continue
+		}
+		if err := s.Close(); err != nil {
+			logrus.Warnf("Error closing event sink: %v", err)
+		}
+	}
+	e.sinks = sinks
+	return nil
+}
+
+// closeSinks closes the sinks the events are forwarded to.
+func (e *Events) closeSinks() {
+	e.sinksMu.Lock()
+	defer e.sinksMu.Unlock()
+	for _, s := range e.sinks {
+		if err := s.Close(); err != nil {
+			logrus.Warnf("Error closing event sink: %v", err)
+		}
+	}
+	e.sinks = nil
+}
+
+// subscribe subscribes to the events matching the filter, without replaying
+// past events.
+func (e *Events) subscribe(ef *Filter) chan interface{} {
+	eventSubscribers.Inc()
+	if ef.filter.Len() == 0 {
+		return e.pub.Subscribe()
+	}
+	return e.pub.SubscribeTopic(func(m interface{}) bool { return ef.Include(m.(eventtypes.Message)) })
+}
+
+// webhookSink posts batches of events to a URL, as JSON arrays. The events
+// are batched as they are received and queued for a separate goroutine to
+// send, so that a slow or unreachable endpoint does not hold up the events
+// publisher. The oldest batches are dropped when the queue is full.
+type webhookSink struct {
+	events  *Events
+	config  *sinkConfig
+	client  *http.Client
+	ch      chan interface{}
+	batches chan []eventtypes.Message
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newWebhookSink(e *Events, c *sinkConfig) *webhookSink {
+	s := &webhookSink{
+		events:  e,
+		config:  c,
+		client:  &http.Client{Timeout: webhookTimeout},
+		ch:      e.subscribe(c.filter),
+		batches: make(chan []eventtypes.Message, webhookQueueSize),
+		done:    make(chan struct{}),
+	}
+	s.wg.Add(2)
+	go s.run()
+	go s.sendLoop()
+	return s
+}
+
+// run batches the events received, and queues the batches for sending.
+func (s *webhookSink) run() {
+	defer s.wg.Done()
+	defer close(s.batches)
+
+	var batch []eventtypes.Message
+	timer := time.NewTimer(s.config.batchTimeout)
+	timer.Stop()
+	for {
+		select {
+		case ev, ok := <-s.ch:
+			if !ok {
+				s.queue(batch)
+				return
+			}
+			batch = append(batch, ev.(eventtypes.Message))
+			if len(batch) == 1 {
+				timer.Reset(s.config.batchTimeout)
+			}
+			if len(batch) < s.config.batchSize {
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
+		case <-s.done:
+			s.queue(batch)
+			return
+		}
+		s.queue(batch)
+		batch = nil
+	}
+}
+
+// queue adds a batch to the queue of the batches to send, dropping the
+// oldest one if the queue is full. run is the only sender on the queue, so
+// there is room for the batch once one has been dropped.
+func (s *webhookSink) queue(batch []eventtypes.Message) {
+	if len(batch) == 0 {
+		return
+	}
+	for {
+		select {
+		case s.batches <- batch:
+			return
+		default:
+		}
+		select {
+		case dropped := <-s.batches:
+			logrus.Warnf("Dropping %d events queued for webhook %s: the queue is full", len(dropped), s.config.Address)
+		default:
+		}
+	}
+}
+
+// sendLoop sends the queued batches. Once the sink is closed, the batches
+// left are not sent anymore after a failure.
+func (s *webhookSink) sendLoop() {
+	defer s.wg.Done()
+	for batch := range s.batches {
+		if !s.send(batch) {
+			select {
+			case <-s.done:
+				for batch := range s.batches {
+					logrus.Warnf("Dropping %d events that could not be sent to webhook %s", len(batch), s.config.Address)
+				}
+				return
+			default:
+			}
+		}
+	}
+}
+
+// send posts the events, retrying with an increasing delay on failure. It
+// returns whether the events were sent.
+func (s *webhookSink) send(batch []eventtypes.Message) bool {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		logrus.Errorf("Error encoding events for webhook %s: %v", s.config.Address, err)
+		return false
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			return true
+		}
+		if attempt >= s.config.maxRetries {
+			break
+		}
+		logrus.Debugf("Error sending events to webhook %s, retrying in %s: %v", s.config.Address, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			logrus.Warnf("Dropping %d events that could not be sent to webhook %s: %v", len(batch), s.config.Address, err)
+			return false
+		}
+		delay *= 2
+	}
+	logrus.Warnf("Dropping %d events that could not be sent to webhook %s: %v", len(batch), s.config.Address, err)
+	return false
+}
+
+func (s *webhookSink) post(body []byte) error {
+	resp, err := s.client.Post(s.config.Address, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	close(s.done)
+	s.events.Evict(s.ch)
+	s.wg.Wait()
+	return nil
+}
+
+// unixSink streams the events, as JSON messages separated by newlines, to
+// every client connected to a unix socket.
+type unixSink struct {
+	events *Events
+	l      net.Listener
+
+	mu sync.Mutex
+	// config is replaced when the sinks are reloaded, the clients already
+	// connected keep the filter they were subscribed with
+	config *sinkConfig
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newUnixSink(e *Events, c *sinkConfig) (*unixSink, error) {
+	// a stale socket is removed, but not a file that was configured by
+	// mistake
+	fi, err := os.Lstat(c.Address)
+	switch {
+	case err == nil:
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on event sink socket %s: the path exists and is not a socket", c.Address)
+		}
+		if err := os.Remove(c.Address); err != nil {
+			return nil, err
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	l, err := net.Listen("unix", c.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on event sink socket %s: %v", c.Address, err)
+	}
+	if err := os.Chmod(c.Address, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	s := &unixSink{
+		events: e,
+		config: c,
+		l:      l,
+		conns:  make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+func (s *unixSink) setConfig(c *sinkConfig) {
+	s.mu.Lock()
+	s.config = c
+	s.mu.Unlock()
+}
+
+func (s *unixSink) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			// the listener was closed
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.stream(conn)
+	}
+}
+
+func (s *unixSink) stream(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	s.mu.Lock()
+	address, filter := s.config.Address, s.config.filter
+	s.mu.Unlock()
+
+	ch := s.events.subscribe(filter)
+	defer s.events.Evict(ch)
+
+	// the client closing the connection is detected by reading from it
+	closed := make(chan struct{})
+	go func() {
+		var b [1]byte
+		for {
+			if _, err := conn.Read(b[:]); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	enc := json.NewEncoder(conn)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(ev); err != nil {
+				logrus.Debugf("Error streaming event to %s: %v", address, err)
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (s *unixSink) Close() error {
+	err := s.l.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,229 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	eventtypes "github.com/docker/docker/api/types/events"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		received []eventtypes.Message
+	)
+	batches := make(chan int, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// fail the first request, so that it is retried
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []eventtypes.Message
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+		received = append(received, batch...)
+		batches <- len(batch)
+	}))
+	defer server.Close()
+
+	retries := 1
+	e := New()
+	err := e.SetSinks([]types.EventSink{{
+		Type:         WebhookSink,
+		Address:      server.URL,
+		Filters:      map[string][]string{"type": {eventtypes.ContainerEventType}},
+		BatchSize:    2,
+		BatchTimeout: "50ms",
+		MaxRetries:   &retries,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	e.Log("create", eventtypes.ContainerEventType, eventtypes.Actor{ID: "a"})
+	e.Log("create", eventtypes.ImageEventType, eventtypes.Actor{ID: "b"})
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "a"})
+	e.Log("die", eventtypes.ContainerEventType, eventtypes.Actor{ID: "a"})
+
+	// a full batch, then one sent on the timeout
+	for _, expected := range []int{2, 1} {
+		select {
+		case n := <-batches:
+			if n != expected {
+				t.Fatalf("expected a batch of %d events, got %d", expected, n)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for events")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(received))
+	}
+	for i, action := range []string{"create", "start", "die"} {
+		if received[i].Type != eventtypes.ContainerEventType || received[i].Action != action {
+			t.Fatalf("unexpected event %d: %v", i, received[i])
+		}
+	}
+}
+
+func TestUnixSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "event-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "events.sock")
+
+	e := New()
+	config := types.EventSink{Type: UnixSink, Address: socket, Filters: map[string][]string{"event": {"die"}}}
+	if err := e.SetSinks([]types.EventSink{config}); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// the socket can be reused when the sinks are reloaded
+	if err := e.SetSinks([]types.EventSink{config}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// wait for the connection to be subscribed
+	for start := time.Now(); e.SubscribersCount() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("timeout waiting for the client to subscribe")
+		}
+	}
+	e.Log("start", eventtypes.ContainerEventType, eventtypes.Actor{ID: "a"})
+	e.Log("die", eventtypes.ContainerEventType, eventtypes.Actor{ID: "a"})
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg eventtypes.Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Action != "die" || msg.Actor.ID != "a" {
+		t.Fatalf("unexpected event: %v", msg)
+	}
+}
+
+func TestSetSinksInvalid(t *testing.T) {
+	for _, config := range []types.EventSink{
+		{Type: "kafka", Address: "localhost:9092"},
+		{Type: WebhookSink, Address: "ftp://example.com"},
+		{Type: WebhookSink, Address: "http://example.com", BatchTimeout: "soon"},
+		{Type: UnixSink},
+	} {
+		if err := New().SetSinks([]types.EventSink{config}); err == nil {
+			t.Fatalf("expected an error for %v", config)
+		}
+	}
+}
+
+func TestUnixSinkNotASocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "event-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.sock")
+	if err := ioutil.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New()
+	defer e.Close()
+	if err := e.SetSinks([]types.EventSink{{Type: UnixSink, Address: path}}); err == nil {
+		t.Fatal("expected an error for a path that is not a socket")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the file to be kept: %v", err)
+	}
+}
+
+func TestSetSinksKeepsSinksOnError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "event-sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "events.sock")
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New()
+	defer e.Close()
+	config := types.EventSink{Type: UnixSink, Address: socket}
+	if err := e.SetSinks([]types.EventSink{config}); err != nil {
+		t.Fatal(err)
+	}
+	sinks := e.sinks
+
+	err = e.SetSinks([]types.EventSink{config, {Type: UnixSink, Address: file}})
+	if err == nil {
+		t.Fatal("expected an error for a path that is not a socket")
+	}
+	if len(e.sinks) != 1 || e.sinks[0] != sinks[0] {
+		t.Fatalf("expected the sinks to be kept, got %v", e.sinks)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestWebhookSinkQueueDropsOldest(t *testing.T) {
+	s := &webhookSink{
+		config:  &sinkConfig{EventSink: types.EventSink{Address: "http://localhost"}},
+		batches: make(chan []eventtypes.Message, 2),
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		s.queue([]eventtypes.Message{{ID: id}})
+	}
+	for _, expected := range []string{"b", "c"} {
+		if batch := <-s.batches; batch[0].ID != expected {
+			t.Fatalf("expected the batch of %s, got %s", expected, batch[0].ID)
+		}
+	}
+}
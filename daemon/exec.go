@@ -3,6 +3,7 @@ package daemon
 import (
 	"fmt"
 	"io"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/docker/docker/libcontainerd"
 	"github.com/docker/docker/pkg/pools"
 	"github.com/docker/docker/pkg/signal"
+	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/term"
 )
 
@@ -102,6 +104,16 @@ func (d *Daemon) ContainerExecCreate(name string, config *types.ExecConfig) (str
 	cmd := strslice.StrSlice(config.Cmd)
 	entrypoint, args := d.getEntrypointAndArgs(strslice.StrSlice{}, cmd)
 
+	if config.WorkingDir != "" {
+		config.WorkingDir = filepath.FromSlash(config.WorkingDir) // Ensure in platform semantics
+		if !system.IsAbs(config.WorkingDir) {
+			return "", errors.NewBadRequestError(fmt.Errorf("the working directory '%s' is invalid, it needs to be an absolute path", config.WorkingDir))
+		}
+		if err := checkExecWorkingDir(cntr, config.WorkingDir); err != nil {
+			return "", errors.NewBadRequestError(err)
+		}
+	}
+
 	keys := []byte{}
 	if config.DetachKeys != "" {
 		keys, err = term.ToBytes(config.DetachKeys)
@@ -122,6 +134,7 @@ func (d *Daemon) ContainerExecCreate(name string, config *types.ExecConfig) (str
 	execConfig.Tty = config.Tty
 	execConfig.Privileged = config.Privileged
 	execConfig.User = config.User
+	execConfig.WorkingDir = config.WorkingDir
 
 	linkedEnv, err := d.setupLinkedContainers(cntr)
 	if err != nil {
@@ -31,6 +31,7 @@ type Config struct {
 	Privileged   bool
 	User         string
 	Env          []string
+	WorkingDir   string
 	Pid          int
 }
 
@@ -1,14 +1,40 @@
 package daemon
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/daemon/caps"
 	"github.com/docker/docker/daemon/exec"
 	"github.com/docker/docker/libcontainerd"
+	"github.com/docker/docker/pkg/symlink"
 	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
+// checkExecWorkingDir checks that dir is a directory in the container's mount
+// namespace, which is reached through the root of the container's process.
+func checkExecWorkingDir(c *container.Container, dir string) error {
+	root := fmt.Sprintf("/proc/%d/root", c.State.GetPID())
+	path, err := symlink.FollowSymlinkInScope(filepath.Join(root, dir), root)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("the working directory '%s' does not exist in the container", dir)
+		}
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("the working directory '%s' is not a directory", dir)
+	}
+	return nil
+}
+
 func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainerd.Process) error {
 	if len(ec.User) > 0 {
 		uid, gid, additionalGids, err := getUser(c, ec.User)
@@ -24,6 +50,9 @@ func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainer
 	if ec.Privileged {
 		p.Capabilities = caps.GetAllCapabilities()
 	}
+	if ec.WorkingDir != "" {
+		p.Cwd = &ec.WorkingDir
+	}
 	if apparmor.IsEnabled() {
 		var appArmorProfile string
 		if c.AppArmorProfile != "" {
@@ -6,6 +6,10 @@ import (
 	"github.com/docker/docker/libcontainerd"
 )
 
+func checkExecWorkingDir(c *container.Container, dir string) error {
+	return nil
+}
+
 func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainerd.Process) error {
 	return nil
 }
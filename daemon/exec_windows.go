@@ -6,9 +6,16 @@ import (
 	"github.com/docker/docker/libcontainerd"
 )
 
+// checkExecWorkingDir is a no-op on Windows, where the working directory is
+// checked when the process is created.
+func checkExecWorkingDir(c *container.Container, dir string) error {
+	return nil
+}
+
 func execSetPlatformOpt(c *container.Container, ec *exec.Config, p *libcontainerd.Process) error {
 	// Process arguments need to be escaped before sending to OCI.
 	p.Args = escapeArgs(p.Args)
 	p.User.Username = ec.User
+	p.Cwd = ec.WorkingDir
 	return nil
 }
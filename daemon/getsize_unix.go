@@ -4,6 +4,7 @@ package daemon
 
 import (
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
 )
 
 // getSize returns the real size & virtual size of the container.
@@ -39,3 +40,25 @@ func (daemon *Daemon) getSize(containerID string) (int64, int64) {
 	}
 	return sizeRw, sizeRootfs
 }
+
+// getDiskQuota returns the size limit of the container's writable layer and
+// the space used against it, or nil if it has no size limit.
+func (daemon *Daemon) getDiskQuota(containerID string) *types.DiskQuota {
+	rwlayer, err := daemon.layerStore.GetRWLayer(containerID)
+	if err != nil {
+		logrus.Errorf("Failed to get disk quota of container rootfs %v: %v", containerID, err)
+		return nil
+	}
+	defer daemon.layerStore.ReleaseRWLayer(rwlayer)
+
+	limit, used, err := rwlayer.DiskQuota()
+	if err != nil {
+		logrus.Errorf("Driver %s couldn't return disk quota of container %s: %s",
+			daemon.GraphDriverName(), containerID, err)
+		return nil
+	}
+	if limit == 0 {
+		return nil
+	}
+	return &types.DiskQuota{Limit: limit, Used: used}
+}
@@ -1,6 +1,7 @@
 // +build linux
 
-package overlay
+// Package copy copies directory trees with their metadata.
+package copy
 
 import (
 	"fmt"
@@ -14,25 +15,46 @@ import (
 	rsystem "github.com/opencontainers/runc/libcontainer/system"
 )
 
-type copyFlags int
+// Mode indicates whether to hardlink or copy the content of the files
+type Mode int
 
 const (
-	copyHardlink copyFlags = 1 << iota
+	// Content creates new files, with the content of the source files
+	Content Mode = iota
+	// Hardlink creates hardlinks to the source files
+	Hardlink
 )
 
-func copyRegular(srcPath, dstPath string, mode os.FileMode) error {
-	srcFile, err := os.Open(srcPath)
+// ficlone is the FICLONE ioctl, making a file share the extents of another
+// one on the filesystems supporting reflinks, such as btrfs and xfs.
+const ficlone = 0x40049409
+
+func copyRegular(srcPath, dstPath string, mode os.FileMode, copyWithFileClone *bool) error {
+	// the files are not followed if they were replaced by symlinks since
+	// they were walked, so that the copy cannot read or write out of the
+	// copied trees
+	srcFile, err := os.OpenFile(srcPath, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
 	if err != nil {
 		return err
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE, mode)
+	dstFile, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|syscall.O_NOFOLLOW, mode)
 	if err != nil {
 		return err
 	}
 	defer dstFile.Close()
 
+	if *copyWithFileClone {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFile.Fd(), ficlone, srcFile.Fd())
+		if errno == 0 {
+			return nil
+		}
+		// the files of the tree are on the same filesystem, the content is
+		// copied for all of them once reflinks are known not to be supported
+		*copyWithFileClone = false
+	}
+
 	_, err = pools.Copy(dstFile, srcFile)
 
 	return err
@@ -51,7 +73,11 @@ func copyXattr(srcPath, dstPath, attr string) error {
 	return nil
 }
 
-func copyDir(srcDir, dstDir string, flags copyFlags) error {
+// DirCopy copies the content of srcDir to dstDir, with the metadata of the
+// files. The content of the files is shared with reflinks when the
+// filesystem supports them, and copied otherwise.
+func DirCopy(srcDir, dstDir string, copyMode Mode) error {
+	copyWithFileClone := true
 	err := filepath.Walk(srcDir, func(srcPath string, f os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -77,13 +103,13 @@ func copyDir(srcDir, dstDir string, flags copyFlags) error {
 
 		switch f.Mode() & os.ModeType {
 		case 0: // Regular file
-			if flags&copyHardlink != 0 {
+			if copyMode == Hardlink {
 				isHardlink = true
 				if err := os.Link(srcPath, dstPath); err != nil {
 					return err
 				}
 			} else {
-				if err := copyRegular(srcPath, dstPath, f.Mode()); err != nil {
+				if err := copyRegular(srcPath, dstPath, f.Mode(), &copyWithFileClone); err != nil {
 					return err
 				}
 			}
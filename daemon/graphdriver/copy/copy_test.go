@@ -0,0 +1,62 @@
+// +build linux
+
+package copy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirCopy(t *testing.T) {
+	srcDir, err := ioutil.TempDir("", "srcDir")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir, err := ioutil.TempDir("", "dstDir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dstDir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(srcDir, "dir"), 0750))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "dir", "file"), []byte("content"), 0640))
+	require.NoError(t, os.Symlink("dir/file", filepath.Join(srcDir, "link")))
+
+	require.NoError(t, DirCopy(srcDir, dstDir, Content))
+
+	content, err := ioutil.ReadFile(filepath.Join(dstDir, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	fi, err := os.Stat(filepath.Join(dstDir, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), fi.Mode())
+	fi, err = os.Stat(filepath.Join(dstDir, "dir"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750)|os.ModeDir, fi.Mode())
+
+	link, err := os.Readlink(filepath.Join(dstDir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "dir/file", link)
+}
+
+func TestCopyRegularDoesNotFollowSymlinks(t *testing.T) {
+	srcDir, err := ioutil.TempDir("", "srcDir")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir, err := ioutil.TempDir("", "dstDir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dstDir)
+
+	// the file was replaced by a symlink after it was walked
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, "secret"), []byte("secret"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(srcDir, "secret"), filepath.Join(srcDir, "file")))
+
+	copyWithFileClone := true
+	err = copyRegular(filepath.Join(srcDir, "file"), filepath.Join(dstDir, "file"), 0644, &copyWithFileClone)
+	assert.Error(t, err)
+	_, err = os.Lstat(filepath.Join(dstDir, "file"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/opencontainers/go-digest"
 	"github.com/vbatts/tar-split/tar/storage"
 
 	"github.com/docker/docker/pkg/archive"
@@ -138,6 +139,49 @@ type DiffGetterDriver interface {
 	DiffGetter(id string) (FileGetCloser, error)
 }
 
+// LazyBlob is a layer blob in the stargz format, which is read remotely.
+type LazyBlob interface {
+	io.ReaderAt
+	// Size returns the size of the blob.
+	Size() int64
+	// Digest returns the digest of the blob.
+	Digest() digest.Digest
+	// TOCDigest returns the digest of the table of contents of the blob,
+	// which the content of its files is verified against.
+	TOCDigest() digest.Digest
+}
+
+// LazyDriver is the interface for layered file system drivers that can
+// mount layers whose content is fetched when it is first read. The diff of
+// a lazy layer is the uncompressed stream of its blob, once it is fetched.
+type LazyDriver interface {
+	Driver
+	// ApplyLazy mounts the layer of blob as the content of the layer id,
+	// and returns its size. Only the table of contents and the files
+	// likely to be read first are fetched.
+	ApplyLazy(id, parent string, blob LazyBlob) (size int64, err error)
+	// IsLazy returns whether id is a lazy layer.
+	IsLazy(id string) bool
+	// LazyBlobMissing returns whether content of the lazy layer id is
+	// missing and cannot be fetched, which is the case once the daemon
+	// restarted.
+	LazyBlobMissing(id string) bool
+	// AttachLazy sets the blob the missing content of the lazy layer id
+	// is fetched from.
+	AttachLazy(id string, blob LazyBlob) error
+	// LazyDiffID returns the digest of the uncompressed content of the
+	// lazy layer id, once all of it is fetched and verified.
+	LazyDiffID(id string) (digest.Digest, bool)
+}
+
+// DiskQuotaDriver is the interface for layered file system drivers that can
+// limit the size of a layer with the size storage option.
+type DiskQuotaDriver interface {
+	// DiskQuota returns the size limit of a layer and the space used
+	// against it. The limit is 0 if the layer has no size limit.
+	DiskQuota(id string) (limit, used int64, err error)
+}
+
 // FileGetCloser extends the storage.FileGetter interface with a Close method
 // for cleaning up.
 type FileGetCloser interface {
@@ -167,3 +167,12 @@ func (gdw *NaiveDiffDriver) DiffSize(id, parent string) (size int64, err error)
 
 	return archive.ChangesSize(layerFs, changes), nil
 }
+
+// DiskQuota returns the size limit of a layer and the space used against it,
+// if the underlying driver supports the size storage option.
+func (gdw *NaiveDiffDriver) DiskQuota(id string) (limit, used int64, err error) {
+	if driver, ok := gdw.ProtoDriver.(DiskQuotaDriver); ok {
+		return driver.DiskQuota(id)
+	}
+	return 0, 0, nil
+}
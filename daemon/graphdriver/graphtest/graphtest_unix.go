@@ -4,6 +4,7 @@ package graphtest
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -314,6 +315,89 @@ func DriverTestChanges(t testing.TB, drivername string, driverOptions ...string)
 	}
 }
 
+// DriverTestLazyLayer tests a lazy layer is mounted as the lower layer of
+// the layers above it, and its diff is the stream of its blob
+func DriverTestLazyLayer(t testing.TB, drivername string, driverOptions ...string) {
+	driver := GetDriver(t, drivername, driverOptions...)
+	defer PutDriver(t)
+	lazyDriver, ok := drv.Driver.(graphdriver.LazyDriver)
+	if !ok {
+		t.Skipf("Driver %s does not support lazy layers", drivername)
+	}
+	base := stringid.GenerateRandomID()
+	lazy := stringid.GenerateRandomID()
+	upper := stringid.GenerateRandomID()
+	lazyContent := randomContent(128*128, 3)
+
+	if err := driver.Create(base, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := addFiles(driver, base, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	blob, diffID, err := newLazyBlob([]tarFile{
+		{name: "file-lazy", content: lazyContent},
+		{name: ".wh.file-a"},
+		{name: "dir-b/"},
+		{name: "dir-b/.wh..wh..opq"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Create(lazy, base, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lazyDriver.ApplyLazy(lazy, base, blob); err != nil {
+		t.Skipf("Cannot mount lazy layer: %v", err)
+	}
+	if !lazyDriver.IsLazy(lazy) || lazyDriver.IsLazy(base) {
+		t.Fatal("Expected only the lazy layer to be lazy")
+	}
+	if lazyDriver.LazyBlobMissing(lazy) {
+		t.Fatal("Expected the blob of the lazy layer to be attached")
+	}
+
+	if err := driver.Create(upper, lazy, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFile(driver, upper, "file-lazy", lazyContent); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFile(driver, upper, "file-c", randomContent(128*128, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFileRemoved(driver, upper, "file-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFileRemoved(driver, upper, "dir-b/file-b"); err != nil {
+		t.Fatal(err)
+	}
+
+	arch, err := driver.Diff(lazy, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := diffID.Verifier()
+	if _, err := io.Copy(verifier, arch); err != nil {
+		t.Fatal(err)
+	}
+	arch.Close()
+	if !verifier.Verified() {
+		t.Fatalf("Expected the diff of the lazy layer to be %s", diffID)
+	}
+	if d, ok := lazyDriver.LazyDiffID(lazy); !ok || d != diffID {
+		t.Fatalf("Expected the diff ID of the lazy layer to be verified as %s, got %q", diffID, d)
+	}
+
+	if err := driver.Remove(upper); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Remove(lazy); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func writeRandomFile(path string, size uint64) error {
 	buf := make([]int64, size/8)
 
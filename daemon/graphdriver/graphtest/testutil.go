@@ -1,6 +1,7 @@
 package graphtest
 
 import (
+	"archive/tar"
 	"bytes"
 	"fmt"
 	"io/ioutil"
@@ -8,10 +9,13 @@ import (
 	"os"
 	"path"
 	"sort"
+	"strings"
 
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/stargz"
 	"github.com/docker/docker/pkg/stringid"
+	"github.com/opencontainers/go-digest"
 )
 
 func randomContent(size int, seed int64) []byte {
@@ -340,3 +344,55 @@ func readDir(dir string) ([]os.FileInfo, error) {
 
 	return b, nil
 }
+
+type tarFile struct {
+	name    string
+	content []byte
+}
+
+type lazyBlob struct {
+	*bytes.Reader
+	digest    digest.Digest
+	tocDigest digest.Digest
+}
+
+func (b lazyBlob) Digest() digest.Digest {
+	return b.digest
+}
+
+func (b lazyBlob) TOCDigest() digest.Digest {
+	return b.tocDigest
+}
+
+// newLazyBlob returns a stargz blob of the files, and its diff ID. The names
+// of directories end with a slash.
+func newLazyBlob(files []tarFile) (graphdriver.LazyBlob, digest.Digest, error) {
+	tarBuf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(tarBuf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(f.content))}
+		if strings.HasSuffix(f.name, "/") {
+			hdr.Mode = 0755
+			hdr.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(f.content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := stargz.NewWriter(buf)
+	if err := w.AppendTar(tarBuf); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return lazyBlob{bytes.NewReader(buf.Bytes()), digest.FromBytes(buf.Bytes()), w.TOCDigest()}, w.DiffID(), nil
+}
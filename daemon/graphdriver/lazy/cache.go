@@ -0,0 +1,352 @@
+// +build linux
+
+package lazy
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	cacheBlobName  = "blob"
+	cacheStateName = "state.json"
+
+	// fetchSize is the size of the pieces of the blob fetched in the
+	// background
+	fetchSize = 4 << 20
+)
+
+// cacheState is the state of a cache, saved along with the blob.
+type cacheState struct {
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+	// TOCDigest is the digest of the table of contents of the blob
+	TOCDigest digest.Digest `json:"tocDigest"`
+	// DiffID is the digest of the uncompressed content of the blob, set
+	// once all of it is fetched and verified
+	DiffID digest.Digest `json:"diffID,omitempty"`
+	// Fetched are the sorted, disjoint ranges of the blob which are
+	// fetched
+	Fetched [][2]int64 `json:"fetched"`
+}
+
+// cache is a local copy of a blob, fetched on demand. It is a sparse file,
+// holding the ranges of the blob which were read.
+type cache struct {
+	dir string
+	f   *os.File
+
+	mu     sync.Mutex
+	state  cacheState
+	remote Blob
+	// err is set when the content of the blob does not match its digest
+	err error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// openCache opens the cache in dir, creating it if needed.
+func openCache(dir string, dgst digest.Digest, size int64, tocDigest digest.Digest) (*cache, error) {
+	c := &cache{dir: dir}
+	st, err := ioutil.ReadFile(filepath.Join(dir, cacheStateName))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(st, &c.state); err != nil {
+			return nil, fmt.Errorf("invalid lazy layer cache state: %v", err)
+		}
+		if dgst != "" && (c.state.Digest != dgst || c.state.Size != size || c.state.TOCDigest != tocDigest) {
+			return nil, fmt.Errorf("the lazy layer cache in %s holds %s, not %s", dir, c.state.Digest, dgst)
+		}
+	case os.IsNotExist(err) && dgst != "":
+		c.state = cacheState{Digest: dgst, Size: size, TOCDigest: tocDigest}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		if err := c.saveState(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to open the lazy layer cache: %v", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, cacheBlobName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(c.state.Size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	c.f = f
+	return c, nil
+}
+
+func (c *cache) saveState() error {
+	st, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	return ioutils.AtomicWriteFile(filepath.Join(c.dir, cacheStateName), st, 0600)
+}
+
+// Size returns the size of the blob.
+func (c *cache) Size() int64 {
+	return c.state.Size
+}
+
+// diffID returns the diff ID of the blob, once all of it is fetched and
+// verified.
+func (c *cache) diffID() (digest.Digest, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state.DiffID, c.state.DiffID != ""
+}
+
+// attach sets the remote blob the missing content is fetched from.
+func (c *cache) attach(remote Blob) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.remote == nil {
+		c.remote = remote
+	}
+}
+
+// fetchInBackground starts fetching the missing content of the blob from
+// the remote blob, and verifying it once it is complete.
+func (c *cache) fetchInBackground() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil || c.state.DiffID != "" || (c.remote == nil && !c.complete()) {
+		return
+	}
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.fetchAll(c.stop, c.done)
+}
+
+// missing reports whether content of the blob is missing and cannot be
+// fetched.
+func (c *cache) missing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remote == nil && !c.complete()
+}
+
+func (c *cache) complete() bool {
+	return len(c.state.Fetched) == 1 && c.state.Fetched[0] == [2]int64{0, c.state.Size}
+}
+
+// gaps returns the ranges of [start, end) which are not fetched.
+func (c *cache) gaps(start, end int64) [][2]int64 {
+	var gaps [][2]int64
+	for _, r := range c.state.Fetched {
+		if r[1] <= start {
+			continue
+		}
+		if r[0] >= end {
+			break
+		}
+		if r[0] > start {
+			gaps = append(gaps, [2]int64{start, r[0]})
+		}
+		start = r[1]
+	}
+	if start < end {
+		gaps = append(gaps, [2]int64{start, end})
+	}
+	return gaps
+}
+
+// ReadAt reads the content of the blob, fetching the missing ranges.
+func (c *cache) ReadAt(p []byte, off int64) (int, error) {
+	if off >= c.state.Size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > c.state.Size {
+		end = c.state.Size
+	}
+	c.mu.Lock()
+	gaps, remote, err := c.gaps(off, end), c.remote, c.err
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if len(gaps) > 0 && remote == nil {
+		return 0, fmt.Errorf("the content of the lazy layer blob %s is not available, the image must be pulled again", c.state.Digest)
+	}
+	for _, g := range gaps {
+		if err := c.fetch(remote, g[0], g[1]); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.f.ReadAt(p[:end-off], off)
+	if err == nil && end-off < int64(len(p)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// fetch fetches the range [start, end) of the blob from remote. Concurrent
+// reads may fetch the same range, the content being the same.
+func (c *cache) fetch(remote Blob, start, end int64) error {
+	buf := make([]byte, end-start)
+	n, err := remote.ReadAt(buf, start)
+	if n < len(buf) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to fetch the lazy layer blob %s: %v", c.state.Digest, err)
+	}
+	if _, err := c.f.WriteAt(buf, start); err != nil {
+		return err
+	}
+	// the content is on disk before the state records it
+	if err := c.f.Sync(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Fetched = addRange(c.state.Fetched, [2]int64{start, end})
+	return c.saveState()
+}
+
+// fetchAll fetches the missing content of the blob, and verifies it once it
+// is complete.
+func (c *cache) fetchAll(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		c.mu.Lock()
+		gaps, remote := c.gaps(0, c.state.Size), c.remote
+		c.mu.Unlock()
+		if len(gaps) == 0 {
+			break
+		}
+		g := gaps[0]
+		if g[1]-g[0] > fetchSize {
+			g[1] = g[0] + fetchSize
+		}
+		if err := c.fetch(remote, g[0], g[1]); err != nil {
+			logrus.Warnf("Stopped fetching the lazy layer blob %s in the background: %v", c.state.Digest, err)
+			return
+		}
+	}
+
+	if err := c.verify(); err != nil {
+		logrus.Errorf("Lazy layer blob %s is invalid: %v", c.state.Digest, err)
+	}
+}
+
+// verify checks the content of the complete blob against its digest, and
+// computes its diff ID. The content is discarded if it does not match.
+func (c *cache) verify() error {
+	verifier := c.state.Digest.Verifier()
+	r := io.TeeReader(io.NewSectionReader(c.f, 0, c.state.Size), verifier)
+	diffID := digest.Canonical.Digester()
+	zr, zerr := gzip.NewReader(r)
+	if zerr == nil {
+		_, zerr = io.Copy(diffID.Hash(), zr)
+	}
+	// the rest of the blob is verified even if it is not a gzip stream
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if verifier.Verified() {
+		if zerr != nil {
+			c.err = fmt.Errorf("the lazy layer blob %s is not a valid gzip stream: %v", c.state.Digest, zerr)
+			return c.err
+		}
+		c.state.DiffID = diffID.Digest()
+		return c.saveState()
+	}
+
+	c.err = fmt.Errorf("the content of the lazy layer blob does not match the digest %s", c.state.Digest)
+	c.state.Fetched = nil
+	if err := c.saveState(); err != nil {
+		logrus.Errorf("Failed to reset the lazy layer cache in %s: %v", c.dir, err)
+	}
+	return c.err
+}
+
+// wait fetches the missing content of the blob, and returns once the blob
+// is complete and verified.
+func (c *cache) wait() error {
+	c.mu.Lock()
+	done := c.done
+	c.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	for retry := true; ; retry = false {
+		c.mu.Lock()
+		err, verified, complete, remote := c.err, c.state.DiffID != "", c.complete(), c.remote
+		c.mu.Unlock()
+		switch {
+		case err != nil:
+			return err
+		case verified:
+			return nil
+		case remote == nil && !complete:
+			return fmt.Errorf("the content of the lazy layer blob %s is not available, the image must be pulled again", c.state.Digest)
+		case !retry:
+			return fmt.Errorf("failed to fetch the lazy layer blob %s", c.state.Digest)
+		}
+		// the background fetch stopped on an error, or the complete blob
+		// was not verified yet, it is tried again
+		c.fetchAll(make(chan struct{}), make(chan struct{}))
+	}
+}
+
+func (c *cache) close() error {
+	c.mu.Lock()
+	stop, done := c.stop, c.done
+	c.stop = nil
+	c.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	return c.f.Close()
+}
+
+// addRange adds r to the sorted, disjoint ranges, merging the ranges it
+// overlaps or touches.
+func addRange(ranges [][2]int64, r [2]int64) [][2]int64 {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i][1] >= r[0] })
+	j := i
+	for ; j < len(ranges) && ranges[j][0] <= r[1]; j++ {
+		if ranges[j][0] < r[0] {
+			r[0] = ranges[j][0]
+		}
+		if ranges[j][1] > r[1] {
+			r[1] = ranges[j][1]
+		}
+	}
+	merged := append([][2]int64{}, ranges[:i]...)
+	merged = append(merged, r)
+	return append(merged, ranges[j:]...)
+}
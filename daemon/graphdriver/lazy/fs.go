@@ -0,0 +1,322 @@
+// +build linux
+
+package lazy
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/stargz"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// node is a file of the file system of a lazy layer.
+type node struct {
+	nodefs.Node
+	fs *fileSystem
+
+	attr     fuse.Attr
+	children map[string]*node
+	link     []byte
+	xattrs   map[string][]byte
+
+	// file is the name of the content of a regular file in the blob
+	file     string
+	openOnce sync.Once
+	fr       *stargz.FileReader
+	openErr  error
+}
+
+// fileSystem is the tree of the files of a stargz blob, as overlay expects
+// the diff directory of a layer: whiteouts are character devices, and
+// opaque directories have the trusted.overlay.opaque attribute.
+type fileSystem struct {
+	r     *stargz.Reader
+	root  *node
+	nodes uint64
+}
+
+func newFileSystem(r *stargz.Reader, opts Options) (*fileSystem, error) {
+	fs := &fileSystem{r: r}
+	root := fs.newNode(syscall.S_IFDIR | 0755)
+	fs.root = root
+
+	for _, e := range r.Entries() {
+		if e.Name == "" && e.Type == "dir" {
+			if err := fs.setAttrs(root, e, opts); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		dir, base := path.Split(e.Name)
+		if dir == "" && (base == stargz.PrefetchLandmark || base == stargz.NoPrefetchLandmark) {
+			// the landmarks are not part of the layer
+			continue
+		}
+		parent := fs.mkdirAll(root, strings.TrimSuffix(dir, "/"))
+
+		if base == archive.WhiteoutOpaqueDir {
+			if parent.xattrs == nil {
+				parent.xattrs = make(map[string][]byte)
+			}
+			parent.xattrs["trusted.overlay.opaque"] = []byte("y")
+			continue
+		}
+		if strings.HasPrefix(base, archive.WhiteoutPrefix) {
+			n := fs.newNode(syscall.S_IFCHR)
+			fs.link(parent, strings.TrimPrefix(base, archive.WhiteoutPrefix), n)
+			continue
+		}
+
+		if e.Type == "hardlink" {
+			target := fs.lookup(root, e.LinkName)
+			if target == nil || target.isDir() {
+				return nil, fmt.Errorf("invalid hardlink %q to %q in the lazy layer", e.Name, e.LinkName)
+			}
+			target.attr.Nlink++
+			fs.link(parent, base, target)
+			continue
+		}
+
+		var mode uint32
+		switch e.Type {
+		case "dir":
+			// directories may be implicitly created by their children
+			// first, or appear several times in the stream
+			if n, ok := parent.children[base]; ok && n.isDir() {
+				if err := fs.setAttrs(n, e, opts); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			mode = syscall.S_IFDIR
+		case "reg":
+			mode = syscall.S_IFREG
+		case "symlink":
+			mode = syscall.S_IFLNK
+		case "char":
+			mode = syscall.S_IFCHR
+		case "block":
+			mode = syscall.S_IFBLK
+		case "fifo":
+			mode = syscall.S_IFIFO
+		default:
+			return nil, fmt.Errorf("unsupported type %q of %q in the lazy layer", e.Type, e.Name)
+		}
+		n := fs.newNode(mode)
+		if err := fs.setAttrs(n, e, opts); err != nil {
+			return nil, err
+		}
+		switch e.Type {
+		case "reg":
+			n.attr.Size = uint64(e.Size)
+			n.attr.Blocks = (n.attr.Size + 511) / 512
+			n.file = e.Name
+		case "symlink":
+			n.link = []byte(e.LinkName)
+			n.attr.Size = uint64(len(n.link))
+		case "char", "block":
+			n.attr.Rdev = encodeDev(uint32(e.DevMajor), uint32(e.DevMinor))
+		}
+		fs.link(parent, base, n)
+	}
+	return fs, nil
+}
+
+func (fs *fileSystem) newNode(mode uint32) *node {
+	fs.nodes++
+	n := &node{
+		Node: nodefs.NewDefaultNode(),
+		fs:   fs,
+		attr: fuse.Attr{
+			Ino:     fs.nodes,
+			Mode:    mode,
+			Nlink:   1,
+			Blksize: 4096,
+		},
+	}
+	if n.isDir() {
+		n.children = make(map[string]*node)
+		n.attr.Nlink = 2
+	}
+	return n
+}
+
+func (n *node) isDir() bool {
+	return n.attr.Mode&syscall.S_IFMT == syscall.S_IFDIR
+}
+
+// link adds n to the directory dir, replacing the existing file of that
+// name.
+func (fs *fileSystem) link(dir *node, name string, n *node) {
+	if old, ok := dir.children[name]; ok {
+		fs.unlink(dir, name, old)
+	}
+	dir.children[name] = n
+	if n.isDir() {
+		dir.attr.Nlink++
+	}
+}
+
+func (fs *fileSystem) unlink(dir *node, name string, n *node) {
+	delete(dir.children, name)
+	if n.isDir() {
+		dir.attr.Nlink--
+	} else if n.attr.Nlink > 1 {
+		n.attr.Nlink--
+	}
+}
+
+// mkdirAll returns the directory at p, creating the missing directories
+// with the default permissions.
+func (fs *fileSystem) mkdirAll(root *node, p string) *node {
+	dir := root
+	if p == "" {
+		return dir
+	}
+	for _, name := range strings.Split(p, "/") {
+		n, ok := dir.children[name]
+		if !ok || !n.isDir() {
+			n = fs.newNode(syscall.S_IFDIR | 0755)
+			fs.link(dir, name, n)
+		}
+		dir = n
+	}
+	return dir
+}
+
+func (fs *fileSystem) lookup(root *node, p string) *node {
+	n := root
+	for _, name := range strings.Split(p, "/") {
+		if n = n.children[name]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+func (fs *fileSystem) setAttrs(n *node, e *stargz.TOCEntry, opts Options) error {
+	uid, err := idtools.ToHost(e.UID, opts.UIDMaps)
+	if err != nil {
+		return err
+	}
+	gid, err := idtools.ToHost(e.GID, opts.GIDMaps)
+	if err != nil {
+		return err
+	}
+	n.attr.Mode = n.attr.Mode&syscall.S_IFMT | uint32(e.Mode)&07777
+	n.attr.Uid = uint32(uid)
+	n.attr.Gid = uint32(gid)
+	mtime := e.ModTime()
+	n.attr.SetTimes(&mtime, &mtime, &mtime)
+	for k, v := range e.Xattrs {
+		if n.xattrs == nil {
+			n.xattrs = make(map[string][]byte)
+		}
+		n.xattrs[k] = v
+	}
+	return nil
+}
+
+// OnMount adds the inodes of the tree once the root is mounted.
+func (n *node) OnMount(conn *nodefs.FileSystemConnector) {
+	n.addInodes()
+}
+
+func (n *node) addInodes() {
+	for name, child := range n.children {
+		if inode := child.Inode(); inode != nil {
+			// a hardlink to a file added before
+			n.Inode().AddChild(name, inode)
+			continue
+		}
+		n.Inode().NewChild(name, child.isDir(), child)
+		child.addInodes()
+	}
+}
+
+// Deletable returns false, the nodes of the tree are kept until the file
+// system is unmounted.
+func (n *node) Deletable() bool {
+	return false
+}
+
+func (n *node) GetAttr(out *fuse.Attr, file nodefs.File, context *fuse.Context) fuse.Status {
+	*out = n.attr
+	return fuse.OK
+}
+
+func (n *node) Readlink(context *fuse.Context) ([]byte, fuse.Status) {
+	if n.attr.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+		return nil, fuse.EINVAL
+	}
+	return n.link, fuse.OK
+}
+
+func (n *node) Open(flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		return nil, fuse.EROFS
+	}
+	// the content is read through the node, it does not change
+	return &nodefs.WithFlags{FuseFlags: fuse.FOPEN_KEEP_CACHE}, fuse.OK
+}
+
+func (n *node) Read(file nodefs.File, dest []byte, off int64, context *fuse.Context) (fuse.ReadResult, fuse.Status) {
+	if n.file == "" || off >= int64(n.attr.Size) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	n.openOnce.Do(func() {
+		n.fr, n.openErr = n.fs.r.OpenFile(n.file)
+	})
+	if n.openErr != nil {
+		logrus.Errorf("Failed to open %q of the lazy layer: %v", n.file, n.openErr)
+		return nil, fuse.EIO
+	}
+	c, err := n.fr.ReadAt(dest, off)
+	if err != nil && err != io.EOF {
+		logrus.Errorf("Failed to read %q of the lazy layer: %v", n.file, err)
+		return nil, fuse.EIO
+	}
+	return fuse.ReadResultData(dest[:c]), fuse.OK
+}
+
+func (n *node) GetXAttr(attribute string, context *fuse.Context) ([]byte, fuse.Status) {
+	v, ok := n.xattrs[attribute]
+	if !ok {
+		return nil, fuse.ENOATTR
+	}
+	return v, fuse.OK
+}
+
+func (n *node) ListXAttr(context *fuse.Context) ([]string, fuse.Status) {
+	var names []string
+	for name := range n.xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, fuse.OK
+}
+
+func (n *node) StatFs() *fuse.StatfsOut {
+	return &fuse.StatfsOut{
+		Files:   n.fs.nodes,
+		Bsize:   4096,
+		NameLen: 255,
+		Frsize:  4096,
+	}
+}
+
+// encodeDev encodes a device number the way the kernel expects it in the
+// attributes of FUSE files.
+func encodeDev(major, minor uint32) uint32 {
+	return minor&0xff | major<<8 | (minor&^0xff)<<12
+}
@@ -0,0 +1,63 @@
+// +build linux
+
+package lazy
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// cacheTimeout is how long the kernel caches the entries and attributes of
+// the files, which do not change.
+const cacheTimeout = time.Hour
+
+// server serves the file system of a lazy layer through FUSE.
+type server struct {
+	target string
+	srv    *fuse.Server
+	done   chan struct{}
+}
+
+// mountFileSystem mounts fs at target, and serves it until it is unmounted.
+func mountFileSystem(target string, fs *fileSystem) (*server, error) {
+	conn := nodefs.NewFileSystemConnector(fs.root, &nodefs.Options{
+		EntryTimeout:    cacheTimeout,
+		AttrTimeout:     cacheTimeout,
+		NegativeTimeout: cacheTimeout,
+	})
+	srv, err := fuse.NewServer(conn.RawFS(), target, &fuse.MountOptions{
+		AllowOther:       true,
+		Options:          []string{"default_permissions"},
+		FsName:           "stargz",
+		Name:             "stargz",
+		DirectMount:      true,
+		DirectMountFlags: syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount the lazy layer at %s: %v", target, err)
+	}
+
+	s := &server{target: target, srv: srv, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		srv.Serve()
+	}()
+	if err := srv.WaitMount(); err != nil {
+		s.unmount()
+		return nil, fmt.Errorf("failed to mount the lazy layer at %s: %v", target, err)
+	}
+	return s, nil
+}
+
+// unmount unmounts the file system, and waits for the server to stop.
+func (s *server) unmount() error {
+	if err := syscall.Unmount(s.target, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL {
+		return err
+	}
+	<-s.done
+	return nil
+}
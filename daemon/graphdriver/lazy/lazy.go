@@ -0,0 +1,188 @@
+// +build linux
+
+// Package lazy mounts layers whose content is fetched on demand. The blob of
+// a lazy layer is in the stargz format, its files are served through FUSE
+// from a local cache of the blob, which fetches the ranges of the blob read
+// from the registry. The content read first is fetched eagerly when the
+// layer is created, the rest of the blob is fetched in the background.
+package lazy
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/stargz"
+	"github.com/opencontainers/go-digest"
+)
+
+// Blob is a stargz blob which is read remotely.
+type Blob interface {
+	io.ReaderAt
+	// Size returns the size of the blob.
+	Size() int64
+	// Digest returns the digest of the blob.
+	Digest() digest.Digest
+	// TOCDigest returns the digest of the table of contents of the blob,
+	// which the content of its files is verified against.
+	TOCDigest() digest.Digest
+}
+
+// Options are the options of the mount of a lazy layer.
+type Options struct {
+	UIDMaps []idtools.IDMap
+	GIDMaps []idtools.IDMap
+}
+
+// Layer is a mounted lazy layer.
+type Layer struct {
+	target string
+	cache  *cache
+	r      *stargz.Reader
+	srv    *server
+}
+
+// Supported returns an error if lazy layers cannot be mounted.
+func Supported() error {
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		return fmt.Errorf("lazy layers need FUSE: %v", err)
+	}
+	return nil
+}
+
+// Mount mounts the layer of the blob at target, keeping the cache of the
+// blob in dir. blob is nil when a layer created before is mounted again,
+// its missing content cannot be read until a blob is attached.
+func Mount(dir, target string, blob Blob, opts Options) (*Layer, error) {
+	var (
+		dgst, tocDigest digest.Digest
+		size            int64
+	)
+	if blob != nil {
+		dgst, size, tocDigest = blob.Digest(), blob.Size(), blob.TOCDigest()
+	}
+	c, err := openCache(dir, dgst, size, tocDigest)
+	if err != nil {
+		return nil, err
+	}
+	if blob != nil {
+		c.attach(blob)
+	}
+
+	l, err := mount(c, target, blob != nil, opts)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	// the rest of the blob is fetched in the background once the eager
+	// content is
+	c.fetchInBackground()
+	return l, nil
+}
+
+func mount(c *cache, target string, prefetch bool, opts Options) (*Layer, error) {
+	// the table of contents is fetched when the blob is opened
+	r, err := stargz.Open(c, c.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the lazy layer blob %s: %v", c.state.Digest, err)
+	}
+	// the files are read from the chunks matching the digests of the table
+	// of contents, which must be the one of the manifest
+	if r.TOCDigest() != c.state.TOCDigest {
+		return nil, fmt.Errorf("the table of contents of the lazy layer blob %s does not match the digest %q", c.state.Digest, c.state.TOCDigest)
+	}
+	if prefetch {
+		// the files before the prefetch landmark are likely to be read when
+		// a container starts
+		if e, ok := r.Lookup(stargz.PrefetchLandmark); ok && e.Offset > 0 {
+			if _, err := c.ReadAt(make([]byte, e.Offset), 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	fs, err := newFileSystem(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, err
+	}
+	srv, err := mountFileSystem(target, fs)
+	if err != nil {
+		return nil, err
+	}
+	return &Layer{target: target, cache: c, r: r, srv: srv}, nil
+}
+
+// Size returns the size of the files of the layer.
+func (l *Layer) Size() int64 {
+	var size int64
+	for _, e := range l.r.Entries() {
+		if e.Type == "reg" {
+			size += e.Size
+		}
+	}
+	return size
+}
+
+// Digest returns the digest of the blob of the layer.
+func (l *Layer) Digest() digest.Digest {
+	return l.cache.state.Digest
+}
+
+// Missing reports whether content of the layer is missing and cannot be
+// read until a blob is attached.
+func (l *Layer) Missing() bool {
+	return l.cache.missing()
+}
+
+// DiffID returns the digest of the uncompressed content of the blob of the
+// layer, once all of it is fetched and verified against the digest of the
+// blob.
+func (l *Layer) DiffID() (digest.Digest, bool) {
+	return l.cache.diffID()
+}
+
+// Attach sets the blob the missing content of the layer is fetched from.
+func (l *Layer) Attach(blob Blob) error {
+	if blob.Digest() != l.cache.state.Digest || blob.Size() != l.cache.state.Size || blob.TOCDigest() != l.cache.state.TOCDigest {
+		return fmt.Errorf("blob %s does not match the lazy layer blob %s", blob.Digest(), l.cache.state.Digest)
+	}
+	l.cache.attach(blob)
+	l.cache.fetchInBackground()
+	return nil
+}
+
+// Diff returns the uncompressed tar stream of the blob, once all its content
+// is fetched and verified.
+func (l *Layer) Diff() (io.ReadCloser, error) {
+	if err := l.cache.wait(); err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(io.NewSectionReader(l.cache, 0, l.cache.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return ioutils.NewReadCloserWrapper(zr, zr.Close), nil
+}
+
+// Unmount unmounts the layer, and stops fetching its content.
+func (l *Layer) Unmount() error {
+	if err := l.srv.unmount(); err != nil {
+		return err
+	}
+	return l.cache.close()
+}
+
+// UnmountStale unmounts target if a lazy layer is still mounted there after
+// the daemon stopped.
+func UnmountStale(target string) error {
+	if err := syscall.Unmount(target, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+// +build linux
+
+package lazy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/pkg/stargz"
+	"github.com/docker/docker/pkg/system"
+	"github.com/opencontainers/go-digest"
+)
+
+type testBlob struct {
+	data      []byte
+	digest    digest.Digest
+	tocDigest digest.Digest
+
+	mu    sync.Mutex
+	reads int
+}
+
+func newTestBlob(data []byte) *testBlob {
+	return &testBlob{data: data, digest: digest.FromBytes(data)}
+}
+
+func (b *testBlob) ReadAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	b.reads++
+	b.mu.Unlock()
+	return bytes.NewReader(b.data).ReadAt(p, off)
+}
+
+func (b *testBlob) Size() int64 {
+	return int64(len(b.data))
+}
+
+func (b *testBlob) Digest() digest.Digest {
+	return b.digest
+}
+
+func (b *testBlob) TOCDigest() digest.Digest {
+	return b.tocDigest
+}
+
+type testFile struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// makeBlob returns a stargz blob of the files, its diff ID and the digest of
+// its table of contents.
+func makeBlob(t *testing.T, files []testFile) ([]byte, digest.Digest, digest.Digest) {
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	for _, f := range files {
+		h := &tar.Header{
+			Name:     f.name,
+			Typeflag: f.typeflag,
+			Linkname: f.linkname,
+			Mode:     0644,
+			Size:     int64(len(f.content)),
+		}
+		if f.typeflag == tar.TypeDir {
+			h.Mode = 0755
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	w := stargz.NewWriter(buf)
+	w.ChunkSize = 64
+	if err := w.AppendTar(tarBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), w.DiffID(), w.TOCDigest()
+}
+
+func TestAddRange(t *testing.T) {
+	var ranges [][2]int64
+	for _, r := range [][2]int64{{10, 20}, {30, 40}, {0, 5}, {20, 25}, {5, 10}, {50, 60}, {24, 55}} {
+		ranges = addRange(ranges, r)
+	}
+	if len(ranges) != 1 || ranges[0] != [2]int64{0, 60} {
+		t.Fatalf("expected the ranges to be merged in [0, 60), got %v", ranges)
+	}
+}
+
+func TestCacheFetchesOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lazy-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte(strings.Repeat("0123456789", 100))
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	gz.Write(content)
+	gz.Close()
+	blob := newTestBlob(buf.Bytes())
+	c, err := openCache(dir, blob.Digest(), blob.Size(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.attach(blob)
+
+	p := make([]byte, 20)
+	for i := 0; i < 2; i++ {
+		if _, err := c.ReadAt(p, 5); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, blob.data[5:25]) {
+			t.Fatalf("unexpected content %q", p)
+		}
+	}
+	if blob.reads != 1 {
+		t.Fatalf("expected the range to be fetched once, got %d reads", blob.reads)
+	}
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fetched ranges can be read without the remote blob
+	c, err = openCache(dir, "", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	if !c.missing() {
+		t.Fatal("expected content to be missing without the remote blob")
+	}
+	if _, err := c.ReadAt(p[:10], 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadAt(p, 20); err == nil {
+		t.Fatal("expected the read of missing content to fail")
+	}
+
+	// the content is verified once it is complete
+	c.attach(blob)
+	if err := c.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if c.missing() {
+		t.Fatal("expected the content to be complete")
+	}
+	if diffID, ok := c.diffID(); !ok || diffID != digest.FromBytes(content) {
+		t.Fatalf("expected the diff ID %s, got %q", digest.FromBytes(content), diffID)
+	}
+}
+
+func TestCacheVerifiesDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lazy-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	blob := newTestBlob([]byte("content"))
+	blob.digest = digest.FromString("other content")
+	c, err := openCache(dir, blob.Digest(), blob.Size(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	c.attach(blob)
+	if err := c.wait(); err == nil || !strings.Contains(err.Error(), "does not match the digest") {
+		t.Fatalf("expected a digest mismatch error, got %v", err)
+	}
+}
+
+func TestMount(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("lazy layers can only be mounted by root")
+	}
+	if err := Supported(); err != nil {
+		t.Skip(err)
+	}
+
+	data, diffID, tocDigest := makeBlob(t, []testFile{
+		{name: "bin/", typeflag: tar.TypeDir},
+		{name: "bin/sh", typeflag: tar.TypeReg, content: strings.Repeat("shell", 100)},
+		{name: stargz.PrefetchLandmark, typeflag: tar.TypeReg, content: "f"},
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "etc/hostname", typeflag: tar.TypeReg, content: "lazy"},
+		{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "etc/hostname"},
+		{name: "hardlink", typeflag: tar.TypeLink, linkname: "bin/sh"},
+	})
+	blob := newTestBlob(data)
+	blob.tocDigest = tocDigest
+
+	dir, err := ioutil.TempDir("", "lazy-mount-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "diff")
+
+	// the table of contents must match the digest of the manifest
+	other := newTestBlob(data)
+	other.tocDigest = digest.FromString("other table of contents")
+	if _, err := Mount(filepath.Join(dir, "other"), target, other, Options{}); err == nil || !strings.Contains(err.Error(), "does not match the digest") {
+		t.Fatalf("expected a table of contents digest mismatch error, got %v", err)
+	}
+
+	l, err := Mount(filepath.Join(dir, "cache"), target, blob, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if l != nil {
+			l.Unmount()
+		}
+	}()
+
+	content, err := ioutil.ReadFile(filepath.Join(target, "bin/sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != strings.Repeat("shell", 100) {
+		t.Fatalf("unexpected content of bin/sh: %q", content)
+	}
+	if link, err := os.Readlink(filepath.Join(target, "link")); err != nil || link != "etc/hostname" {
+		t.Fatalf("expected the link to etc/hostname, got %q: %v", link, err)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(filepath.Join(target, "hardlink"), &st); err != nil || st.Nlink != 2 {
+		t.Fatalf("expected 2 links to bin/sh, got %d: %v", st.Nlink, err)
+	}
+	if err := syscall.Lstat(filepath.Join(target, "etc/removed"), &st); err != nil || st.Mode&syscall.S_IFMT != syscall.S_IFCHR || st.Rdev != 0 {
+		t.Fatalf("expected etc/removed to be a whiteout, got %+v: %v", st, err)
+	}
+	if opaque, err := system.Lgetxattr(filepath.Join(target, "etc"), "trusted.overlay.opaque"); err != nil || string(opaque) != "y" {
+		t.Fatalf("expected etc to be opaque, got %q: %v", opaque, err)
+	}
+	entries, err := ioutil.ReadDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, " "); got != "bin etc hardlink link" {
+		t.Fatalf("unexpected files %q", got)
+	}
+
+	rc, err := l.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := diffID.Verifier()
+	_, err = io.Copy(verifier, rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifier.Verified() {
+		t.Fatalf("expected the diff to match %s", diffID)
+	}
+	if d, ok := l.DiffID(); !ok || d != diffID {
+		t.Fatalf("expected the diff ID %s to be verified, got %q", diffID, d)
+	}
+
+	// the layer is mounted again from the cache
+	if err := l.Unmount(); err != nil {
+		t.Fatal(err)
+	}
+	l = nil
+	reads := blob.reads
+	if l, err = Mount(filepath.Join(dir, "cache"), target, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(filepath.Join(target, "etc/hostname")); err != nil || string(content) != "lazy" {
+		t.Fatalf("unexpected content of etc/hostname: %q: %v", content, err)
+	}
+	if blob.reads != reads || l.Missing() {
+		t.Fatal("expected the layer to be read from the cache")
+	}
+}
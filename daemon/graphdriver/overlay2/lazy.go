@@ -0,0 +1,161 @@
+// +build linux
+
+package overlay2
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/lazy"
+	"github.com/opencontainers/go-digest"
+)
+
+// The content of a lazy layer is a file system served through FUSE from a
+// cache of its blob, the "lazy" directory of the layer. The file system is
+// mounted on the "diff" directory, so that the layers above it use it as a
+// regular lower directory.
+const lazyDir = "lazy"
+
+func (d *Driver) lazyOptions() lazy.Options {
+	return lazy.Options{UIDMaps: d.uidMaps, GIDMaps: d.gidMaps}
+}
+
+// ApplyLazy mounts the layer of blob as the content of the layer id.
+func (d *Driver) ApplyLazy(id, parent string, blob graphdriver.LazyBlob) (int64, error) {
+	if err := lazy.Supported(); err != nil {
+		return 0, err
+	}
+	if !d.isParent(id, parent) {
+		return 0, fmt.Errorf("cannot mount lazy layer %s on %s", id, parent)
+	}
+	d.locker.Lock(id)
+	defer d.locker.Unlock(id)
+
+	dir := d.dir(id)
+	l, err := lazy.Mount(path.Join(dir, lazyDir), d.getDiffPath(id), blob, d.lazyOptions())
+	if err != nil {
+		os.RemoveAll(path.Join(dir, lazyDir))
+		return 0, err
+	}
+
+	d.lazyMu.Lock()
+	d.lazyLayers[id] = l
+	d.lazyMu.Unlock()
+	return l.Size(), nil
+}
+
+// IsLazy returns whether id is a lazy layer.
+func (d *Driver) IsLazy(id string) bool {
+	_, err := os.Stat(path.Join(d.dir(id), lazyDir))
+	return err == nil
+}
+
+// LazyBlobMissing returns whether content of the lazy layer id is missing
+// and cannot be fetched.
+func (d *Driver) LazyBlobMissing(id string) bool {
+	d.lazyMu.Lock()
+	l, ok := d.lazyLayers[id]
+	d.lazyMu.Unlock()
+	return !ok || l.Missing()
+}
+
+// LazyDiffID returns the diff ID computed from the content of the lazy layer
+// id, once all of it is fetched and verified.
+func (d *Driver) LazyDiffID(id string) (digest.Digest, bool) {
+	d.lazyMu.Lock()
+	l, ok := d.lazyLayers[id]
+	d.lazyMu.Unlock()
+	if !ok {
+		return "", false
+	}
+	return l.DiffID()
+}
+
+// AttachLazy sets the blob the missing content of the lazy layer id is
+// fetched from. The layer is mounted if it could not be mounted when the
+// driver was initialized.
+func (d *Driver) AttachLazy(id string, blob graphdriver.LazyBlob) error {
+	d.locker.Lock(id)
+	defer d.locker.Unlock(id)
+
+	d.lazyMu.Lock()
+	defer d.lazyMu.Unlock()
+	if l, ok := d.lazyLayers[id]; ok {
+		return l.Attach(blob)
+	}
+	l, err := lazy.Mount(path.Join(d.dir(id), lazyDir), d.getDiffPath(id), blob, d.lazyOptions())
+	if err != nil {
+		return err
+	}
+	d.lazyLayers[id] = l
+	return nil
+}
+
+// lazyDiff returns the diff of the lazy layer id, once its content is
+// fetched.
+func (d *Driver) lazyDiff(id string) (io.ReadCloser, error) {
+	d.lazyMu.Lock()
+	l, ok := d.lazyLayers[id]
+	d.lazyMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("lazy layer %s is not mounted", id)
+	}
+	return l.Diff()
+}
+
+// unmountLazy unmounts the lazy layer id, if it is mounted.
+func (d *Driver) unmountLazy(id string) error {
+	d.lazyMu.Lock()
+	l, ok := d.lazyLayers[id]
+	delete(d.lazyLayers, id)
+	d.lazyMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return l.Unmount()
+}
+
+// remountLazyLayers mounts the lazy layers again when the driver is
+// initialized. Their content which was not fetched yet cannot be read until
+// their image is pulled again.
+func (d *Driver) remountLazyLayers() {
+	dirs, err := ioutil.ReadDir(d.home)
+	if err != nil {
+		logrus.Warnf("Failed to list the layers of %s: %v", d.home, err)
+		return
+	}
+	for _, fi := range dirs {
+		id := fi.Name()
+		if !fi.IsDir() || id == linkDir || !d.IsLazy(id) {
+			continue
+		}
+		diffDir := d.getDiffPath(id)
+		if err := lazy.UnmountStale(diffDir); err != nil {
+			logrus.Warnf("Failed to unmount lazy layer %s: %v", id, err)
+			continue
+		}
+		l, err := lazy.Mount(path.Join(d.dir(id), lazyDir), diffDir, nil, d.lazyOptions())
+		if err != nil {
+			logrus.Warnf("Failed to mount lazy layer %s, its image must be pulled again: %v", id, err)
+			continue
+		}
+		d.lazyLayers[id] = l
+	}
+}
+
+// unmountLazyLayers unmounts the lazy layers when the driver is cleaned up.
+func (d *Driver) unmountLazyLayers() {
+	d.lazyMu.Lock()
+	defer d.lazyMu.Unlock()
+	for id, l := range d.lazyLayers {
+		if err := l.Unmount(); err != nil {
+			logrus.Warnf("Failed to unmount lazy layer %s: %v", id, err)
+		}
+		delete(d.lazyLayers, id)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/Sirupsen/logrus"
 
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/lazy"
 	"github.com/docker/docker/daemon/graphdriver/overlayutils"
 	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/archive"
@@ -101,6 +102,9 @@ type Driver struct {
 	naiveDiff     graphdriver.DiffDriver
 	supportsDType bool
 	locker        *locker.Locker
+
+	lazyMu     sync.Mutex
+	lazyLayers map[string]*lazy.Layer
 }
 
 var (
@@ -194,14 +198,18 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 		ctr:           graphdriver.NewRefCounter(graphdriver.NewFsChecker(graphdriver.FsMagicOverlay)),
 		supportsDType: supportsDType,
 		locker:        locker.New(),
+		lazyLayers:    make(map[string]*lazy.Layer),
 	}
 
 	d.naiveDiff = graphdriver.NewNaiveDiffDriver(d, uidMaps, gidMaps)
+	d.remountLazyLayers()
 
-	if backingFs == "xfs" {
-		// Try to enable project quota support over xfs.
+	if backingFs == "xfs" || backingFs == "extfs" {
+		// Try to enable project quota support over xfs or ext4.
 		if d.quotaCtl, err = quota.NewControl(home); err == nil {
 			projectQuotaSupported = true
+		} else {
+			logrus.Debugf("overlay2: project quotas are not supported on %s: %v", home, err)
 		}
 	}
 
@@ -303,9 +311,10 @@ func (d *Driver) GetMetadata(id string) (map[string]string, error) {
 }
 
 // Cleanup any state created by overlay which should be cleaned when daemon
-// is being shutdown. For now, we just have to unmount the bind mounted
-// we had created.
+// is being shutdown. For now, we just have to unmount the lazy layers and
+// the bind mounted we had created.
 func (d *Driver) Cleanup() error {
+	d.unmountLazyLayers()
 	return mount.Unmount(d.home)
 }
 
@@ -320,7 +329,7 @@ func (d *Driver) CreateReadWrite(id, parent string, opts *graphdriver.CreateOpts
 func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) (retErr error) {
 
 	if opts != nil && len(opts.StorageOpt) != 0 && !projectQuotaSupported {
-		return fmt.Errorf("--storage-opt is supported only for overlay over xfs with 'pquota' mount option")
+		return fmt.Errorf("--storage-opt is supported only for overlay over xfs with 'pquota' mount option or ext4 with 'prjquota' mount option")
 	}
 
 	dir := d.dir(id)
@@ -416,6 +425,22 @@ func (d *Driver) parseStorageOpt(storageOpt map[string]string, driver *Driver) e
 	return nil
 }
 
+// DiskQuota returns the size limit of a layer set with the size storage
+// option, and the space used against it.
+func (d *Driver) DiskQuota(id string) (int64, int64, error) {
+	if d.quotaCtl == nil {
+		return 0, 0, nil
+	}
+	var q quota.Quota
+	if err := d.quotaCtl.GetQuota(d.dir(id), &q); err != nil {
+		if err == quota.ErrQuotaNotSet {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	return int64(q.Size), int64(q.Used), nil
+}
+
 func (d *Driver) getLower(parent string) (string, error) {
 	parentDir := d.dir(parent)
 
@@ -467,6 +492,9 @@ func (d *Driver) getLowerDirs(id string) ([]string, error) {
 func (d *Driver) Remove(id string) error {
 	d.locker.Lock(id)
 	defer d.locker.Unlock(id)
+	if err := d.unmountLazy(id); err != nil {
+		return err
+	}
 	dir := d.dir(id)
 	lid, err := ioutil.ReadFile(path.Join(dir, "link"))
 	if err == nil {
@@ -660,6 +688,9 @@ func (d *Driver) DiffSize(id, parent string) (size int64, err error) {
 // Diff produces an archive of the changes between the specified
 // layer and its parent layer which may be "".
 func (d *Driver) Diff(id, parent string) (io.ReadCloser, error) {
+	if d.IsLazy(id) {
+		return d.lazyDiff(id)
+	}
 	if useNaiveDiff(d.home) || !d.isParent(id, parent) {
 		return d.naiveDiff.Diff(id, parent)
 	}
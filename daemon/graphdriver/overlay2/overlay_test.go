@@ -78,6 +78,10 @@ func TestOverlayChanges(t *testing.T) {
 	graphtest.DriverTestChanges(t, driverName)
 }
 
+func TestOverlayLazyLayer(t *testing.T) {
+	graphtest.DriverTestLazyLayer(t, driverName)
+}
+
 func TestOverlayTeardown(t *testing.T) {
 	graphtest.PutDriver(t)
 }
@@ -1,12 +1,11 @@
 // +build linux
 
 //
-// projectquota.go - implements XFS project quota controls
+// projectquota.go - implements project quota controls
 // for setting quota limits on a newly created directory.
-// It currently supports the legacy XFS specific ioctls.
-//
-// TODO: use generic quota control ioctl FS_IOC_FS{GET,SET}XATTR
-//       for both xfs/ext4 for kernel version >= v4.5
+// It uses the XFS specific quotactl commands, which the kernel
+// also supports for ext4 project quotas since v4.5, along with
+// the generic FS_IOC_FS{GET,SET}XATTR ioctls.
 //
 
 package quota
@@ -55,25 +54,55 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"unsafe"
 
 	"github.com/Sirupsen/logrus"
 )
 
-// Quota limit params - currently we only control blocks hard limit
-type Quota struct {
-	Size uint64
-}
-
 // Control - Context to be used by storage driver (e.g. overlay)
 // who wants to apply project quotas to container dirs
 type Control struct {
 	backingFsBlockDev string
-	nextProjectID     uint32
+	projectIDs        *projectIDs
+	mu                sync.Mutex
 	quotas            map[string]uint32
 }
 
+// projectIDs allocates the project ids of a backing filesystem. The controls
+// of directories on the same filesystem, e.g. the home directory of the
+// storage driver and the directory of the local volumes, share it so that
+// they do not assign the same project ids.
+type projectIDs struct {
+	mu   sync.Mutex
+	next uint32
+}
+
+var (
+	projectIDsMu sync.Mutex
+	// projectIDsByDev are the project id allocators of the backing
+	// filesystems, by device number
+	projectIDsByDev = make(map[uint64]*projectIDs)
+)
+
+// reserve makes sure that the project ids up to id are not allocated.
+func (p *projectIDs) reserve(id uint32) {
+	p.mu.Lock()
+	if p.next <= id {
+		p.next = id + 1
+	}
+	p.mu.Unlock()
+}
+
+func (p *projectIDs) allocate() uint32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.next
+	p.next++
+	return id
+}
+
 // NewControl - initialize project quota support.
 // Test to make sure that quota can be set on a test dir and find
 // the first project id to be used for the next container create.
@@ -81,13 +110,16 @@ type Control struct {
 // Returns nil (and error) if project quota is not supported.
 //
 // First get the project id of the home directory.
-// This test will fail if the backing fs is not xfs.
+// This test will fail if the backing fs is neither xfs nor ext4.
 //
 // xfs_quota tool can be used to assign a project id to the driver home directory, e.g.:
 //    echo 999:/var/lib/docker/overlay2 >> /etc/projects
 //    echo docker:999 >> /etc/projid
 //    xfs_quota -x -c 'project -s docker' /<xfs mount point>
 //
+// On ext4, the filesystem has to be created with the project and quota
+// features, and mounted with the prjquota option.
+//
 // In that case, the home directory project id will be used as a "start offset"
 // and all containers will be assigned larger project ids (e.g. >= 1000).
 // This is a way to prevent xfs_quota management from conflicting with docker.
@@ -96,6 +128,11 @@ type Control struct {
 // on it. If that works, continue to scan existing containers to map allocated
 // project ids.
 //
+// The project ids are allocated per backing filesystem: the controls of
+// directories on the same filesystem share the next project id to use, and
+// the test is only done by the first one, so that it does not reset the
+// quota of a project id assigned by another control.
+//
 func NewControl(basePath string) (*Control, error) {
 	//
 	// Get project id of parent dir as minimal id to be used by driver
@@ -109,47 +146,58 @@ func NewControl(basePath string) (*Control, error) {
 	//
 	// create backing filesystem device node
 	//
-	backingFsBlockDev, err := makeBackingFsDev(basePath)
+	backingFsBlockDev, dev, err := makeBackingFsDev(basePath)
 	if err != nil {
 		return nil, err
 	}
 
-	//
-	// Test if filesystem supports project quotas by trying to set
-	// a quota on the first available project id
-	//
-	quota := Quota{
-		Size: 0,
-	}
-	if err := setProjectQuota(backingFsBlockDev, minProjectID, quota); err != nil {
-		return nil, err
+	projectIDsMu.Lock()
+	defer projectIDsMu.Unlock()
+
+	ids, ok := projectIDsByDev[dev]
+	if !ok {
+		//
+		// Test if filesystem supports project quotas by trying to set
+		// a quota on the first available project id
+		//
+		quota := Quota{
+			Size: 0,
+		}
+		if err := setProjectQuota(backingFsBlockDev, minProjectID, quota); err != nil {
+			return nil, err
+		}
+		ids = &projectIDs{}
 	}
+	ids.reserve(minProjectID)
 
 	q := Control{
 		backingFsBlockDev: backingFsBlockDev,
-		nextProjectID:     minProjectID + 1,
+		projectIDs:        ids,
 		quotas:            make(map[string]uint32),
 	}
 
 	//
 	// get first project id to be used for next container
 	//
-	err = q.findNextProjectID(basePath)
+	err = q.findNextProjectID(basePath, minProjectID)
 	if err != nil {
 		return nil, err
 	}
+	projectIDsByDev[dev] = ids
 
-	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, q.nextProjectID)
+	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, ids.next)
 	return &q, nil
 }
 
 // SetQuota - assign a unique project id to directory and set the quota limits
 // for that project id
 func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
 	projectID, ok := q.quotas[targetPath]
 	if !ok {
-		projectID = q.nextProjectID
+		projectID = q.projectIDs.allocate()
 
 		//
 		// assign project id to new container directory
@@ -160,7 +208,6 @@ func (q *Control) SetQuota(targetPath string, quota Quota) error {
 		}
 
 		q.quotas[targetPath] = projectID
-		q.nextProjectID++
 	}
 
 	//
@@ -170,7 +217,7 @@ func (q *Control) SetQuota(targetPath string, quota Quota) error {
 	return setProjectQuota(q.backingFsBlockDev, projectID, quota)
 }
 
-// setProjectQuota - set the quota for project id on xfs block device
+// setProjectQuota - set the quota for project id on the backing block device
 func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) error {
 	var d C.fs_disk_quota_t
 	d.d_version = C.FS_DQUOT_VERSION
@@ -195,12 +242,14 @@ func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) er
 	return nil
 }
 
-// GetQuota - get the quota limits of a directory that was configured with SetQuota
+// GetQuota - get the quota limits and usage of a directory that was configured
+// with SetQuota. It returns ErrQuotaNotSet for other directories.
 func (q *Control) GetQuota(targetPath string, quota *Quota) error {
-
+	q.mu.Lock()
 	projectID, ok := q.quotas[targetPath]
+	q.mu.Unlock()
 	if !ok {
-		return fmt.Errorf("quota not found for path : %s", targetPath)
+		return ErrQuotaNotSet
 	}
 
 	//
@@ -219,11 +268,12 @@ func (q *Control) GetQuota(targetPath string, quota *Quota) error {
 			projectID, q.backingFsBlockDev, errno.Error())
 	}
 	quota.Size = uint64(d.d_blk_hardlimit) * 512
+	quota.Used = uint64(d.d_bcount) * 512
 
 	return nil
 }
 
-// getProjectID - get the project id of path on xfs
+// getProjectID - get the project id of path
 func getProjectID(targetPath string) (uint32, error) {
 	dir, err := openDir(targetPath)
 	if err != nil {
@@ -241,7 +291,7 @@ func getProjectID(targetPath string) (uint32, error) {
 	return uint32(fsx.fsx_projid), nil
 }
 
-// setProjectID - set the project id of path on xfs
+// setProjectID - set the project id of path
 func setProjectID(targetPath string, projectID uint32) error {
 	dir, err := openDir(targetPath)
 	if err != nil {
@@ -267,8 +317,10 @@ func setProjectID(targetPath string, projectID uint32) error {
 }
 
 // findNextProjectID - find the next project id to be used for containers
-// by scanning driver home directory to find used project ids
-func (q *Control) findNextProjectID(home string) error {
+// by scanning driver home directory to find used project ids. Directories
+// with a project id lower than minProjectID, e.g. inherited from the home
+// directory, have no quota of their own.
+func (q *Control) findNextProjectID(home string, minProjectID uint32) error {
 	files, err := ioutil.ReadDir(home)
 	if err != nil {
 		return fmt.Errorf("read directory failed : %s", home)
@@ -282,12 +334,11 @@ func (q *Control) findNextProjectID(home string) error {
 		if err != nil {
 			return err
 		}
-		if projid > 0 {
-			q.quotas[path] = projid
-		}
-		if q.nextProjectID <= projid {
-			q.nextProjectID = projid + 1
+		if projid < minProjectID {
+			continue
 		}
+		q.quotas[path] = projid
+		q.projectIDs.reserve(projid)
 	}
 
 	return nil
@@ -320,11 +371,12 @@ func getDirFd(dir *C.DIR) uintptr {
 
 // Get the backing block device of the driver home directory
 // and create a block device node under the home directory
-// to be used by quotactl commands
-func makeBackingFsDev(home string) (string, error) {
+// to be used by quotactl commands. The device number is
+// returned along with the path of the node.
+func makeBackingFsDev(home string) (string, uint64, error) {
 	fileinfo, err := os.Stat(home)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	backingFsBlockDev := path.Join(home, "backingFsBlockDev")
@@ -332,8 +384,8 @@ func makeBackingFsDev(home string) (string, error) {
 	syscall.Unlink(backingFsBlockDev)
 	stat := fileinfo.Sys().(*syscall.Stat_t)
 	if err := syscall.Mknod(backingFsBlockDev, syscall.S_IFBLK|0600, int(stat.Dev)); err != nil {
-		return "", fmt.Errorf("Failed to mknod %s: %v", backingFsBlockDev, err)
+		return "", 0, fmt.Errorf("Failed to mknod %s: %v", backingFsBlockDev, err)
 	}
 
-	return backingFsBlockDev, nil
+	return backingFsBlockDev, uint64(stat.Dev), nil
 }
@@ -0,0 +1,24 @@
+// +build !linux !cgo
+
+package quota
+
+// Control is a no-op on platforms without project quota support.
+type Control struct{}
+
+// NewControl returns ErrQuotaNotSupported on platforms without project
+// quota support.
+func NewControl(basePath string) (*Control, error) {
+	return nil, ErrQuotaNotSupported
+}
+
+// SetQuota returns ErrQuotaNotSupported on platforms without project quota
+// support.
+func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	return ErrQuotaNotSupported
+}
+
+// GetQuota returns ErrQuotaNotSupported on platforms without project quota
+// support.
+func (q *Control) GetQuota(targetPath string, quota *Quota) error {
+	return ErrQuotaNotSupported
+}
@@ -0,0 +1,20 @@
+package quota
+
+import "errors"
+
+var (
+	// ErrQuotaNotSupported is returned by NewControl on platforms without
+	// project quota support.
+	ErrQuotaNotSupported = errors.New("project quotas are not supported")
+
+	// ErrQuotaNotSet is returned by GetQuota for a directory that has no
+	// quota set.
+	ErrQuotaNotSet = errors.New("no quota set for the directory")
+)
+
+// Quota limit params - currently we only control blocks hard limit
+type Quota struct {
+	Size uint64
+	// Used is the space used against the limit. It is only set by GetQuota.
+	Used uint64
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/chrootarchive"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/system"
+	"github.com/docker/go-units"
 
 	"github.com/opencontainers/selinux/go-selinux/label"
 )
@@ -37,6 +41,16 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 	if err := idtools.MkdirAllAs(home, 0700, rootUID, rootGID); err != nil {
 		return nil, err
 	}
+
+	// Layers can only be limited in size on filesystems with project
+	// quota support
+	if err := idtools.MkdirAllAs(filepath.Join(home, "dir"), 0700, rootUID, rootGID); err != nil {
+		return nil, err
+	}
+	if d.quotaCtl, err = quota.NewControl(filepath.Join(home, "dir")); err != nil {
+		logrus.Debugf("vfs: project quotas are not supported on %s: %v", home, err)
+	}
+
 	return graphdriver.NewNaiveDiffDriver(d, uidMaps, gidMaps), nil
 }
 
@@ -45,9 +59,10 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 // In order to support layering, files are copied from the parent layer into the new layer. There is no copy-on-write support.
 // Driver must be wrapped in NaiveDiffDriver to be used as a graphdriver.Driver
 type Driver struct {
-	home    string
-	uidMaps []idtools.IDMap
-	gidMaps []idtools.IDMap
+	home     string
+	uidMaps  []idtools.IDMap
+	gidMaps  []idtools.IDMap
+	quotaCtl *quota.Control
 }
 
 func (d *Driver) String() string {
@@ -77,8 +92,15 @@ func (d *Driver) CreateReadWrite(id, parent string, opts *graphdriver.CreateOpts
 
 // Create prepares the filesystem for the VFS driver and copies the directory for the given id under the parent.
 func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) error {
+	var size uint64
 	if opts != nil && len(opts.StorageOpt) != 0 {
-		return fmt.Errorf("--storage-opt is not supported for vfs")
+		if d.quotaCtl == nil {
+			return fmt.Errorf("--storage-opt is supported only for vfs over xfs with 'pquota' mount option or ext4 with 'prjquota' mount option")
+		}
+		var err error
+		if size, err = parseStorageOpt(opts.StorageOpt); err != nil {
+			return err
+		}
 	}
 
 	dir := d.dir(id)
@@ -92,6 +114,13 @@ func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) error {
 	if err := idtools.MkdirAs(dir, 0755, rootUID, rootGID); err != nil {
 		return err
 	}
+	if size > 0 {
+		// the limit applies to the whole layer, including the content
+		// copied from the parent
+		if err := d.quotaCtl.SetQuota(dir, quota.Quota{Size: size}); err != nil {
+			return err
+		}
+	}
 	labelOpts := []string{"level:s0"}
 	if _, mountLabel, err := label.InitLabels(labelOpts); err == nil {
 		label.SetFileLabel(dir, mountLabel)
@@ -109,6 +138,40 @@ func (d *Driver) Create(id, parent string, opts *graphdriver.CreateOpts) error {
 	return nil
 }
 
+// parseStorageOpt returns the size limit set in the storage options.
+func parseStorageOpt(storageOpt map[string]string) (uint64, error) {
+	var size uint64
+	for key, val := range storageOpt {
+		switch strings.ToLower(key) {
+		case "size":
+			s, err := units.RAMInBytes(val)
+			if err != nil {
+				return 0, err
+			}
+			size = uint64(s)
+		default:
+			return 0, fmt.Errorf("Unknown option %s", key)
+		}
+	}
+	return size, nil
+}
+
+// DiskQuota returns the size limit of a layer set with the size storage
+// option, and the space used against it.
+func (d *Driver) DiskQuota(id string) (int64, int64, error) {
+	if d.quotaCtl == nil {
+		return 0, 0, nil
+	}
+	var q quota.Quota
+	if err := d.quotaCtl.GetQuota(d.dir(id), &q); err != nil {
+		if err == quota.ErrQuotaNotSet {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	return int64(q.Size), int64(q.Used), nil
+}
+
 func (d *Driver) dir(id string) string {
 	return filepath.Join(d.home, "dir", filepath.Base(id))
 }
@@ -3,9 +3,14 @@
 package vfs
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/daemon/graphdriver/graphtest"
+	"github.com/docker/go-units"
 
 	"github.com/docker/docker/pkg/reexec"
 )
@@ -35,3 +40,41 @@ func TestVfsCreateSnap(t *testing.T) {
 func TestVfsTeardown(t *testing.T) {
 	graphtest.PutDriver(t)
 }
+
+func TestVfsStorageOptSize(t *testing.T) {
+	home, err := ioutil.TempDir("", "vfs-quota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	driver, err := Init(home, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Cleanup()
+
+	err = driver.Create("unknown", "", &graphdriver.CreateOpts{StorageOpt: map[string]string{"foo": "bar"}})
+	if err == nil {
+		t.Fatal("expected an error creating a layer with an unknown storage option")
+	}
+
+	err = driver.Create("sized", "", &graphdriver.CreateOpts{StorageOpt: map[string]string{"size": "50M"}})
+	if driver.(*graphdriver.NaiveDiffDriver).ProtoDriver.(*Driver).quotaCtl == nil {
+		if err == nil || !strings.Contains(err.Error(), "--storage-opt is supported only for vfs") {
+			t.Fatalf("expected an error about the missing quota support, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit, _, err := driver.(graphdriver.DiskQuotaDriver).DiskQuota("sized")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != 50*units.MiB {
+		t.Fatalf("expected a limit of %d, got %d", 50*units.MiB, limit)
+	}
+}
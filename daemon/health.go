@@ -3,9 +3,13 @@ package daemon
 import (
 	"bytes"
 	"fmt"
+	"net/http"
+	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/context"
@@ -13,8 +17,11 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/events"
 	"github.com/docker/docker/daemon/exec"
 )
 
@@ -112,6 +119,131 @@ func (p *cmdProbe) run(ctx context.Context, d *Daemon, cntr *container.Container
 	}, nil
 }
 
+// httpProbe implements the "HTTP" probe type, which sends a GET request to
+// the container. The probe succeeds if the response status is 2xx or 3xx.
+type httpProbe struct{}
+
+func (p *httpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	port, path, err := parseHTTPProbe(cntr.Config.Healthcheck.Test)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext:       containerDialer(cntr),
+			DisableKeepAlives: true,
+		},
+		// redirects are not followed, they are a valid response
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequest("GET", "http://localhost:"+port+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.HealthcheckResult{ExitCode: exitStatusUnhealthy}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		result.Output = err.Error()
+	} else {
+		resp.Body.Close()
+		result.Output = "HTTP " + resp.Status
+		if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+			result.ExitCode = exitStatusHealthy
+		}
+	}
+	result.End = time.Now()
+	return result, nil
+}
+
+// tcpProbe implements the "TCP" probe type. The probe succeeds if a TCP
+// connection to the port of the container can be established.
+type tcpProbe struct{}
+
+func (p *tcpProbe) run(ctx context.Context, d *Daemon, cntr *container.Container) (*types.HealthcheckResult, error) {
+	port, err := parseTCPProbe(cntr.Config.Healthcheck.Test)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.HealthcheckResult{ExitCode: exitStatusUnhealthy}
+	conn, err := containerDialer(cntr)(ctx, "tcp", "localhost:"+port)
+	if err != nil {
+		result.Output = err.Error()
+	} else {
+		conn.Close()
+		result.ExitCode = exitStatusHealthy
+		result.Output = "connected to port " + port
+	}
+	result.End = time.Now()
+	return result, nil
+}
+
+// parseHTTPProbe returns the port and path of an "HTTP" probe, given as
+// {"HTTP", "[:port]/path"}. The port defaults to 80.
+func parseHTTPProbe(test []string) (string, string, error) {
+	if len(test) != 2 || test[1] == "" {
+		return "", "", fmt.Errorf("HTTP health check takes a single path argument")
+	}
+	port, path := "80", test[1]
+	if strings.HasPrefix(path, ":") {
+		i := strings.Index(path, "/")
+		if i < 0 {
+			i = len(path)
+		}
+		port, path = path[1:i], path[i:]
+		if err := validateProbePort(port); err != nil {
+			return "", "", err
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("invalid HTTP health check path %q: it must start with /", path)
+	}
+	if _, err := url.ParseRequestURI(path); err != nil {
+		return "", "", fmt.Errorf("invalid HTTP health check path %q: %v", path, err)
+	}
+	return port, path, nil
+}
+
+// parseTCPProbe returns the port of a "TCP" probe, given as {"TCP", port}.
+func parseTCPProbe(test []string) (string, error) {
+	if len(test) != 2 {
+		return "", fmt.Errorf("TCP health check takes a single port argument")
+	}
+	if err := validateProbePort(test[1]); err != nil {
+		return "", err
+	}
+	return test[1], nil
+}
+
+func validateProbePort(port string) error {
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid health check port %q", port)
+	}
+	return nil
+}
+
+// validateHealthcheckTest validates the arguments of the probes run by the
+// daemon itself.
+func validateHealthcheckTest(test []string) error {
+	if len(test) == 0 {
+		return nil
+	}
+	var err error
+	switch test[0] {
+	case "HTTP":
+		_, _, err = parseHTTPProbe(test)
+	case "TCP":
+		_, err = parseTCPProbe(test)
+	}
+	return err
+}
+
 // Update the container's Status.Health struct based on the latest probe's result.
 func handleProbeResult(d *Daemon, c *container.Container, result *types.HealthcheckResult, done chan struct{}) {
 	c.Lock()
@@ -169,7 +301,108 @@ func handleProbeResult(d *Daemon, c *container.Container, result *types.Healthch
 
 	if oldStatus != h.Status {
 		d.LogContainerEvent(c, "health_status: "+h.Status)
+		if h.Status == types.Healthy && c.HostConfig != nil {
+			c.RestartManager().SetHealthy()
+		}
+	}
+
+	if h.Status == types.Unhealthy && c.HostConfig != nil && c.HostConfig.RestartPolicy.UnhealthyThreshold > 0 &&
+		c.RestartManager().ShouldRestartUnhealthy(h.FailingStreak-retries+1) {
+		logrus.Infof("Restarting container %s after %d failed health checks", c.ID, h.FailingStreak)
+		go d.restartUnhealthy(c)
+	}
+}
+
+// restartUnhealthy stops a container that its restart manager decided to
+// restart after failed health checks. Unlike "docker stop", the container
+// is not marked as manually stopped, so that it is restarted when it exits.
+func (d *Daemon) restartUnhealthy(c *container.Container) {
+	c.Lock()
+	err := d.kill(c, c.StopSignal())
+	c.Unlock()
+	if err != nil {
+		logrus.Warnf("Failed to stop unhealthy container %s: %v", c.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.StopTimeout())*time.Second)
+	defer cancel()
+	if status := <-c.Wait(ctx, container.WaitConditionNotRunning); status.Err() != nil {
+		c.Lock()
+		err := d.kill(c, int(syscall.SIGKILL))
+		c.Unlock()
+		if err != nil {
+			logrus.Errorf("Failed to kill unhealthy container %s: %v", c.ID, err)
+		}
+	}
+}
+
+// waitLinksHealthy waits for the containers linked to c that have a health
+// check to be healthy.
+func (d *Daemon) waitLinksHealthy(ctx context.Context, c *container.Container) error {
+	for alias, child := range d.children(c) {
+		if err := d.waitHealthy(ctx, child); err != nil {
+			return fmt.Errorf("linked container %s is not healthy: %v", strings.TrimPrefix(alias, "/"), err)
+		}
+	}
+	return nil
+}
+
+// waitHealthy waits for the health status of a container to be known. It
+// returns an error if the container is unhealthy or not running, or if its
+// status is still unknown once its health check had the time to fail
+// as many times as its retries, after its start period.
+func (d *Daemon) waitHealthy(ctx context.Context, c *container.Container) error {
+	args := filters.NewArgs()
+	args.Add("type", eventtypes.ContainerEventType)
+	args.Add("container", c.ID)
+	_, ch := d.EventsService.SubscribeTopic(time.Time{}, time.Time{}, events.NewFilter(args))
+	defer d.EventsService.Evict(ch)
+
+	var timeout <-chan time.Time
+	for {
+		c.Lock()
+		running, health := c.Running, c.State.Health
+		status := types.NoHealthcheck
+		if health != nil {
+			status = health.Status
+		}
+		if timeout == nil && c.Config.Healthcheck != nil {
+			timeout = time.After(healthyTimeout(c.Config.Healthcheck))
+		}
+		c.Unlock()
+
+		switch {
+		case !running:
+			return fmt.Errorf("container is not running")
+		case status == types.Healthy || status == types.NoHealthcheck:
+			return nil
+		case status == types.Unhealthy:
+			return fmt.Errorf("container is unhealthy")
+		}
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("events stream closed")
+			}
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for the container to be healthy")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// healthyTimeout returns how long a container with the given health check
+// can take to be healthy.
+func healthyTimeout(config *containertypes.HealthConfig) time.Duration {
+	retries := config.Retries
+	if retries <= 0 {
+		retries = defaultProbeRetries
 	}
+	interval := timeoutWithDefault(config.Interval, defaultProbeInterval)
+	probeTimeout := timeoutWithDefault(config.Timeout, defaultProbeTimeout)
+	startPeriod := timeoutWithDefault(config.StartPeriod, defaultStartPeriod)
+	return startPeriod + time.Duration(retries)*(interval+probeTimeout)
 }
 
 // Run the container's monitoring thread until notified via "stop".
@@ -245,8 +478,12 @@ func getProbe(c *container.Container) probe {
 		return &cmdProbe{shell: false}
 	case "CMD-SHELL":
 		return &cmdProbe{shell: true}
+	case "HTTP":
+		return &httpProbe{}
+	case "TCP":
+		return &tcpProbe{}
 	default:
-		logrus.Warnf("Unknown healthcheck type '%s' (expected 'CMD') in container %s", config.Test[0], c.ID)
+		logrus.Warnf("Unknown healthcheck type '%s' (expected 'CMD', 'HTTP' or 'TCP') in container %s", config.Test[0], c.ID)
 		return nil
 	}
 }
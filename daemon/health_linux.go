@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/container"
+	"github.com/vishvananda/netns"
+)
+
+// containerDialer returns a dial function connecting to the loopback
+// interface of the container, from its network namespace.
+func containerDialer(c *container.Container) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	pid := c.GetPID()
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if pid == 0 {
+			return nil, fmt.Errorf("container %s is not running", c.ID)
+		}
+
+		// The socket is created in the namespace of the current thread, so
+		// the goroutine must stay on it while the namespace is switched.
+		runtime.LockOSThread()
+		origin, err := netns.Get()
+		if err != nil {
+			runtime.UnlockOSThread()
+			return nil, err
+		}
+		defer origin.Close()
+		ns, err := netns.GetFromPid(pid)
+		if err != nil {
+			runtime.UnlockOSThread()
+			return nil, fmt.Errorf("failed to get the network namespace of container %s: %v", c.ID, err)
+		}
+		defer ns.Close()
+		if err := netns.Set(ns); err != nil {
+			runtime.UnlockOSThread()
+			return nil, fmt.Errorf("failed to enter the network namespace of container %s: %v", c.ID, err)
+		}
+
+		// an IP address is used, so that the dial is not raced across
+		// several goroutines
+		var d net.Dialer
+		conn, dialErr := d.DialContext(ctx, "tcp4", net.JoinHostPort("127.0.0.1", port))
+
+		if err := netns.Set(origin); err != nil {
+			// the thread is left locked, so that it is terminated with the
+			// goroutine instead of being reused in the wrong namespace
+			logrus.Errorf("Failed to restore the network namespace after a health check: %v", err)
+			return conn, dialErr
+		}
+		runtime.UnlockOSThread()
+		return conn, dialErr
+	}
+}
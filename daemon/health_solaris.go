@@ -0,0 +1,15 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/container"
+)
+
+func containerDialer(c *container.Container) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, fmt.Errorf("HTTP and TCP health checks are not supported on Solaris")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
 	eventtypes "github.com/docker/docker/api/types/events"
@@ -143,3 +145,64 @@ func TestHealthStates(t *testing.T) {
 		t.Errorf("Expecting FailingStreak=0, but got %d\n", c.State.Health.FailingStreak)
 	}
 }
+
+func TestParseHTTPProbe(t *testing.T) {
+	for _, tc := range []struct {
+		arg, port, path string
+	}{
+		{"/health", "80", "/health"},
+		{":8080/health?full=1", "8080", "/health?full=1"},
+		{":8080", "8080", "/"},
+	} {
+		port, path, err := parseHTTPProbe([]string{"HTTP", tc.arg})
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tc.arg, err)
+		}
+		if port != tc.port || path != tc.path {
+			t.Fatalf("expected %s and %s for %s, got %s and %s", tc.port, tc.path, tc.arg, port, path)
+		}
+	}
+	for _, arg := range []string{"health", ":http/health", ":0/", ""} {
+		if _, _, err := parseHTTPProbe([]string{"HTTP", arg}); err == nil {
+			t.Fatalf("expected an error for %q", arg)
+		}
+	}
+	if err := validateHealthcheckTest([]string{"TCP", "65536"}); err == nil {
+		t.Fatal("expected an error for an invalid port")
+	}
+}
+
+func TestWaitHealthy(t *testing.T) {
+	c := &container.Container{
+		CommonContainer: container.CommonContainer{
+			ID: "container_id",
+			Config: &containertypes.Config{
+				Healthcheck: &containertypes.HealthConfig{
+					Test:     []string{"CMD", "true"},
+					Interval: 10 * time.Millisecond,
+					Timeout:  10 * time.Millisecond,
+					Retries:  1,
+				},
+			},
+		},
+	}
+	reset(c)
+	c.Running = true
+	daemon := &Daemon{EventsService: events.New()}
+
+	if err := daemon.waitHealthy(context.Background(), c); err == nil {
+		t.Fatal("expected a timeout waiting for the container to be healthy")
+	}
+
+	c.Config.Healthcheck.Interval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := daemon.waitHealthy(ctx, c); err != context.Canceled {
+		t.Fatalf("expected the wait to be canceled, got %v", err)
+	}
+
+	c.State.Health.Status = types.Healthy
+	if err := daemon.waitHealthy(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+}
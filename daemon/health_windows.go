@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/container"
+)
+
+// containerDialer returns a dial function connecting to the IP address of
+// the container, as the network compartment of a container cannot be
+// entered from the daemon.
+func containerDialer(c *container.Container) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var ip string
+	c.Lock()
+	if c.NetworkSettings != nil {
+		for _, ep := range c.NetworkSettings.Networks {
+			if ep.EndpointSettings != nil && ep.IPAddress != "" {
+				ip = ep.IPAddress
+				break
+			}
+		}
+	}
+	c.Unlock()
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if ip == "" {
+			return nil, fmt.Errorf("container %s has no IP address", c.ID)
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	}
+}
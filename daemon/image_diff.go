@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/pkg/errors"
+)
+
+// imageDiffLayerPrefix prefixes the names of the RW layers that are mounted
+// to diff images.
+const imageDiffLayerPrefix = "image-diff-"
+
+// ImageDiff returns the paths added, modified and deleted between the root
+// filesystem of the base image and the one of the image named name.
+func (daemon *Daemon) ImageDiff(name, base string) ([]imagetypes.DiffResponseItem, error) {
+	start := time.Now()
+	img, err := daemon.GetImage(name)
+	if err != nil {
+		return nil, err
+	}
+	baseImg, err := daemon.GetImage(base)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := []imagetypes.DiffResponseItem{}
+	if img.RootFS.ChainID() == baseImg.RootFS.ChainID() {
+		return diff, nil
+	}
+
+	newDir, release, err := daemon.mountImageRootFS(img)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	oldDir, releaseBase, err := daemon.mountImageRootFS(baseImg)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseBase()
+
+	changes, err := archive.ChangesDirs(newDir, oldDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		// deleted paths are sized from the base image
+		dir := newDir
+		if change.Kind == archive.ChangeDelete {
+			dir = oldDir
+		}
+		var size int64
+		fi, err := os.Lstat(filepath.Join(dir, change.Path))
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+		diff = append(diff, imagetypes.DiffResponseItem{
+			Path: change.Path,
+			Kind: uint8(change.Kind),
+			Size: size,
+		})
+	}
+	imageActions.WithValues("diff").UpdateSince(start)
+	return diff, nil
+}
+
+// mountImageRootFS mounts the root filesystem of img, or creates an empty
+// directory if the image has no layers. The returned function unmounts and
+// releases it.
+func (daemon *Daemon) mountImageRootFS(img *image.Image) (string, func(), error) {
+	if img.RootFS.ChainID() == "" {
+		dir, err := ioutil.TempDir("", "docker-image-diff-")
+		if err != nil {
+			return "", nil, err
+		}
+		return dir, func() { os.RemoveAll(dir) }, nil
+	}
+
+	// Hold a reference to the image layer so that it can't be removed while
+	// it is mounted
+	roLayer, err := daemon.layerStore.Get(img.RootFS.ChainID())
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to get layer for image %s", img.ImageID())
+	}
+	releaseRO := func() {
+		metadata, err := daemon.layerStore.Release(roLayer)
+		layer.LogReleaseMetadata(metadata)
+		if err != nil {
+			logrus.Errorf("Failed to release layer of image %s: %v", img.ImageID(), err)
+		}
+	}
+
+	// The RW layer is recorded before it is created, so that it is removed
+	// when the daemon starts if it was not released
+	name := imageDiffLayerPrefix + stringid.GenerateRandomID()
+	record := filepath.Join(daemon.imageDiffLayersDir, name)
+	if err := os.MkdirAll(daemon.imageDiffLayersDir, 0700); err != nil {
+		releaseRO()
+		return "", nil, err
+	}
+	if err := ioutil.WriteFile(record, nil, 0600); err != nil {
+		releaseRO()
+		return "", nil, err
+	}
+	rwLayer, err := daemon.layerStore.CreateRWLayer(name, roLayer.ChainID(), nil)
+	if err != nil {
+		os.Remove(record)
+		releaseRO()
+		return "", nil, errors.Wrap(err, "failed to create rwlayer")
+	}
+	releaseRW := func() {
+		metadata, err := daemon.layerStore.ReleaseRWLayer(rwLayer)
+		layer.LogReleaseMetadata(metadata)
+		if err != nil {
+			logrus.Errorf("Failed to release RWLayer: %v", err)
+		} else {
+			os.Remove(record)
+		}
+		releaseRO()
+	}
+
+	dir, err := rwLayer.Mount("")
+	if err != nil {
+		releaseRW()
+		return "", nil, errors.Wrapf(err, "failed to mount image %s", img.ImageID())
+	}
+	return dir, func() {
+		if err := rwLayer.Unmount(); err != nil {
+			logrus.Errorf("Failed to unmount image %s: %v", img.ImageID(), err)
+		}
+		releaseRW()
+	}, nil
+}
+
+// cleanupImageDiffLayers removes the RW layers that were mounted to diff
+// images and not released, because the daemon exited during the diff.
+func (daemon *Daemon) cleanupImageDiffLayers() error {
+	records, err := ioutil.ReadDir(daemon.imageDiffLayersDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, record := range records {
+		name := record.Name()
+		if !strings.HasPrefix(name, imageDiffLayerPrefix) {
+			continue
+		}
+		rwLayer, err := daemon.layerStore.GetRWLayer(name)
+		if err == nil {
+			metadata, err := daemon.layerStore.ReleaseRWLayer(rwLayer)
+			layer.LogReleaseMetadata(metadata)
+			if err != nil {
+				logrus.Warnf("Failed to remove layer %s of an interrupted image diff: %v", name, err)
+				continue
+			}
+		} else if err != layer.ErrMountDoesNotExist {
+			logrus.Warnf("Failed to get layer %s of an interrupted image diff: %v", name, err)
+			continue
+		}
+		if err := os.Remove(filepath.Join(daemon.imageDiffLayersDir, name)); err != nil {
+			logrus.Warnf("Failed to remove record of layer %s: %v", name, err)
+		}
+	}
+	return nil
+}
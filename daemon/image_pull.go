@@ -70,8 +70,10 @@ func (daemon *Daemon) pullImageWithReference(ctx context.Context, ref reference.
 			ImageStore:       distribution.NewImageConfigStoreFromStore(daemon.imageStore),
 			ReferenceStore:   daemon.referenceStore,
 		},
-		DownloadManager: daemon.downloadManager,
-		Schema2Types:    distribution.ImageTypes,
+		DownloadManager:    daemon.downloadManager,
+		Schema2Types:       distribution.ImageTypes,
+		DownloadStagingDir: daemon.downloadStagingDir,
+		LazyPull:           daemon.configStore.LazyPull,
 	}
 
 	err := distribution.Pull(ctx, ref, imagePullConfig)
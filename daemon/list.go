@@ -232,6 +232,7 @@ func (daemon *Daemon) reducePsContainer(container *container.Container, ctx *lis
 		sizeRw, sizeRootFs := daemon.getSize(newC.ID)
 		newC.SizeRw = sizeRw
 		newC.SizeRootFs = sizeRootFs
+		newC.DiskQuota = daemon.getDiskQuota(newC.ID)
 	}
 	return newC, nil
 }
@@ -121,6 +121,24 @@ var builtInLogOpts = map[string]bool{
 	"max-buffer-size": true,
 }
 
+var externalValidators []LogOptValidator
+
+// AddBuiltinLogOpts adds log options that are handled by the daemon rather
+// than by the log drivers, so that they are not passed to the drivers' own
+// validators. It must only be called during package initialization.
+func AddBuiltinLogOpts(opts map[string]bool) {
+	for k, v := range opts {
+		builtInLogOpts[k] = v
+	}
+}
+
+// RegisterExternalValidator registers a validator for log options added with
+// AddBuiltinLogOpts. External validators are called with all the options of
+// every log driver. It must only be called during package initialization.
+func RegisterExternalValidator(v LogOptValidator) {
+	externalValidators = append(externalValidators, v)
+}
+
 // ValidateLogOpts checks the options for the given log driver. The
 // options supported are specific to the LogDriver implementation.
 func ValidateLogOpts(name string, cfg map[string]string) error {
@@ -147,6 +165,12 @@ func ValidateLogOpts(name string, cfg map[string]string) error {
 		return fmt.Errorf("logger: no log driver named '%s' is registered", name)
 	}
 
+	for _, validator := range externalValidators {
+		if err := validator(cfg); err != nil {
+			return err
+		}
+	}
+
 	filteredOpts := make(map[string]string, len(builtInLogOpts))
 	for k, v := range cfg {
 		if !builtInLogOpts[k] {
@@ -152,6 +152,76 @@ func TestJSONFileLoggerWithOpts(t *testing.T) {
 
 }
 
+func TestJSONFileLoggerWithCompress(t *testing.T) {
+	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "container.log")
+	config := map[string]string{"max-file": "3", "max-size": "1k", "compress": "true"}
+	l, err := New(logger.Info{
+		ContainerID: cid,
+		LogPath:     filename,
+		Config:      config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i := 0; i < 36; i++ {
+		if err := l.Log(&logger.Message{Line: []byte("line" + strconv.Itoa(i)), Source: "src1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher := l.(logger.LogReader).ReadLogs(logger.ReadConfig{Tail: -1})
+	var lines []string
+	for msg := range watcher.Msg {
+		lines = append(lines, string(msg.Line))
+	}
+	select {
+	case err := <-watcher.Err:
+		t.Fatal(err)
+	default:
+	}
+	if len(lines) != 36 {
+		t.Fatalf("Wrong number of log lines: %d, expected 36", len(lines))
+	}
+	for i, line := range lines {
+		if expected := "line" + strconv.Itoa(i) + "\n"; line != expected {
+			t.Fatalf("Wrong log line: %q, expected %q", line, expected)
+		}
+	}
+
+	for _, name := range []string{filename + ".1.gz", filename + ".2.gz"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{filename + ".1", filename + ".2"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed after compression, got %v", name, err)
+		}
+	}
+}
+
+func TestJSONFileLoggerCompressWithoutRotation(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	_, err = New(logger.Info{
+		LogPath: filepath.Join(tmp, "container.log"),
+		Config:  map[string]string{"compress": "true"},
+	})
+	if err == nil {
+		t.Fatal("Expected an error when compress is set without max-file and max-size")
+	}
+}
+
 func TestJSONFileLoggerWithLabelsEnv(t *testing.T) {
 	cid := "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657"
 	tmp, err := ioutil.TempDir("", "docker-logger-")
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"time"
@@ -14,6 +13,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
 	"github.com/docker/docker/pkg/filenotify"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/jsonlog"
@@ -49,61 +49,79 @@ func (l *JSONFileLogger) readLogs(logWatcher *logger.LogWatcher, config logger.R
 	defer close(logWatcher.Msg)
 
 	// lock so the read stream doesn't get corrupted due to rotations or other log data written while we read
-	// This will block writes!!!
+	// This will block writes, so the files are only opened while locked,
+	// and read after unlocking
 	l.mu.Lock()
 
 	pth := l.writer.LogPath()
-	var files []io.ReadSeeker
-	for i := l.writer.MaxFiles(); i > 1; i-- {
-		f, err := os.Open(fmt.Sprintf("%s.%d", pth, i-1))
+
+	// rotated files may have to be decompressed, so only open them when
+	// they are going to be read
+	var rotatedFiles []loggerutils.ReadSeekCloser
+	if config.Tail != 0 {
+		var err error
+		rotatedFiles, err = l.writer.OpenRotatedFiles()
 		if err != nil {
-			if !os.IsNotExist(err) {
-				logWatcher.Err <- err
-				break
-			}
-			continue
+			logWatcher.Err <- err
+			l.mu.Unlock()
+			return
 		}
-		defer f.Close()
-
+	}
+	var files []io.ReadSeeker
+	for _, f := range rotatedFiles {
 		files = append(files, f)
 	}
 
 	latestFile, err := os.Open(pth)
 	if err != nil {
+		for _, f := range rotatedFiles {
+			f.Close()
+		}
 		logWatcher.Err <- err
 		l.mu.Unlock()
 		return
 	}
 	defer latestFile.Close()
 
+	// only the messages written before unlocking are tailed, the
+	// following ones are read by followLogs from the end of the tail
+	size, err := latestFile.Seek(0, os.SEEK_END)
+	if err != nil {
+		for _, f := range rotatedFiles {
+			f.Close()
+		}
+		logWatcher.Err <- err
+		l.mu.Unlock()
+		return
+	}
+
+	follow := config.Follow && !l.closed
+	var notifyRotate chan interface{}
+	if follow {
+		notifyRotate = l.writer.NotifyRotate()
+		defer l.writer.NotifyRotateEvict(notifyRotate)
+
+		l.readers[logWatcher] = struct{}{}
+	}
+
+	l.mu.Unlock()
+
 	if config.Tail != 0 {
-		tailer := ioutils.MultiReadSeeker(append(files, latestFile)...)
+		tailer := ioutils.MultiReadSeeker(append(files, io.NewSectionReader(latestFile, 0, size))...)
 		tailFile(tailer, logWatcher, config.Tail, config.Since)
 	}
 
 	// close all the rotated files
-	for _, f := range files {
-		if err := f.(io.Closer).Close(); err != nil {
+	for _, f := range rotatedFiles {
+		if err := f.Close(); err != nil {
 			logrus.WithField("logger", "json-file").Warnf("error closing tailed log file: %v", err)
 		}
 	}
 
-	if !config.Follow || l.closed {
-		l.mu.Unlock()
+	if !follow {
 		return
 	}
 
-	if config.Tail >= 0 {
-		latestFile.Seek(0, os.SEEK_END)
-	}
-
-	notifyRotate := l.writer.NotifyRotate()
-	defer l.writer.NotifyRotateEvict(notifyRotate)
-
-	l.readers[logWatcher] = struct{}{}
-
-	l.mu.Unlock()
-
 	followLogs(latestFile, logWatcher, notifyRotate, config.Since)
 
 	l.mu.Lock()
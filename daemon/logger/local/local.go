@@ -0,0 +1,212 @@
+// Package local provides a logger implementation that stores logs on disk in
+// a compact binary format.
+//
+// Each log message is stored as a record made of the size of the encoded
+// message as a big-endian uint32, the message encoded as a protobuf
+// logdriver.LogEntry, and the size again. The trailing size allows reading
+// the records backwards, which is how the logs are tailed.
+package local
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types/plugins/logdriver"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Name is the name of the driver
+	Name = "local"
+
+	encodeBinaryLen = 4
+	initialBufSize  = 2048
+	// maxMsgLen is the maximum size of an encoded message. Log lines are
+	// split into partial messages by the log copier long before that, so
+	// larger sizes are only found in corrupted files.
+	maxMsgLen = 1024 * 1024
+
+	defaultMaxFileSize  int64 = 20 * 1024 * 1024
+	defaultMaxFileCount       = 5
+)
+
+// logOptKeys are the keys of the log options supported by the local driver.
+var logOptKeys = map[string]bool{
+	"max-file": true,
+	"max-size": true,
+	"compress": true,
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.RegisterLogOptValidator(Name, ValidateLogOpt); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// ValidateLogOpt looks for log driver specific options.
+func ValidateLogOpt(cfg map[string]string) error {
+	for key := range cfg {
+		if !logOptKeys[key] {
+			return fmt.Errorf("unknown log opt '%s' for log driver %s", key, Name)
+		}
+	}
+	_, err := parseConfig(cfg)
+	return err
+}
+
+// createConfig holds the options of an instance of the driver.
+type createConfig struct {
+	DisableCompression bool
+	MaxFileSize        int64
+	MaxFileCount       int
+}
+
+func parseConfig(cfg map[string]string) (*createConfig, error) {
+	config := &createConfig{
+		MaxFileSize:  defaultMaxFileSize,
+		MaxFileCount: defaultMaxFileCount,
+	}
+
+	if s, ok := cfg["max-size"]; ok {
+		size, err := units.FromHumanSize(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing max-size")
+		}
+		if size <= 0 {
+			return nil, errors.New("max-size must be a positive number")
+		}
+		config.MaxFileSize = size
+	}
+
+	if s, ok := cfg["max-file"]; ok {
+		count, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing max-file")
+		}
+		if count < 1 {
+			return nil, errors.New("max-file cannot be less than 1")
+		}
+		config.MaxFileCount = count
+	}
+
+	// rotated files are compressed by default, when there are any
+	config.DisableCompression = config.MaxFileCount < 2
+	if s, ok := cfg["compress"]; ok {
+		compress, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing compress")
+		}
+		if compress && config.MaxFileCount < 2 {
+			return nil, errors.New("compress cannot be true when max-file is less than 2")
+		}
+		config.DisableCompression = !compress
+	}
+
+	return config, nil
+}
+
+type driver struct {
+	mu      sync.Mutex
+	closed  bool
+	writer  *loggerutils.RotateFileWriter
+	readers map[*logger.LogWatcher]struct{} // stores the active log followers
+	buffer  []byte                          // reused by Log to encode records
+	entry   logdriver.LogEntry
+}
+
+// New creates a new local logger. The logs are written to info.LogPath, and
+// rotated according to the max-size and max-file options.
+func New(info logger.Info) (logger.Logger, error) {
+	if info.LogPath == "" {
+		return nil, errors.New("log path is missing -- this is a bug and should not happen")
+	}
+
+	cfg, err := parseConfig(info.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(info.LogPath), 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating local logs dir")
+	}
+
+	writer, err := loggerutils.NewRotateFileWriter(info.LogPath, cfg.MaxFileSize, cfg.MaxFileCount, !cfg.DisableCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &driver{
+		writer:  writer,
+		readers: make(map[*logger.LogWatcher]struct{}),
+		buffer:  make([]byte, initialBufSize),
+	}, nil
+}
+
+// Log encodes the message into a record and writes it to the log file.
+func (d *driver) Log(msg *logger.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entry.Source = msg.Source
+	d.entry.TimeNano = msg.Timestamp.UnixNano()
+	d.entry.Partial = msg.Partial
+	d.entry.Line = msg.Line
+
+	var err error
+	d.buffer, err = marshalRecord(&d.entry, d.buffer)
+	d.entry.Reset()
+	logger.PutMessage(msg)
+	if err != nil {
+		return errors.Wrap(err, "error encoding log message")
+	}
+
+	_, err = d.writer.Write(d.buffer)
+	return err
+}
+
+// marshalRecord encodes the entry as a record in buf, which is grown if it
+// is too small, and returns the record.
+func marshalRecord(entry *logdriver.LogEntry, buf []byte) ([]byte, error) {
+	size := entry.Size()
+	total := size + 2*encodeBinaryLen
+	if cap(buf) < total {
+		buf = make([]byte, total)
+	}
+	buf = buf[:total]
+
+	binary.BigEndian.PutUint32(buf, uint32(size))
+	if _, err := entry.MarshalTo(buf[encodeBinaryLen : encodeBinaryLen+size]); err != nil {
+		return buf, err
+	}
+	binary.BigEndian.PutUint32(buf[encodeBinaryLen+size:], uint32(size))
+	return buf, nil
+}
+
+// Close closes the log file and signals all readers to stop.
+func (d *driver) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	err := d.writer.Close()
+	for r := range d.readers {
+		r.Close()
+		delete(d.readers, r)
+	}
+	d.mu.Unlock()
+	return err
+}
+
+// Name returns the name of this logger.
+func (d *driver) Name() string {
+	return Name
+}
@@ -0,0 +1,211 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/plugins/logdriver"
+	"github.com/docker/docker/daemon/logger"
+)
+
+func newTestLogger(t *testing.T, dir string, config map[string]string) *driver {
+	l, err := New(logger.Info{
+		ContainerID: "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		LogPath:     filepath.Join(dir, "local-logs", "container.log"),
+		Config:      config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.(*driver)
+}
+
+// logMessages logs the lines numbered from first to first+count-1, with
+// timestamps a second apart from start.
+func logMessages(t *testing.T, l logger.Logger, start time.Time, first, count int) {
+	for i := first; i < first+count; i++ {
+		msg := logger.NewMessage()
+		msg.Line = append(msg.Line, fmt.Sprintf("line%d", i)...)
+		msg.Source = "stdout"
+		msg.Timestamp = start.Add(time.Duration(i) * time.Second)
+		if err := l.Log(msg); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
+
+func readMessages(t *testing.T, l logger.LogReader, config logger.ReadConfig) []string {
+	watcher := l.ReadLogs(config)
+	defer watcher.Close()
+
+	var lines []string
+	for {
+		select {
+		case msg, ok := <-watcher.Msg:
+			if !ok {
+				return lines
+			}
+			lines = append(lines, string(msg.Line))
+		case err := <-watcher.Err:
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout reading logs")
+		}
+	}
+}
+
+func expectLines(t *testing.T, lines []string, first, count int) {
+	if len(lines) != count {
+		t.Fatalf("expected %d lines, got %d: %v", count, len(lines), lines)
+	}
+	for i, line := range lines {
+		if expected := fmt.Sprintf("line%d\n", first+i); line != expected {
+			t.Fatalf("expected line %d to be %q, got %q", i, expected, line)
+		}
+	}
+}
+
+func TestReadLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := newTestLogger(t, dir, nil)
+	defer l.Close()
+
+	start := time.Unix(1500000000, 0)
+	logMessages(t, l, start, 0, 10)
+
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: -1}), 0, 10)
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: 3}), 7, 3)
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: 20}), 0, 10)
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: -1, Since: start.Add(6 * time.Second)}), 6, 4)
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: 0}), 0, 0)
+}
+
+func TestReadRotatedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// each record is 33 bytes, so a file is rotated after its 4th record
+	l := newTestLogger(t, dir, map[string]string{"max-size": "100", "max-file": "2"})
+	defer l.Close()
+
+	logMessages(t, l, time.Unix(1500000000, 0), 0, 10)
+
+	// the first 4 records were rotated out, the next 4 are in the rotated
+	// file and the last 2 in the current one
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: -1}), 4, 6)
+	expectLines(t, readMessages(t, l, logger.ReadConfig{Tail: 3}), 7, 3)
+
+	if _, err := os.Stat(l.writer.LogPath() + ".1.gz"); err != nil {
+		t.Fatalf("expected rotated file to be compressed: %v", err)
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a file is rotated after its 7th record
+	l := newTestLogger(t, dir, map[string]string{"max-size": "200", "max-file": "2"})
+
+	start := time.Unix(1500000000, 0)
+	logMessages(t, l, start, 0, 2)
+
+	watcher := l.ReadLogs(logger.ReadConfig{Tail: 1, Follow: true})
+	defer watcher.Close()
+
+	var lines []string
+	select {
+	case msg := <-watcher.Msg:
+		lines = append(lines, string(msg.Line))
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout tailing logs")
+	}
+
+	// the follower has to see the records written across the rotation
+	go logMessages(t, l, start, 2, 8)
+
+	for len(lines) < 9 {
+		select {
+		case msg := <-watcher.Msg:
+			lines = append(lines, string(msg.Line))
+		case err := <-watcher.Err:
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout following logs, got %v", lines)
+		}
+	}
+	expectLines(t, lines, 1, 9)
+
+	l.Close()
+	select {
+	case _, ok := <-watcher.Msg:
+		if ok {
+			t.Fatal("expected no more messages after the logger is closed")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the follower to stop")
+	}
+}
+
+func TestDecodeIncompleteRecord(t *testing.T) {
+	entry := logdriver.LogEntry{Source: "stderr", TimeNano: 1500000000, Line: []byte("partial"), Partial: true}
+	record, err := marshalRecord(&entry, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(record[:len(record)/2])
+	dec := newDecoder(buf)
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected io.EOF decoding an incomplete record, got %v", err)
+	}
+
+	buf.Write(record[len(record)/2:])
+	msg, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Source != "stderr" || string(msg.Line) != "partial" || !msg.Partial || msg.Timestamp.UnixNano() != 1500000000 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	for _, tc := range []struct {
+		config map[string]string
+		valid  bool
+	}{
+		{config: map[string]string{"max-size": "10m", "max-file": "3", "compress": "false"}, valid: true},
+		{config: map[string]string{"max-file": "1"}, valid: true},
+		{config: map[string]string{"max-file": "1", "compress": "true"}},
+		{config: map[string]string{"max-file": "0"}},
+		{config: map[string]string{"max-size": "-1"}},
+		{config: map[string]string{"labels": "foo"}},
+	} {
+		err := ValidateLogOpt(tc.config)
+		if tc.valid && err != nil {
+			t.Fatalf("unexpected error for %v: %v", tc.config, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("expected an error for %v", tc.config)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package local
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types/plugins/logdriver"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	"github.com/docker/docker/pkg/filenotify"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var errDone = errors.New("done")
+
+// ReadLogs implements the logger's LogReader interface for the logs
+// created by this driver.
+func (d *driver) ReadLogs(config logger.ReadConfig) *logger.LogWatcher {
+	logWatcher := logger.NewLogWatcher()
+
+	go d.readLogs(logWatcher, config)
+	return logWatcher
+}
+
+func (d *driver) readLogs(logWatcher *logger.LogWatcher, config logger.ReadConfig) {
+	defer close(logWatcher.Msg)
+
+	// lock so the read stream doesn't get corrupted due to rotations or
+	// other log data written while we read, the files are only opened
+	// while locked and read after unlocking so that writes are not blocked
+	d.mu.Lock()
+
+	// rotated files may have to be decompressed, so only open them when
+	// they are going to be read
+	var rotatedFiles []loggerutils.ReadSeekCloser
+	if config.Tail != 0 {
+		var err error
+		rotatedFiles, err = d.writer.OpenRotatedFiles()
+		if err != nil {
+			logWatcher.Err <- err
+			d.mu.Unlock()
+			return
+		}
+	}
+	var files []io.ReadSeeker
+	for _, f := range rotatedFiles {
+		files = append(files, f)
+	}
+
+	latestFile, err := os.Open(d.writer.LogPath())
+	if err != nil {
+		for _, f := range rotatedFiles {
+			f.Close()
+		}
+		logWatcher.Err <- err
+		d.mu.Unlock()
+		return
+	}
+	defer latestFile.Close()
+
+	// only the records written before unlocking are tailed, the following
+	// ones are read by followLogs from the end of the tail
+	size, err := latestFile.Seek(0, os.SEEK_END)
+	if err != nil {
+		for _, f := range rotatedFiles {
+			f.Close()
+		}
+		logWatcher.Err <- err
+		d.mu.Unlock()
+		return
+	}
+
+	follow := config.Follow && !d.closed
+	var notifyRotate chan interface{}
+	if follow {
+		notifyRotate = d.writer.NotifyRotate()
+		defer d.writer.NotifyRotateEvict(notifyRotate)
+
+		d.readers[logWatcher] = struct{}{}
+	}
+
+	d.mu.Unlock()
+
+	if config.Tail != 0 {
+		tailFiles(ioutils.MultiReadSeeker(append(files, io.NewSectionReader(latestFile, 0, size))...), logWatcher, config.Tail, config.Since)
+	}
+
+	for _, f := range rotatedFiles {
+		if err := f.Close(); err != nil {
+			logrus.WithField("logger", Name).Warnf("error closing tailed log file: %v", err)
+		}
+	}
+
+	if !follow {
+		return
+	}
+
+	followLogs(latestFile, logWatcher, notifyRotate, config.Since)
+
+	d.mu.Lock()
+	delete(d.readers, logWatcher)
+	d.mu.Unlock()
+}
+
+// tailFiles sends the last tail messages of r, or all of them if tail is
+// negative.
+func tailFiles(r io.ReadSeeker, logWatcher *logger.LogWatcher, tail int, since time.Time) {
+	if tail > 0 {
+		if err := seekToTail(r, tail); err != nil {
+			logWatcher.Err <- err
+			return
+		}
+	}
+
+	err := sendMessages(newDecoder(r), logWatcher, logWatcher.WatchClose(), since)
+	if err != nil && err != errDone {
+		logWatcher.Err <- err
+	}
+}
+
+// seekToTail seeks r to the start of its last n records, reading their
+// sizes backwards from the end of r.
+func seekToTail(r io.ReadSeeker, n int) error {
+	offset, err := r.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+
+	sizeBuf := make([]byte, encodeBinaryLen)
+	for i := 0; i < n && offset > 0; i++ {
+		if _, err := r.Seek(offset-encodeBinaryLen, os.SEEK_SET); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, sizeBuf); err != nil {
+			return errors.Wrap(err, "error reading log record size")
+		}
+		size := int64(binary.BigEndian.Uint32(sizeBuf))
+		if size > maxMsgLen || size+2*encodeBinaryLen > offset {
+			return errors.New("log file is corrupted")
+		}
+		offset -= size + 2*encodeBinaryLen
+	}
+
+	_, err = r.Seek(offset, os.SEEK_SET)
+	return err
+}
+
+// sendMessages decodes messages and sends them to the watcher until the end
+// of the stream. It returns errDone if done is closed first.
+func sendMessages(dec *decoder, logWatcher *logger.LogWatcher, done <-chan struct{}, since time.Time) error {
+	for {
+		msg, err := dec.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !since.IsZero() && msg.Timestamp.Before(since) {
+			continue
+		}
+		select {
+		case <-done:
+			return errDone
+		case logWatcher.Msg <- msg:
+		}
+	}
+}
+
+func watchFile(name string) (filenotify.FileWatcher, error) {
+	fileWatcher, err := filenotify.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fileWatcher.Add(name); err != nil {
+		logrus.WithField("logger", Name).Warnf("falling back to file poller due to error: %v", err)
+		fileWatcher.Close()
+		fileWatcher = filenotify.NewPollingWatcher()
+
+		if err := fileWatcher.Add(name); err != nil {
+			fileWatcher.Close()
+			logrus.Debugf("error watching log file for modifications: %v", err)
+			return nil, err
+		}
+	}
+	return fileWatcher, nil
+}
+
+func followLogs(f *os.File, logWatcher *logger.LogWatcher, notifyRotate chan interface{}, since time.Time) {
+	dec := newDecoder(f)
+
+	name := f.Name()
+	fileWatcher, err := watchFile(name)
+	if err != nil {
+		logWatcher.Err <- err
+		return
+	}
+	defer func() {
+		f.Close()
+		fileWatcher.Remove(name)
+		fileWatcher.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-logWatcher.WatchClose():
+			fileWatcher.Remove(name)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}()
+
+	handleRotate := func() error {
+		// the records written before the rotation are read from the
+		// rotated file, which is still open
+		if err := sendMessages(dec, logWatcher, ctx.Done(), since); err != nil {
+			return err
+		}
+
+		f.Close()
+		fileWatcher.Remove(name)
+
+		// retry when the file doesn't exist
+		for retries := 0; retries <= 5; retries++ {
+			f, err = os.Open(name)
+			if err == nil || !os.IsNotExist(err) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := fileWatcher.Add(name); err != nil {
+			return err
+		}
+		dec = newDecoder(f)
+		return nil
+	}
+
+	var retries int
+	for {
+		if err := sendMessages(dec, logWatcher, ctx.Done(), since); err != nil {
+			if err != errDone {
+				logWatcher.Err <- err
+			}
+			return
+		}
+
+		// wait for more records to be written
+		select {
+		case e := <-fileWatcher.Events():
+			switch e.Op {
+			case fsnotify.Rename, fsnotify.Remove:
+				select {
+				case <-notifyRotate:
+				case <-ctx.Done():
+					return
+				}
+				if err := handleRotate(); err != nil {
+					if err != errDone {
+						logWatcher.Err <- err
+					}
+					return
+				}
+			}
+		case err := <-fileWatcher.Errors():
+			logrus.Debugf("logger got error watching file: %v", err)
+			// Something happened, let's try and stay alive and create a new watcher
+			if retries > 5 {
+				logWatcher.Err <- err
+				return
+			}
+			fileWatcher.Close()
+			fileWatcher, err = watchFile(name)
+			if err != nil {
+				logWatcher.Err <- err
+				return
+			}
+			retries++
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// decoder decodes the records of a log file into messages.
+type decoder struct {
+	rdr io.Reader
+	// buf holds the part of the current record read so far
+	buf []byte
+}
+
+func newDecoder(rdr io.Reader) *decoder {
+	return &decoder{
+		rdr: rdr,
+		buf: make([]byte, 0, initialBufSize),
+	}
+}
+
+// Decode decodes the next record. It returns io.EOF if the stream ends
+// before the record is complete; the part of the record read so far is kept,
+// so that Decode can be called again once more data was written.
+func (d *decoder) Decode() (*logger.Message, error) {
+	if err := d.fill(encodeBinaryLen); err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint32(d.buf))
+	if size > maxMsgLen {
+		return nil, errors.Errorf("log message is too large (%d > %d)", size, maxMsgLen)
+	}
+
+	total := size + 2*encodeBinaryLen
+	if err := d.fill(total); err != nil {
+		return nil, err
+	}
+	if trailer := int(binary.BigEndian.Uint32(d.buf[encodeBinaryLen+size:])); trailer != size {
+		return nil, errors.New("log record is corrupted")
+	}
+
+	var entry logdriver.LogEntry
+	if err := entry.Unmarshal(d.buf[encodeBinaryLen : encodeBinaryLen+size]); err != nil {
+		return nil, errors.Wrap(err, "error decoding log message")
+	}
+	d.buf = d.buf[:0]
+
+	msg := &logger.Message{
+		Source:    entry.Source,
+		Timestamp: time.Unix(0, entry.TimeNano),
+		Line:      entry.Line,
+		Partial:   entry.Partial,
+	}
+	if !msg.Partial {
+		msg.Line = append(msg.Line, '\n')
+	}
+	return msg, nil
+}
+
+// fill reads from the stream until the buffer holds n bytes.
+func (d *decoder) fill(n int) error {
+	if cap(d.buf) < n {
+		buf := make([]byte, len(d.buf), n)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	for len(d.buf) < n {
+		read, err := d.rdr.Read(d.buf[len(d.buf):n])
+		d.buf = d.buf[:len(d.buf)+read]
+		if err != nil && len(d.buf) < n {
+			return err
+		}
+	}
+	return nil
+}
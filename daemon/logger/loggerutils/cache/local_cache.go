@@ -0,0 +1,139 @@
+// Package cache provides a logger that keeps a copy of the messages sent to
+// a log driver in a local log file, so that the logs of containers using a
+// driver that cannot read logs back can still be read with `docker logs`.
+package cache
+
+import (
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/local"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DriverName is the name of the driver used for the local cache
+	DriverName = local.Name
+
+	cachePrefix      = "cache-"
+	cacheDisabledKey = cachePrefix + "disabled"
+)
+
+// builtInCacheLogOpts are the log options configuring the cache. They map to
+// the options of the local driver once the prefix is removed.
+var builtInCacheLogOpts = map[string]bool{
+	cacheDisabledKey:         true,
+	cachePrefix + "max-file": true,
+	cachePrefix + "max-size": true,
+	cachePrefix + "compress": true,
+}
+
+func init() {
+	logger.AddBuiltinLogOpts(builtInCacheLogOpts)
+	logger.RegisterExternalValidator(ValidateLogOpt)
+}
+
+// ValidateLogOpt validates the cache options in cfg. Other options are
+// ignored.
+func ValidateLogOpt(cfg map[string]string) error {
+	if _, err := IsDisabled(cfg); err != nil {
+		return err
+	}
+	if err := local.ValidateLogOpt(localConfig(cfg)); err != nil {
+		return errors.Wrap(err, "invalid log cache option")
+	}
+	return nil
+}
+
+// IsDisabled returns whether the cache-disabled option of cfg is set.
+func IsDisabled(cfg map[string]string) (bool, error) {
+	s, ok := cfg[cacheDisabledKey]
+	if !ok {
+		return false, nil
+	}
+	disabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.Wrapf(err, "error parsing %s", cacheDisabledKey)
+	}
+	return disabled, nil
+}
+
+// MergeDefaultLogConfig copies the cache options of the daemon's default
+// log configuration to cfg, unless cfg sets them already. Unlike the options
+// of the default log driver, these apply to containers using any driver.
+func MergeDefaultLogConfig(cfg, defaults map[string]string) {
+	for k, v := range defaults {
+		if !builtInCacheLogOpts[k] {
+			continue
+		}
+		if _, ok := cfg[k]; !ok {
+			cfg[k] = v
+		}
+	}
+}
+
+// localConfig returns the options of the local driver used as a cache.
+func localConfig(cfg map[string]string) map[string]string {
+	config := make(map[string]string)
+	for k, v := range cfg {
+		if builtInCacheLogOpts[k] && k != cacheDisabledKey {
+			config[k[len(cachePrefix):]] = v
+		}
+	}
+	return config
+}
+
+// WithLocalCache wraps l so that messages are also written to a local cache
+// at info.LogPath, from which the logs are read back.
+func WithLocalCache(l logger.Logger, info logger.Info) (logger.Logger, error) {
+	info.Config = localConfig(info.Config)
+	cacher, err := local.New(info)
+	if err != nil {
+		return nil, errors.Wrap(err, "error initializing local log cache driver")
+	}
+
+	return &loggerWithCache{
+		l:     l,
+		cache: cacher,
+	}, nil
+}
+
+type loggerWithCache struct {
+	l     logger.Logger
+	cache logger.Logger
+}
+
+// Log writes a copy of the message to the cache, then sends it to the
+// wrapped logger. Failing to write to the cache does not prevent the message
+// from being logged.
+func (l *loggerWithCache) Log(msg *logger.Message) error {
+	// the message is put back in the pool by the driver, so the cache gets
+	// its own copy
+	dup := logger.NewMessage()
+	dup.Line = append(dup.Line, msg.Line...)
+	dup.Source = msg.Source
+	dup.Partial = msg.Partial
+	dup.Timestamp = msg.Timestamp
+	if err := l.cache.Log(dup); err != nil {
+		logrus.WithField("driver", l.l.Name()).Warnf("error writing to log cache: %v", err)
+	}
+	return l.l.Log(msg)
+}
+
+func (l *loggerWithCache) Name() string {
+	return l.l.Name()
+}
+
+// ReadLogs reads the logs from the cache.
+func (l *loggerWithCache) ReadLogs(config logger.ReadConfig) *logger.LogWatcher {
+	return l.cache.(logger.LogReader).ReadLogs(config)
+}
+
+func (l *loggerWithCache) Close() error {
+	err := l.l.Close()
+	if err := l.cache.Close(); err != nil {
+		logrus.WithField("driver", l.l.Name()).Warnf("error closing log cache: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+)
+
+// fakeLogger records the lines it is sent, and puts the messages back in the
+// pool like the drivers do.
+type fakeLogger struct {
+	lines  []string
+	closed bool
+}
+
+func (l *fakeLogger) Log(msg *logger.Message) error {
+	l.lines = append(l.lines, string(msg.Line))
+	logger.PutMessage(msg)
+	return nil
+}
+
+func (l *fakeLogger) Name() string {
+	return "fake"
+}
+
+func (l *fakeLogger) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestLogWithCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	driver := &fakeLogger{}
+	l, err := WithLocalCache(driver, logger.Info{
+		LogPath: filepath.Join(dir, "container-cached.log"),
+		Config:  map[string]string{"cache-max-size": "1m", "labels": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"hello", "world"} {
+		msg := logger.NewMessage()
+		msg.Line = append(msg.Line, line...)
+		msg.Source = "stdout"
+		msg.Timestamp = time.Now()
+		if err := l.Log(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(driver.lines) != 2 || driver.lines[0] != "hello" || driver.lines[1] != "world" {
+		t.Fatalf("unexpected lines sent to the driver: %v", driver.lines)
+	}
+	if l.Name() != "fake" {
+		t.Fatalf("expected the name of the wrapped driver, got %s", l.Name())
+	}
+
+	watcher := l.(logger.LogReader).ReadLogs(logger.ReadConfig{Tail: -1})
+	var lines []string
+	for done := false; !done; {
+		select {
+		case msg, ok := <-watcher.Msg:
+			if !ok {
+				done = true
+				break
+			}
+			lines = append(lines, string(msg.Line))
+		case err := <-watcher.Err:
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout reading logs")
+		}
+	}
+	watcher.Close()
+	if len(lines) != 2 || lines[0] != "hello\n" || lines[1] != "world\n" {
+		t.Fatalf("unexpected lines read from the cache: %v", lines)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !driver.closed {
+		t.Fatal("expected the wrapped driver to be closed")
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	for _, tc := range []struct {
+		config map[string]string
+		valid  bool
+	}{
+		{config: map[string]string{"cache-max-size": "10m", "cache-max-file": "3", "cache-compress": "false"}, valid: true},
+		{config: map[string]string{"cache-disabled": "true", "max-file": "1", "tag": "foo"}, valid: true},
+		{config: map[string]string{"cache-disabled": "maybe"}},
+		{config: map[string]string{"cache-max-file": "0"}},
+		{config: map[string]string{"cache-max-size": "big"}},
+	} {
+		err := ValidateLogOpt(tc.config)
+		if tc.valid && err != nil {
+			t.Fatalf("unexpected error for %v: %v", tc.config, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("expected an error for %v", tc.config)
+		}
+	}
+}
+
+func TestMergeDefaultLogConfig(t *testing.T) {
+	cfg := map[string]string{"cache-max-file": "2"}
+	MergeDefaultLogConfig(cfg, map[string]string{"cache-max-file": "4", "cache-disabled": "true", "max-file": "3"})
+
+	expected := map[string]string{"cache-max-file": "2", "cache-disabled": "true"}
+	if len(cfg) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, cfg)
+	}
+	for k, v := range expected {
+		if cfg[k] != v {
+			t.Fatalf("expected %v, got %v", expected, cfg)
+		}
+	}
+}
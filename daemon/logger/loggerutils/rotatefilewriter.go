@@ -1,27 +1,41 @@
 package loggerutils
 
 import (
+	"compress/gzip"
 	"errors"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/pubsub"
 )
 
+// ReadSeekCloser is the interface of the files returned by
+// RotateFileWriter.OpenRotatedFiles.
+type ReadSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
 // RotateFileWriter is Logger implementation for default Docker logging.
 type RotateFileWriter struct {
 	f            *os.File // store for closing
 	closed       bool
 	mu           sync.Mutex
+	rotateMu     sync.Mutex
 	capacity     int64 //maximum size of each file
 	currentSize  int64 // current size of the latest file
 	maxFiles     int   //maximum number of files
+	compress     bool  // whether rotated files are gzip compressed
 	notifyRotate *pubsub.Publisher
 }
 
 //NewRotateFileWriter creates new RotateFileWriter
-func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateFileWriter, error) {
+func NewRotateFileWriter(logPath string, capacity int64, maxFiles int, compress bool) (*RotateFileWriter, error) {
 	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
 	if err != nil {
 		return nil, err
@@ -37,6 +51,7 @@ func NewRotateFileWriter(logPath string, capacity int64, maxFiles int) (*RotateF
 		capacity:     capacity,
 		currentSize:  size,
 		maxFiles:     maxFiles,
+		compress:     compress,
 		notifyRotate: pubsub.NewPublisher(0, 1),
 	}, nil
 }
@@ -67,36 +82,64 @@ func (w *RotateFileWriter) checkCapacityAndRotate() error {
 	}
 
 	if w.currentSize >= w.capacity {
+		// rotateMu is held until the rotated file is compressed, so this
+		// waits for the compression of the previous rotation to finish
+		w.rotateMu.Lock()
 		name := w.f.Name()
 		if err := w.f.Close(); err != nil {
+			w.rotateMu.Unlock()
 			return err
 		}
 		if err := rotate(name, w.maxFiles); err != nil {
+			w.rotateMu.Unlock()
 			return err
 		}
 		file, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 06400)
 		if err != nil {
+			w.rotateMu.Unlock()
 			return err
 		}
 		w.f = file
 		w.currentSize = 0
 		w.notifyRotate.Publish(struct{}{})
+
+		if w.maxFiles < 2 || !w.compress {
+			w.rotateMu.Unlock()
+			return nil
+		}
+		go func() {
+			compressFile(name + ".1")
+			w.rotateMu.Unlock()
+		}()
 	}
 
 	return nil
 }
 
+// rotate shifts the rotated files, dropping the oldest one. The files are
+// shifted whether they are compressed or not, since a file whose compression
+// failed is kept uncompressed.
 func rotate(name string, maxFiles int) error {
 	if maxFiles < 2 {
 		return nil
 	}
-	for i := maxFiles - 1; i > 1; i-- {
-		toPath := name + "." + strconv.Itoa(i)
-		fromPath := name + "." + strconv.Itoa(i-1)
-		if err := os.Rename(fromPath, toPath); err != nil && !os.IsNotExist(err) {
+
+	extensions := []string{"", ".gz"}
+	oldest := name + "." + strconv.Itoa(maxFiles-1)
+	for _, extension := range extensions {
+		if err := os.Remove(oldest + extension); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
+	for i := maxFiles - 1; i > 1; i-- {
+		for _, extension := range extensions {
+			toPath := name + "." + strconv.Itoa(i) + extension
+			fromPath := name + "." + strconv.Itoa(i-1) + extension
+			if err := os.Rename(fromPath, toPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
 
 	if err := os.Rename(name, name+".1"); err != nil && !os.IsNotExist(err) {
 		return err
@@ -104,6 +147,159 @@ func rotate(name string, maxFiles int) error {
 	return nil
 }
 
+// compressFile gzips fileName into fileName.gz and removes the original. If
+// compression fails the original file is kept, so it can still be read.
+func compressFile(fileName string) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		logrus.Errorf("Failed to open log file %s for compression: %v", fileName, err)
+		return
+	}
+	defer file.Close()
+
+	outFile, err := os.OpenFile(fileName+".gz", os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0640)
+	if err != nil {
+		logrus.Errorf("Failed to create compressed log file %s.gz: %v", fileName, err)
+		return
+	}
+
+	compressWriter := gzip.NewWriter(outFile)
+	_, err = io.Copy(compressWriter, file)
+	if err == nil {
+		err = compressWriter.Close()
+	}
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logrus.Errorf("Failed to compress log file %s: %v", fileName, err)
+		os.Remove(fileName + ".gz")
+		return
+	}
+
+	if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+		logrus.Errorf("Failed to remove log file %s after compression: %v", fileName, err)
+	}
+}
+
+// OpenRotatedFiles opens the rotated log files, from the oldest to the most
+// recent. Compressed files are only decompressed when they are first read,
+// into temporary files in the log directory which are removed when closed, so
+// the files can be opened while holding a lock and read after releasing it.
+// The caller must close all the returned files.
+func (w *RotateFileWriter) OpenRotatedFiles() (files []ReadSeekCloser, err error) {
+	// the path is read before rotateMu is locked, since a rotating writer
+	// holds mu while it waits for rotateMu
+	name := w.LogPath()
+
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			files = nil
+		}
+	}()
+
+	// make sure no compression is in progress while the files are opened
+	w.rotateMu.Lock()
+	defer w.rotateMu.Unlock()
+	for i := w.maxFiles - 1; i > 0; i-- {
+		f, gz, err := openRotatedFile(name + "." + strconv.Itoa(i))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return files, err
+		}
+		if gz {
+			files = append(files, &compressedFile{gzFile: f})
+		} else {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// openRotatedFile opens the rotated file at fileName, or, if it doesn't
+// exist, fileName.gz, in which case it also returns true.
+func openRotatedFile(fileName string) (*os.File, bool, error) {
+	f, err := os.Open(fileName)
+	if err == nil || !os.IsNotExist(err) {
+		return f, false, err
+	}
+	f, err = os.Open(fileName + ".gz")
+	return f, err == nil, err
+}
+
+// decompressFile decompresses gzFile into a temporary file next to it, so
+// that the decompressed logs are stored with the other logs of the container.
+func decompressFile(gzFile *os.File) (*os.File, error) {
+	rdr, err := gzip.NewReader(gzFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(gzFile.Name()), filepath.Base(gzFile.Name())+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(tmpFile, rdr)
+	if err == nil {
+		_, err = tmpFile.Seek(0, os.SEEK_SET)
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+	return tmpFile, nil
+}
+
+// compressedFile is a compressed rotated file, which is decompressed into a
+// temporary file when it is first read. The temporary file is removed when
+// closed.
+type compressedFile struct {
+	gzFile *os.File
+	f      *os.File
+	err    error
+}
+
+func (c *compressedFile) decompress() error {
+	if c.f == nil && c.err == nil {
+		c.f, c.err = decompressFile(c.gzFile)
+	}
+	return c.err
+}
+
+func (c *compressedFile) Read(p []byte) (int, error) {
+	if err := c.decompress(); err != nil {
+		return 0, err
+	}
+	return c.f.Read(p)
+}
+
+func (c *compressedFile) Seek(offset int64, whence int) (int64, error) {
+	if err := c.decompress(); err != nil {
+		return 0, err
+	}
+	return c.f.Seek(offset, whence)
+}
+
+func (c *compressedFile) Close() error {
+	err := c.gzFile.Close()
+	if c.f != nil {
+		if closeErr := c.f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if rmErr := os.Remove(c.f.Name()); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
 // LogPath returns the location the given writer logs to.
 func (w *RotateFileWriter) LogPath() string {
 	w.mu.Lock()
@@ -0,0 +1,150 @@
+package loggerutils
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateKeepsUncompressedFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "rotatefilewriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// name.1 was kept uncompressed because its compression failed
+	name := filepath.Join(tmp, "container.log")
+	writeRotatedFile(t, name+".2.gz", "second\n", true)
+	writeRotatedFile(t, name+".1", "third\n", false)
+	writeRotatedFile(t, name, "fourth\n", false)
+
+	w, err := NewRotateFileWriter(name, 1, 4, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// name.1 is compressed in the background after the rotation,
+	// OpenRotatedFiles waits for it
+	if _, err := w.Write([]byte("fifth\n")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := w.OpenRotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content string
+	for _, f := range files {
+		b, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content += string(b)
+	}
+	if expected := "second\nthird\nfourth\n"; content != expected {
+		t.Fatalf("expected rotated files to contain %q, got %q", expected, content)
+	}
+	if _, err := os.Stat(name + ".2"); err != nil {
+		t.Fatalf("expected the uncompressed file to be rotated to %s.2: %v", name, err)
+	}
+}
+
+func TestOpenRotatedFilesDecompressesIntoLogDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "rotatefilewriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	name := filepath.Join(tmp, "container.log")
+	writeRotatedFile(t, name+".1.gz", "first\n", true)
+	writeRotatedFile(t, name, "second\n", false)
+
+	w, err := NewRotateFileWriter(name, -1, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	files, err := w.OpenRotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 rotated file, got %d", len(files))
+	}
+	// the file is only decompressed when read
+	assertLogDirFiles(t, tmp, 2)
+	b, err := ioutil.ReadAll(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "first\n" {
+		t.Fatalf("expected the rotated file to contain %q, got %q", "first\n", b)
+	}
+	assertLogDirFiles(t, tmp, 3)
+	if err := files[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertLogDirFiles(t, tmp, 2)
+}
+
+func assertLogDirFiles(t *testing.T, dir string, n int) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != n {
+		t.Fatalf("expected %d files in the log directory, got %d", n, len(fis))
+	}
+}
+
+func TestRotateDropsOldestFile(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "rotatefilewriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	name := filepath.Join(tmp, "container.log")
+	writeRotatedFile(t, name+".2.gz", "first\n", true)
+	writeRotatedFile(t, name+".1", "second\n", false)
+	writeRotatedFile(t, name, "third\n", false)
+
+	if err := rotate(name, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name + ".2.gz"); !os.IsNotExist(err) {
+		t.Fatalf("expected %s.2.gz to be removed, got %v", name, err)
+	}
+	for _, fileName := range []string{name + ".2", name + ".1"} {
+		if _, err := os.Stat(fileName); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeRotatedFile(t *testing.T, fileName, content string, compress bool) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !compress {
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
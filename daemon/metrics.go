@@ -25,7 +25,8 @@ var (
 	healthChecksCounter       metrics.Counter
 	healthChecksFailedCounter metrics.Counter
 
-	stateCtr *stateCounter
+	stateCtr            *stateCounter
+	containerMetricsCtr *containerMetrics
 )
 
 func init() {
@@ -59,6 +60,8 @@ func init() {
 
 	stateCtr = newStateCounter(ns.NewDesc("container_states", "The count of containers in various states", metrics.Unit("containers"), "state"))
 	ns.Add(stateCtr)
+	containerMetricsCtr = newContainerMetrics(ns)
+	ns.Add(containerMetricsCtr)
 
 	metrics.Register(ns)
 }
@@ -0,0 +1,294 @@
+package daemon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/container"
+	"github.com/docker/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The container attributes that can be used as labels of the per-container
+// metrics. Other keys are container labels.
+const (
+	containerMetricsLabelID    = "id"
+	containerMetricsLabelName  = "name"
+	containerMetricsLabelImage = "image"
+)
+
+var invalidMetricLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// resourceUsage is the resource usage of a container, extracted from its
+// stats.
+type resourceUsage struct {
+	cpuSeconds   float64
+	memoryBytes  float64
+	memoryLimit  float64
+	blkioRead    float64
+	blkioWrite   float64
+	networkRx    float64
+	networkTx    float64
+	pids         float64
+	hasPids      bool
+	hasMemLimits bool
+}
+
+// containerMetrics exports the resource usage of the containers, sampled by
+// the stats collector, as per-container series. The labels of the series
+// are configurable to control their cardinality: the series of containers
+// with the same label values are summed. No series are exported when no
+// labels are configured.
+//
+// The cumulative usages are counters when the series are labeled with the
+// container ID. Otherwise they are gauges, as a sum decreases when a
+// container stops, which would be seen as a counter reset.
+type containerMetrics struct {
+	ns *metrics.Namespace
+
+	mu      sync.Mutex
+	daemon  *Daemon
+	keys    []string
+	descs   *containerMetricsDescs
+	samples map[string]*containerSample
+}
+
+type containerMetricsDescs struct {
+	cpu, memory, memoryLimit, blkioRead, blkioWrite, networkRx, networkTx, pids *prometheus.Desc
+	// cumulative is the type of the cumulative usages
+	cumulative prometheus.ValueType
+}
+
+// containerSample holds the last stats of a container sent by the stats
+// collector.
+type containerSample struct {
+	container *container.Container
+	ch        chan interface{}
+
+	mu    sync.Mutex
+	stats *types.StatsJSON
+}
+
+func newContainerMetrics(ns *metrics.Namespace) *containerMetrics {
+	return &containerMetrics{ns: ns, samples: make(map[string]*containerSample)}
+}
+
+// metricLabelNames returns the names of the metric labels for the given
+// keys, which are validated.
+func metricLabelNames(keys []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]string)
+	for _, key := range keys {
+		var name string
+		switch key {
+		case containerMetricsLabelID, containerMetricsLabelName, containerMetricsLabelImage:
+			name = key
+		case "":
+			return nil, fmt.Errorf("invalid empty metrics container label")
+		default:
+			name = "label_" + invalidMetricLabelChars.ReplaceAllString(key, "_")
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("metrics container labels %q and %q conflict", other, key)
+		}
+		seen[name] = key
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// configure sets the daemon the stats are collected from, and the keys of
+// the labels of the series.
+func (cm *containerMetrics) configure(daemon *Daemon, keys []string) error {
+	names, err := metricLabelNames(keys)
+	if err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.daemon = daemon
+	cm.keys = keys
+	if len(keys) == 0 {
+		cm.descs = nil
+		cm.unsubscribeAll()
+		return nil
+	}
+
+	cumulative := prometheus.GaugeValue
+	for _, key := range keys {
+		if key == containerMetricsLabelID {
+			cumulative = prometheus.CounterValue
+		}
+	}
+	// counters are named "<name>_<unit>_total", gauges "<name>_<unit>"
+	cumulativeDesc := func(name, help string, unit metrics.Unit) *prometheus.Desc {
+		if cumulative == prometheus.CounterValue {
+			return cm.ns.NewDesc(name+"_"+string(unit), help, metrics.Total, names...)
+		}
+		return cm.ns.NewDesc(name, help, unit, names...)
+	}
+	cm.descs = &containerMetricsDescs{
+		cpu:         cumulativeDesc("container_cpu_usage", "The total CPU time consumed by containers", metrics.Seconds),
+		memory:      cm.ns.NewDesc("container_memory_usage", "The memory used by containers", metrics.Bytes, names...),
+		memoryLimit: cm.ns.NewDesc("container_memory_limit", "The memory limit of containers", metrics.Bytes, names...),
+		blkioRead:   cumulativeDesc("container_blkio_read", "The number of bytes read from block devices by containers", metrics.Bytes),
+		blkioWrite:  cumulativeDesc("container_blkio_write", "The number of bytes written to block devices by containers", metrics.Bytes),
+		networkRx:   cumulativeDesc("container_network_receive", "The number of bytes received by containers on their network interfaces", metrics.Bytes),
+		networkTx:   cumulativeDesc("container_network_transmit", "The number of bytes transmitted by containers on their network interfaces", metrics.Bytes),
+		pids:        cm.ns.NewDesc("container_pids", "The number of processes of containers", metrics.Unit("processes"), names...),
+		cumulative:  cumulative,
+	}
+	return nil
+}
+
+// unsubscribeAll stops receiving the stats of all the containers, it is
+// called with the lock held.
+func (cm *containerMetrics) unsubscribeAll() {
+	for id, s := range cm.samples {
+		cm.daemon.statsCollector.Unsubscribe(s.container, s.ch)
+		delete(cm.samples, id)
+	}
+}
+
+// subscribe makes sure that the stats of the running containers, and only
+// them, are received. It is called with the lock held.
+func (cm *containerMetrics) subscribe() {
+	running := make(map[string]bool)
+	for _, c := range cm.daemon.List() {
+		if !c.IsRunning() {
+			continue
+		}
+		running[c.ID] = true
+		if _, ok := cm.samples[c.ID]; ok {
+			continue
+		}
+		s := &containerSample{container: c, ch: cm.daemon.statsCollector.Collect(c)}
+		cm.samples[c.ID] = s
+		go s.receive()
+	}
+	for id, s := range cm.samples {
+		if !running[id] {
+			cm.daemon.statsCollector.Unsubscribe(s.container, s.ch)
+			delete(cm.samples, id)
+		}
+	}
+}
+
+func (s *containerSample) receive() {
+	for v := range s.ch {
+		stats, ok := v.(types.StatsJSON)
+		if !ok || stats.Read.IsZero() {
+			continue
+		}
+		s.mu.Lock()
+		s.stats = &stats
+		s.mu.Unlock()
+	}
+}
+
+func (s *containerSample) last() *types.StatsJSON {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// labelValues returns the values of the labels of the series of a
+// container.
+func (cm *containerMetrics) labelValues(c *container.Container) []string {
+	var values []string
+	for _, key := range cm.keys {
+		switch key {
+		case containerMetricsLabelID:
+			values = append(values, c.ID)
+		case containerMetricsLabelName:
+			values = append(values, strings.TrimPrefix(c.Name, "/"))
+		case containerMetricsLabelImage:
+			values = append(values, c.Config.Image)
+		default:
+			values = append(values, c.Config.Labels[key])
+		}
+	}
+	return values
+}
+
+// usageByLabels returns the sum of the resource usage of the containers
+// with the same label values, it is called with the lock held.
+func (cm *containerMetrics) usageByLabels() map[string]*labeledUsage {
+	usages := make(map[string]*labeledUsage)
+	for _, s := range cm.samples {
+		stats := s.last()
+		if stats == nil {
+			continue
+		}
+		values := cm.labelValues(s.container)
+		key := strings.Join(values, "\x00")
+		u, ok := usages[key]
+		if !ok {
+			u = &labeledUsage{values: values}
+			usages[key] = u
+		}
+		u.add(containerResourceUsage(stats))
+	}
+	return usages
+}
+
+type labeledUsage struct {
+	values []string
+	resourceUsage
+}
+
+func (u *labeledUsage) add(o resourceUsage) {
+	u.cpuSeconds += o.cpuSeconds
+	u.memoryBytes += o.memoryBytes
+	u.memoryLimit += o.memoryLimit
+	u.blkioRead += o.blkioRead
+	u.blkioWrite += o.blkioWrite
+	u.networkRx += o.networkRx
+	u.networkTx += o.networkTx
+	u.pids += o.pids
+	u.hasPids = u.hasPids || o.hasPids
+	u.hasMemLimits = u.hasMemLimits || o.hasMemLimits
+}
+
+// Describe sends the descriptions of the series of the current
+// configuration. They are replaced when the labels are reloaded.
+func (cm *containerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if d := cm.descs; d != nil {
+		for _, desc := range []*prometheus.Desc{d.cpu, d.memory, d.memoryLimit, d.blkioRead, d.blkioWrite, d.networkRx, d.networkTx, d.pids} {
+			ch <- desc
+		}
+	}
+}
+
+func (cm *containerMetrics) Collect(ch chan<- prometheus.Metric) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.descs == nil || cm.daemon == nil {
+		return
+	}
+	// containers started since the last scrape are exported from the next
+	// one, once their stats have been collected
+	cm.subscribe()
+
+	d := cm.descs
+	for _, u := range cm.usageByLabels() {
+		ch <- prometheus.MustNewConstMetric(d.cpu, d.cumulative, u.cpuSeconds, u.values...)
+		ch <- prometheus.MustNewConstMetric(d.memory, prometheus.GaugeValue, u.memoryBytes, u.values...)
+		if u.hasMemLimits {
+			ch <- prometheus.MustNewConstMetric(d.memoryLimit, prometheus.GaugeValue, u.memoryLimit, u.values...)
+		}
+		ch <- prometheus.MustNewConstMetric(d.blkioRead, d.cumulative, u.blkioRead, u.values...)
+		ch <- prometheus.MustNewConstMetric(d.blkioWrite, d.cumulative, u.blkioWrite, u.values...)
+		ch <- prometheus.MustNewConstMetric(d.networkRx, d.cumulative, u.networkRx, u.values...)
+		ch <- prometheus.MustNewConstMetric(d.networkTx, d.cumulative, u.networkTx, u.values...)
+		if u.hasPids {
+			ch <- prometheus.MustNewConstMetric(d.pids, prometheus.GaugeValue, u.pids, u.values...)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	"github.com/docker/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricLabelNames(t *testing.T) {
+	names, err := metricLabelNames([]string{"name", "image", "com.docker.compose.service"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "image", "label_com_docker_compose_service"}, names)
+
+	_, err = metricLabelNames([]string{"a.b", "a-b"})
+	assert.Error(t, err)
+	_, err = metricLabelNames([]string{""})
+	assert.Error(t, err)
+}
+
+func TestContainerMetricsSumsByLabels(t *testing.T) {
+	cm := newContainerMetrics(metrics.NewNamespace("test", "", nil))
+	require.NoError(t, cm.configure(nil, []string{"com.example.service"}))
+
+	newSample := func(id, service string, memory uint64) *containerSample {
+		c := container.NewBaseContainer(id, "")
+		c.Name = "/" + id
+		c.Config = &containertypes.Config{Labels: map[string]string{"com.example.service": service}}
+		stats := &types.StatsJSON{Stats: types.Stats{Read: time.Now()}}
+		stats.MemoryStats.Usage = memory
+		return &containerSample{container: c, stats: stats}
+	}
+	cm.samples["a"] = newSample("a", "web", 1)
+	cm.samples["b"] = newSample("b", "web", 2)
+	cm.samples["c"] = newSample("c", "db", 4)
+	cm.samples["d"] = &containerSample{container: container.NewBaseContainer("d", "")}
+
+	usages := cm.usageByLabels()
+	require.Len(t, usages, 2)
+	for _, u := range usages {
+		switch u.values[0] {
+		case "web":
+			assert.Equal(t, float64(3), u.memoryBytes)
+		case "db":
+			assert.Equal(t, float64(4), u.memoryBytes)
+		default:
+			t.Fatalf("unexpected label values %v", u.values)
+		}
+	}
+}
+
+func TestContainerMetricsDescs(t *testing.T) {
+	cm := newContainerMetrics(metrics.NewNamespace("test", "", nil))
+	descs := func() []string {
+		ch := make(chan *prometheus.Desc, 10)
+		cm.Describe(ch)
+		close(ch)
+		var s []string
+		for d := range ch {
+			s = append(s, d.String())
+		}
+		return s
+	}
+	assert.Empty(t, descs())
+
+	require.NoError(t, cm.configure(nil, []string{"name"}))
+	assert.Equal(t, prometheus.GaugeValue, cm.descs.cumulative)
+	assert.Len(t, descs(), 8)
+	assert.Contains(t, cm.descs.cpu.String(), `"test_container_cpu_usage_seconds"`)
+
+	require.NoError(t, cm.configure(nil, []string{"id", "name"}))
+	assert.Equal(t, prometheus.CounterValue, cm.descs.cumulative)
+	assert.Contains(t, cm.descs.cpu.String(), `"test_container_cpu_usage_seconds_total"`)
+}
@@ -0,0 +1,35 @@
+// +build !windows
+
+package daemon
+
+import (
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// containerResourceUsage extracts the resource usage of a container from
+// its stats.
+func containerResourceUsage(stats *types.StatsJSON) resourceUsage {
+	u := resourceUsage{
+		cpuSeconds:   float64(stats.CPUStats.CPUUsage.TotalUsage) / 1e9,
+		memoryBytes:  float64(stats.MemoryStats.Usage),
+		memoryLimit:  float64(stats.MemoryStats.Limit),
+		hasMemLimits: stats.MemoryStats.Limit > 0,
+		pids:         float64(stats.PidsStats.Current),
+		hasPids:      stats.PidsStats.Current > 0,
+	}
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			u.blkioRead += float64(entry.Value)
+		case "write":
+			u.blkioWrite += float64(entry.Value)
+		}
+	}
+	for _, n := range stats.Networks {
+		u.networkRx += float64(n.RxBytes)
+		u.networkTx += float64(n.TxBytes)
+	}
+	return u
+}
@@ -0,0 +1,22 @@
+package daemon
+
+import "github.com/docker/docker/api/types"
+
+// containerResourceUsage extracts the resource usage of a container from
+// its stats.
+func containerResourceUsage(stats *types.StatsJSON) resourceUsage {
+	u := resourceUsage{
+		// the CPU usage is in 100ns intervals on Windows
+		cpuSeconds:  float64(stats.CPUStats.CPUUsage.TotalUsage) / 1e7,
+		memoryBytes: float64(stats.MemoryStats.PrivateWorkingSet),
+		blkioRead:   float64(stats.StorageStats.ReadSizeBytes),
+		blkioWrite:  float64(stats.StorageStats.WriteSizeBytes),
+		pids:        float64(stats.NumProcs),
+		hasPids:     true,
+	}
+	for _, n := range stats.Networks {
+		u.networkRx += float64(n.RxBytes)
+		u.networkTx += float64(n.TxBytes)
+	}
+	return u
+}
@@ -2,6 +2,9 @@ package daemon
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
 	"sync/atomic"
 	"time"
@@ -46,6 +49,9 @@ var (
 		"label!": true,
 		"until":  true,
 	}
+	buildCacheAcceptedFilters = map[string]bool{
+		"until": true,
+	}
 )
 
 // ContainersPrune removes unused containers
@@ -134,7 +140,7 @@ func (daemon *Daemon) VolumesPrune(ctx context.Context, pruneFilters filters.Arg
 					return nil
 				}
 			}
-			vSize, err := directory.Size(v.Path())
+			vSize, err := volumeUsage(v)
 			if err != nil {
 				logrus.Warnf("could not determine size of volume %s: %v", name, err)
 			}
@@ -427,6 +433,89 @@ func (daemon *Daemon) NetworksPrune(ctx context.Context, pruneFilters filters.Ar
 	return rep, nil
 }
 
+// BuildCachePrune removes the build caches that are not mounted by a
+// container nor leased by a build, and the imported build cache manifest
+// entries.
+func (daemon *Daemon) BuildCachePrune(ctx context.Context, pruneFilters filters.Args) (*types.BuildCachePruneReport, error) {
+	if !atomic.CompareAndSwapInt32(&daemon.pruneRunning, 0, 1) {
+		return nil, errPruneRunning
+	}
+	defer atomic.StoreInt32(&daemon.pruneRunning, 0)
+
+	// make sure that only accepted filters have been received
+	err := pruneFilters.Validate(buildCacheAcceptedFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	until, err := getUntilFromPruneFilters(pruneFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &types.BuildCachePruneReport{}
+	rep.CachesDeleted, err = daemon.pruneCacheManifest(until)
+	if err != nil {
+		return nil, err
+	}
+
+	daemon.buildCacheLock.Lock()
+	defer daemon.buildCacheLock.Unlock()
+
+	inUse := make(map[string]bool)
+	for _, c := range daemon.List() {
+		for _, m := range c.HostConfig.Mounts {
+			inUse[filepath.Clean(m.Source)] = true
+		}
+	}
+
+	dirs, err := ioutil.ReadDir(daemon.buildCacheRoot())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rep, nil
+		}
+		return nil, err
+	}
+	for _, d := range dirs {
+		select {
+		case <-ctx.Done():
+			logrus.Warnf("BuildCachePrune operation cancelled: %#v", *rep)
+			return rep, ctx.Err()
+		default:
+		}
+
+		dir := filepath.Join(daemon.buildCacheRoot(), d.Name())
+		if inUse[filepath.Join(dir, "data")] || daemon.buildCacheLeases[dir] > 0 {
+			continue
+		}
+		idFile := filepath.Join(dir, "id")
+		fi, err := os.Stat(idFile)
+		if err != nil {
+			logrus.Warnf("could not read build cache %s: %v", dir, err)
+			continue
+		}
+		if !until.IsZero() && fi.ModTime().After(until) {
+			continue
+		}
+		id, err := ioutil.ReadFile(idFile)
+		if err != nil {
+			logrus.Warnf("could not read build cache %s: %v", dir, err)
+			continue
+		}
+		size, err := directory.Size(dir)
+		if err != nil {
+			logrus.Warnf("could not determine size of build cache %s: %v", id, err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logrus.Warnf("could not remove build cache %s: %v", id, err)
+			continue
+		}
+		rep.SpaceReclaimed += uint64(size)
+		rep.CachesDeleted = append(rep.CachesDeleted, string(id))
+	}
+	return rep, nil
+}
+
 func getUntilFromPruneFilters(pruneFilters filters.Args) (time.Time, error) {
 	until := time.Time{}
 	if !pruneFilters.Include("until") {
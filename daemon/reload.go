@@ -23,6 +23,7 @@ import (
 // - Insecure registries
 // - Registry mirrors
 // - Daemon live restore
+// - Labels of the per-container metrics
 func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	daemon.configStore.Lock()
 	attributes := map[string]string{}
@@ -60,6 +61,15 @@ func (daemon *Daemon) Reload(conf *config.Config) (err error) {
 	if err := daemon.reloadLiveRestore(conf, attributes); err != nil {
 		return err
 	}
+	if err := daemon.reloadAuthorizationPolicy(conf, attributes); err != nil {
+		return err
+	}
+	if err := daemon.reloadEventSinks(conf, attributes); err != nil {
+		return err
+	}
+	if err := daemon.reloadMetricsContainerLabels(conf, attributes); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -300,6 +310,10 @@ func (daemon *Daemon) reloadRegistryMirrors(conf *config.Config, attributes map[
 func (daemon *Daemon) reloadLiveRestore(conf *config.Config, attributes map[string]string) error {
 	// update corresponding configuration
 	if conf.IsValueSet("live-restore") {
+		// lazy-pull cannot be reloaded, it may only be set by a flag
+		if conf.LiveRestoreEnabled && daemon.configStore.LazyPull {
+			return fmt.Errorf("live-restore cannot be enabled with lazy-pull")
+		}
 		daemon.configStore.LiveRestoreEnabled = conf.LiveRestoreEnabled
 		if err := daemon.containerdRemote.UpdateOptions(libcontainerd.WithLiveRestore(conf.LiveRestoreEnabled)); err != nil {
 			return err
@@ -310,3 +324,54 @@ func (daemon *Daemon) reloadLiveRestore(conf *config.Config, attributes map[stri
 	attributes["live-restore"] = fmt.Sprintf("%t", daemon.configStore.LiveRestoreEnabled)
 	return nil
 }
+
+// reloadAuthorizationPolicy reads the authorization policy file again, so
+// that changes to the file are applied, and updates the passed attributes
+func (daemon *Daemon) reloadAuthorizationPolicy(conf *config.Config, attributes map[string]string) error {
+	policyFile := daemon.configStore.AuthorizationPolicy
+	if conf.IsValueSet("authorization-policy") {
+		policyFile = conf.AuthorizationPolicy
+	}
+	if err := daemon.setAuthorizationPolicy(policyFile); err != nil {
+		return err
+	}
+	daemon.configStore.AuthorizationPolicy = policyFile
+
+	// prepare reload event attributes with updatable configurations
+	attributes["authorization-policy"] = policyFile
+	return nil
+}
+
+// reloadEventSinks replaces the sinks the events are forwarded to and
+// updates the passed attributes
+func (daemon *Daemon) reloadEventSinks(conf *config.Config, attributes map[string]string) error {
+	if conf.IsValueSet("event-sinks") {
+		if err := daemon.EventsService.SetSinks(conf.EventSinks); err != nil {
+			return err
+		}
+		daemon.configStore.EventSinks = conf.EventSinks
+	}
+
+	// prepare reload event attributes with updatable configurations
+	var addresses []string
+	for _, sink := range daemon.configStore.EventSinks {
+		addresses = append(addresses, sink.Type+"="+sink.Address)
+	}
+	attributes["event-sinks"] = fmt.Sprintf("%v", addresses)
+	return nil
+}
+
+// reloadMetricsContainerLabels updates the labels of the per-container
+// metrics and updates the passed attributes
+func (daemon *Daemon) reloadMetricsContainerLabels(conf *config.Config, attributes map[string]string) error {
+	if conf.IsValueSet("metrics-container-labels") {
+		if err := containerMetricsCtr.configure(daemon, conf.MetricsContainerLabels); err != nil {
+			return err
+		}
+		daemon.configStore.MetricsContainerLabels = conf.MetricsContainerLabels
+	}
+
+	// prepare reload event attributes with updatable configurations
+	attributes["metrics-container-labels"] = fmt.Sprintf("%v", daemon.configStore.MetricsContainerLabels)
+	return nil
+}
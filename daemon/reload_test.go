@@ -41,6 +41,29 @@ func TestDaemonReloadLabels(t *testing.T) {
 	}
 }
 
+func TestDaemonReloadLiveRestoreWithLazyPull(t *testing.T) {
+	daemon := &Daemon{}
+	daemon.configStore = &config.Config{
+		CommonConfig: config.CommonConfig{
+			LazyPull: true,
+		},
+	}
+
+	newConfig := &config.Config{
+		CommonConfig: config.CommonConfig{
+			LiveRestoreEnabled: true,
+			ValuesSet:          map[string]interface{}{"live-restore": true},
+		},
+	}
+
+	if err := daemon.Reload(newConfig); err == nil {
+		t.Fatal("Expected live-restore not to be enabled with lazy-pull")
+	}
+	if daemon.configStore.LiveRestoreEnabled {
+		t.Fatal("Expected live-restore to stay disabled")
+	}
+}
+
 func TestDaemonReloadAllowNondistributableArtifacts(t *testing.T) {
 	daemon := &Daemon{
 		configStore: &config.Config{},
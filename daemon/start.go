@@ -8,6 +8,7 @@ import (
 	"syscall"
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
 	"github.com/Sirupsen/logrus"
@@ -17,8 +18,9 @@ import (
 	"github.com/docker/docker/container"
 )
 
-// ContainerStart starts a container.
-func (daemon *Daemon) ContainerStart(name string, hostConfig *containertypes.HostConfig, checkpoint string, checkpointDir string) error {
+// ContainerStart starts a container. When the container waits for its links
+// to be healthy, the wait is aborted if ctx is canceled.
+func (daemon *Daemon) ContainerStart(ctx context.Context, name string, hostConfig *containertypes.HostConfig, checkpoint string, checkpointDir string) error {
 	if checkpoint != "" && !daemon.HasExperimental() {
 		return apierrors.NewBadRequestError(fmt.Errorf("checkpoint is only supported in experimental mode"))
 	}
@@ -83,6 +85,12 @@ func (daemon *Daemon) ContainerStart(name string, hostConfig *containertypes.Hos
 		}
 	}
 
+	if container.HostConfig.WaitHealthy {
+		if err := daemon.waitLinksHealthy(ctx, container); err != nil {
+			return apierrors.NewRequestConflictError(err)
+		}
+	}
+
 	return daemon.containerStart(container, checkpoint, checkpointDir, true)
 }
 
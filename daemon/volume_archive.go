@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+
+	apierrors "github.com/docker/docker/api/errors"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/volume"
+	volumestore "github.com/docker/docker/volume/store"
+	"github.com/pkg/errors"
+)
+
+// VolumeExport writes a tar archive of the content of a volume to the given
+// writer. The volume is mounted while it is archived.
+func (daemon *Daemon) VolumeExport(name string, out io.Writer) error {
+	v, err := daemon.volumes.Get(name)
+	if err != nil {
+		return err
+	}
+	ref := "export-" + stringid.GenerateNonCryptoID()
+	v, err = daemon.volumes.GetWithRef(v.Name(), v.DriverName(), ref)
+	if err != nil {
+		return err
+	}
+	defer daemon.volumes.Dereference(v, ref)
+
+	path, err := mountVolume(v, ref)
+	if err != nil {
+		return err
+	}
+	defer v.Unmount(ref)
+
+	// the volume is archived in a chroot of the volume, so that its
+	// symlinks cannot point out of it, even if swapped while archiving
+	uidMaps, gidMaps := daemon.GetUIDGIDMaps()
+	data, err := chrootarchive.Tar(path, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     uidMaps,
+		GIDMaps:     gidMaps,
+	}, path)
+	if err != nil {
+		return fmt.Errorf("Error exporting volume %s: %v", name, err)
+	}
+	defer data.Close()
+
+	if _, err := io.Copy(out, data); err != nil {
+		return fmt.Errorf("Error exporting volume %s: %v", name, err)
+	}
+	daemon.LogVolumeEvent(v.Name(), "export", map[string]string{"driver": v.DriverName()})
+	return nil
+}
+
+// VolumeImport extracts a tar archive into a volume. Existing files of the
+// volume are overwritten by the ones of the archive. A volume referenced by
+// containers is only imported into when force is set.
+func (daemon *Daemon) VolumeImport(name string, force bool, content io.Reader) error {
+	v, err := daemon.volumes.Get(name)
+	if err != nil {
+		return err
+	}
+	ref := "import-" + stringid.GenerateNonCryptoID()
+	if force {
+		v, err = daemon.volumes.GetWithRef(v.Name(), v.DriverName(), ref)
+	} else {
+		v, err = daemon.volumes.GetUnusedWithRef(v.Name(), v.DriverName(), ref)
+	}
+	if err != nil {
+		if volumestore.IsInUse(err) {
+			return apierrors.NewRequestConflictError(errors.Wrap(err, "unable to import into volume, use force to import into volumes in use"))
+		}
+		return err
+	}
+	defer daemon.volumes.Dereference(v, ref)
+
+	path, err := mountVolume(v, ref)
+	if err != nil {
+		return err
+	}
+	defer v.Unmount(ref)
+
+	// the archive is extracted in a chroot of the volume, so that its
+	// symlinks cannot point out of it
+	if err := chrootarchive.Untar(content, path, daemon.defaultTarCopyOptions(false)); err != nil {
+		return fmt.Errorf("Error importing into volume %s: %v", name, err)
+	}
+	daemon.LogVolumeEvent(v.Name(), "import", map[string]string{"driver": v.DriverName()})
+	return nil
+}
+
+func mountVolume(v volume.Volume, ref string) (string, error) {
+	path, err := v.Mount(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "error while mounting volume %s", v.Name())
+	}
+	return path, nil
+}
@@ -0,0 +1,9 @@
+package daemon
+
+import "github.com/docker/docker/daemon/graphdriver/copy"
+
+// copyVolumeData copies the content of a volume, sharing the content of the
+// files with reflinks when the filesystem supports them.
+func copyVolumeData(srcPath, dstPath string) error {
+	return copy.DirCopy(srcPath, dstPath, copy.Content)
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package daemon
+
+import "github.com/docker/docker/pkg/chrootarchive"
+
+// copyVolumeData copies the content of a volume.
+func copyVolumeData(srcPath, dstPath string) error {
+	return chrootarchive.CopyWithTar(srcPath, dstPath)
+}
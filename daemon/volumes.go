@@ -15,6 +15,7 @@ import (
 	containertypes "github.com/docker/docker/api/types/container"
 	mounttypes "github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/pkg/directory"
 	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
 )
@@ -393,3 +394,14 @@ func (daemon *Daemon) traverseLocalVolumes(fn func(volume.Volume) error) error {
 
 	return nil
 }
+
+// volumeUsage returns the space used by a volume. It is reported by the
+// volumes tracking it, the data of the others is walked.
+func volumeUsage(v volume.Volume) (int64, error) {
+	if uv, ok := v.(interface {
+		Usage() (int64, error)
+	}); ok {
+		return uv.Usage()
+	}
+	return directory.Size(v.Path())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	refstore "github.com/docker/docker/reference"
 	"github.com/docker/docker/registry"
@@ -58,6 +59,15 @@ type ImagePullConfig struct {
 	// Schema2Types is the valid schema2 configuration types allowed
 	// by the pull operation.
 	Schema2Types []string
+	// DownloadStagingDir is the directory where partially downloaded
+	// layers are kept, so that later pulls, including pulls after a
+	// daemon restart, can resume them. This value is optional, when
+	// excluded partial downloads are discarded.
+	DownloadStagingDir string
+	// LazyPull registers the layers in the stargz format as lazy layers,
+	// whose content is fetched from the registry when it is read, if the
+	// storage driver supports it.
+	LazyPull bool
 }
 
 // ImagePushConfig stores push configuration.
@@ -74,6 +84,10 @@ type ImagePushConfig struct {
 	TrustKey libtrust.PrivateKey
 	// UploadManager dispatches uploads.
 	UploadManager *xfer.LayerUploadManager
+	// LayerCompression is the compression used for uncompressed layers
+	// which are pushed. Layers are gzip compressed unless it is
+	// archive.Zstd.
+	LayerCompression archive.Compression
 }
 
 // ImageConfigStore handles storing and getting image configurations
@@ -4,9 +4,13 @@ import (
 	"fmt"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/progress"
 	refstore "github.com/docker/docker/reference"
 	"github.com/docker/docker/registry"
@@ -158,6 +162,64 @@ func Pull(ctx context.Context, ref reference.Named, imagePullConfig *ImagePullCo
 	return TranslatePullError(lastErr, ref)
 }
 
+// PullLayers downloads the given layer blobs of the repository of ref, from
+// the bottom-most one, and registers them in the layer store. DiffIDs are
+// the diff IDs of the layers, the layers already in the layer store are not
+// downloaded. The returned function releases the layers, it must be called
+// once they are referenced, e.g. by an image.
+func PullLayers(ctx context.Context, ref reference.Named, layers []distribution.Descriptor, diffIDs []layer.DiffID, imagePullConfig *ImagePullConfig) (func(), error) {
+	if len(layers) != len(diffIDs) {
+		return nil, errRootFSMismatch
+	}
+
+	repoInfo, err := imagePullConfig.RegistryService.ResolveRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRepoName(repoInfo.Name); err != nil {
+		return nil, err
+	}
+	endpoints, err := imagePullConfig.RegistryService.LookupPullEndpoints(reference.Domain(repoInfo.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	lastErr := fmt.Errorf("no v2 endpoints found for %s", reference.FamiliarName(repoInfo.Name))
+	for _, endpoint := range endpoints {
+		// layers are only addressed by the digest of their blob in v2
+		// registries
+		if endpoint.Version != registry.APIVersion2 {
+			continue
+		}
+		repo, _, err := NewV2Repository(ctx, repoInfo, endpoint, imagePullConfig.MetaHeaders, imagePullConfig.AuthConfig, "pull")
+		if err != nil {
+			logrus.Debugf("Error getting v2 registry %s: %v", endpoint.URL, err)
+			lastErr = err
+			continue
+		}
+
+		v2MetadataService := metadata.NewV2MetadataService(imagePullConfig.MetadataStore)
+		var descriptors []xfer.DownloadDescriptor
+		for i, l := range layers {
+			descriptors = append(descriptors, &v2LayerDescriptor{
+				digest:            l.Digest,
+				diffID:            diffIDs[i],
+				repo:              repo,
+				repoInfo:          repoInfo,
+				V2MetadataService: v2MetadataService,
+				src:               l,
+				stagingDir:        imagePullConfig.DownloadStagingDir,
+			})
+		}
+		_, release, err := imagePullConfig.DownloadManager.Download(ctx, *image.NewRootFS(), descriptors, imagePullConfig.ProgressOutput)
+		if err != nil {
+			return nil, TranslatePullError(err, ref)
+		}
+		return release, nil
+	}
+	return nil, TranslatePullError(lastErr, ref)
+}
+
 // writeStatus writes a status message to out. If layersDownloaded is true, the
 // status message indicates that a newer image was downloaded. Otherwise, it
 // indicates that the image is up to date. requestedTag is the tag the message
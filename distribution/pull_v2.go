@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/distribution"
@@ -138,6 +140,14 @@ type v2LayerDescriptor struct {
 	tmpFile           *os.File
 	verifier          digest.Verifier
 	src               distribution.Descriptor
+	// stagingDir is where partial downloads are kept so they can be
+	// resumed by later pulls. If empty, partial downloads are discarded.
+	stagingDir string
+	// lazy is whether the layer may be registered as a lazy layer.
+	lazy bool
+	// tocDigest is the digest of the stargz table of contents of the
+	// layer, recorded in the annotations of the manifest.
+	tocDigest digest.Digest
 }
 
 func (ld *v2LayerDescriptor) Key() string {
@@ -164,26 +174,41 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 	)
 
 	if ld.tmpFile == nil {
-		ld.tmpFile, err = createDownloadFile()
+		// A staged partial download from an earlier pull is picked up
+		// here, and resumed like the partial download of a failed
+		// attempt.
+		ld.tmpFile, err = ld.createDownloadFile()
 		if err != nil {
 			return nil, 0, xfer.DoNotRetry{Err: err}
 		}
-	} else {
-		offset, err = ld.tmpFile.Seek(0, os.SEEK_END)
-		if err != nil {
-			logrus.Debugf("error seeking to end of download file: %v", err)
-			offset = 0
+	}
 
-			ld.tmpFile.Close()
-			if err := os.Remove(ld.tmpFile.Name()); err != nil {
-				logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
-			}
-			ld.tmpFile, err = createDownloadFile()
-			if err != nil {
-				return nil, 0, xfer.DoNotRetry{Err: err}
+	offset, err = ld.tmpFile.Seek(0, os.SEEK_END)
+	if err != nil {
+		logrus.Debugf("error seeking to end of download file: %v", err)
+		offset = 0
+
+		ld.tmpFile.Close()
+		if err := os.Remove(ld.tmpFile.Name()); err != nil {
+			logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
+		}
+		ld.tmpFile, err = ld.createDownloadFile()
+		if err != nil {
+			return nil, 0, xfer.DoNotRetry{Err: err}
+		}
+	} else if offset != 0 {
+		logrus.Debugf("attempting to resume download of %q from %d bytes", ld.digest, offset)
+
+		if ld.verifier == nil {
+			// The partial download was staged by an earlier pull, so
+			// its content has not been hashed yet.
+			if err := ld.hashDownloadFile(offset); err != nil {
+				logrus.Debugf("error hashing partial download of %q: %v", ld.digest, err)
+				offset = 0
+				if err := ld.truncateDownloadFile(); err != nil {
+					return nil, 0, xfer.DoNotRetry{Err: err}
+				}
 			}
-		} else if offset != 0 {
-			logrus.Debugf("attempting to resume download of %q from %d bytes", ld.digest, offset)
 		}
 	}
 
@@ -235,7 +260,11 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 		ld.verifier = ld.digest.Verifier()
 	}
 
-	_, err = io.Copy(tmpFile, io.TeeReader(reader, ld.verifier))
+	// A range request from the end of the blob would fail, so a complete
+	// staged download is not read from the registry again.
+	if size == 0 || offset < size {
+		_, err = io.Copy(tmpFile, io.TeeReader(reader, ld.verifier))
+	}
 	if err != nil {
 		if err == transport.ErrWrongCodeForByteRange {
 			if err := ld.truncateDownloadFile(); err != nil {
@@ -261,6 +290,11 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 
 			return nil, 0, err
 		}
+		// Do not keep content which failed verification staged for
+		// later pulls.
+		if err := ld.truncateDownloadFile(); err != nil {
+			logrus.Errorf("Failed to truncate download file: %s", ld.tmpFile.Name())
+		}
 		return nil, 0, xfer.DoNotRetry{Err: err}
 	}
 
@@ -296,12 +330,68 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 func (ld *v2LayerDescriptor) Close() {
 	if ld.tmpFile != nil {
 		ld.tmpFile.Close()
+		if fi, err := os.Stat(ld.tmpFile.Name()); ld.stagingDir != "" && err == nil && fi.Size() != 0 {
+			// keep the partial download for later pulls to resume
+			return
+		}
 		if err := os.RemoveAll(ld.tmpFile.Name()); err != nil {
 			logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
 		}
 	}
 }
 
+// PartialSize returns the size of the partial download the next download
+// attempt resumes from.
+func (ld *v2LayerDescriptor) PartialSize() int64 {
+	if ld.tmpFile != nil {
+		if fi, err := ld.tmpFile.Stat(); err == nil {
+			return fi.Size()
+		}
+		return 0
+	}
+	if ld.stagingDir == "" || ld.digest.Validate() != nil {
+		return 0
+	}
+	fi, err := os.Stat(stagingPath(ld.stagingDir, ld.digest))
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// createDownloadFile opens the file the blob is downloaded to. If the
+// descriptor has a staging directory, this is the staging file of the blob,
+// which may contain a partial download from an earlier pull. Otherwise, it is
+// a new temporary file.
+func (ld *v2LayerDescriptor) createDownloadFile() (*os.File, error) {
+	if ld.stagingDir == "" {
+		return createDownloadFile()
+	}
+	// the digest is part of a path, so it must not contain separators
+	if err := ld.digest.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(ld.stagingDir, 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(stagingPath(ld.stagingDir, ld.digest), os.O_RDWR|os.O_CREATE, 0600)
+}
+
+// hashDownloadFile feeds the first size bytes of the download file to a new
+// verifier, leaving the file offset at size.
+func (ld *v2LayerDescriptor) hashDownloadFile(size int64) error {
+	ld.verifier = ld.digest.Verifier()
+
+	if _, err := ld.tmpFile.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(ld.verifier, ld.tmpFile, size); err != nil {
+		ld.verifier = nil
+		return err
+	}
+	return nil
+}
+
 func (ld *v2LayerDescriptor) truncateDownloadFile() error {
 	// Need a new hash context since we will be redoing the download
 	ld.verifier = nil
@@ -321,7 +411,7 @@ func (ld *v2LayerDescriptor) truncateDownloadFile() error {
 
 func (ld *v2LayerDescriptor) Registered(diffID layer.DiffID) {
 	// Cache mapping from this layer's DiffID to the blobsum
-	ld.V2MetadataService.Add(diffID, metadata.V2Metadata{Digest: ld.digest, SourceRepository: ld.repoInfo.Name.Name()})
+	ld.V2MetadataService.Add(diffID, metadata.V2Metadata{Digest: ld.digest, SourceRepository: ld.repoInfo.Name.Name(), MediaType: v2MetadataMediaType(ld.src.MediaType)})
 }
 
 func (p *v2Puller) pullV2Tag(ctx context.Context, ref reference.Named) (tagUpdated bool, err error) {
@@ -481,6 +571,7 @@ func (p *v2Puller) pullSchema1(ctx context.Context, ref reference.Named, unverif
 			repoInfo:          p.repoInfo,
 			repo:              p.repo,
 			V2MetadataService: p.V2MetadataService,
+			stagingDir:        p.config.DownloadStagingDir,
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
@@ -520,17 +611,28 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 		return target.Digest, manifestDigest, nil
 	}
 
-	var descriptors []xfer.DownloadDescriptor
+	var (
+		descriptors []xfer.DownloadDescriptor
+		tocDigests  []digest.Digest
+	)
+	if p.config.LazyPull {
+		tocDigests = layerTOCDigests(mfst)
+	}
 
 	// Note that the order of this loop is in the direction of bottom-most
 	// to top-most, so that the downloads slice gets ordered correctly.
-	for _, d := range mfst.Layers {
+	for i, d := range mfst.Layers {
 		layerDescriptor := &v2LayerDescriptor{
 			digest:            d.Digest,
 			repo:              p.repo,
 			repoInfo:          p.repoInfo,
 			V2MetadataService: p.V2MetadataService,
 			src:               d,
+			stagingDir:        p.config.DownloadStagingDir,
+			lazy:              p.config.LazyPull,
+		}
+		if i < len(tocDigests) {
+			layerDescriptor.tocDigest = tocDigests[i]
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
@@ -570,7 +672,10 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 	// which aren't suitable for NTFS. At some point in the future, if a similar
 	// check to block Windows images being pulled on Linux is implemented, it
 	// may be necessary to perform the same type of serialisation.
-	if runtime.GOOS == "windows" {
+	//
+	// Lazy layers are registered without being downloaded, so their diff IDs
+	// must be known from the configuration beforehand as well.
+	if runtime.GOOS == "windows" || p.config.LazyPull {
 		configJSON, configRootFS, err = receiveConfig(p.config.ImageStore, configChan, configErrChan)
 		if err != nil {
 			return "", "", err
@@ -897,3 +1002,29 @@ func fixManifestLayers(m *schema1.Manifest) error {
 func createDownloadFile() (*os.File, error) {
 	return ioutil.TempFile("", "GetImageBlob")
 }
+
+// stagingPath returns the path of the staging file of a blob.
+func stagingPath(stagingDir string, dgst digest.Digest) string {
+	return filepath.Join(stagingDir, dgst.Algorithm().String()+"-"+dgst.Hex())
+}
+
+// PruneDownloadStagingDir removes the partial downloads in stagingDir which
+// were not written to in the last maxAge.
+func PruneDownloadStagingDir(stagingDir string, maxAge time.Duration) error {
+	fis, err := ioutil.ReadDir(stagingDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, fi := range fis {
+		if time.Since(fi.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(stagingDir, fi.Name())); err != nil {
+			logrus.Errorf("Failed to remove partial download %s: %v", fi.Name(), err)
+		}
+	}
+	return nil
+}
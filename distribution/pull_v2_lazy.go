@@ -0,0 +1,126 @@
+package distribution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/stargz"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/net/context"
+)
+
+var errNotLazy = errors.New("lazy pulls are not enabled")
+
+// LazyBlob returns the blob the layer is registered lazily from, if it is a
+// stargz blob. Its content is fetched with range requests when it is read.
+func (ld *v2LayerDescriptor) LazyBlob(ctx context.Context) (layer.LazyBlob, error) {
+	if !ld.lazy {
+		return nil, errNotLazy
+	}
+	if ld.src.MediaType != schema2.MediaTypeLayer && ld.src.MediaType != ocispec.MediaTypeImageLayerGzip {
+		return nil, fmt.Errorf("media type %q cannot be pulled lazily", ld.src.MediaType)
+	}
+	if len(ld.src.URLs) > 0 {
+		return nil, errors.New("foreign layers cannot be pulled lazily")
+	}
+	if ld.src.Size <= stargz.FooterSize {
+		return nil, stargz.ErrNotStargz
+	}
+	// the content is served as it is fetched, it is verified against the
+	// table of contents, which must be recorded in the manifest
+	if ld.tocDigest == "" {
+		return nil, fmt.Errorf("the manifest has no %s annotation for the layer", stargz.TOCDigestAnnotation)
+	}
+
+	// The blob is still read after the pull is complete, its requests must
+	// not be cancelled with the pull.
+	blob := &remoteBlob{
+		blobs:     ld.repo.Blobs(context.Background()),
+		digest:    ld.digest,
+		size:      ld.src.Size,
+		tocDigest: ld.tocDigest,
+	}
+	footer := make([]byte, stargz.FooterSize)
+	if _, err := blob.ReadAt(footer, blob.size-stargz.FooterSize); err != nil {
+		return nil, retryOnError(err)
+	}
+	if _, err := stargz.ParseFooter(footer); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// remoteBlob reads a blob from the registry. Each read is a range request.
+type remoteBlob struct {
+	blobs     distribution.BlobStore
+	digest    digest.Digest
+	size      int64
+	tocDigest digest.Digest
+}
+
+func (b *remoteBlob) ReadAt(p []byte, off int64) (int, error) {
+	if off >= b.size {
+		return 0, io.EOF
+	}
+	rsc, err := b.blobs.Open(context.Background(), b.digest)
+	if err != nil {
+		return 0, err
+	}
+	defer rsc.Close()
+	if _, err := rsc.Seek(off, os.SEEK_SET); err != nil {
+		return 0, err
+	}
+	if remaining := b.size - off; int64(len(p)) > remaining {
+		n, err := io.ReadFull(rsc, p[:remaining])
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return io.ReadFull(rsc, p)
+}
+
+func (b *remoteBlob) Size() int64 {
+	return b.size
+}
+
+func (b *remoteBlob) Digest() digest.Digest {
+	return b.digest
+}
+
+func (b *remoteBlob) TOCDigest() digest.Digest {
+	return b.tocDigest
+}
+
+// layerTOCDigests returns the digests of the stargz tables of contents
+// recorded in the annotations of the layers of the manifest, in the order of
+// the layers. The vendored descriptors do not have the annotations, they are
+// decoded from the payload of the manifest.
+func layerTOCDigests(mfst *schema2.DeserializedManifest) []digest.Digest {
+	_, payload, err := mfst.Payload()
+	if err != nil {
+		return nil
+	}
+	var m struct {
+		Layers []struct {
+			Annotations map[string]string `json:"annotations,omitempty"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil
+	}
+	tocDigests := make([]digest.Digest, len(m.Layers))
+	for i, l := range m.Layers {
+		if dgst, err := digest.Parse(l.Annotations[stargz.TOCDigestAnnotation]); err == nil {
+			tocDigests[i] = dgst
+		}
+	}
+	return tocDigests
+}
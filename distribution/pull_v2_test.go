@@ -1,16 +1,30 @@
 package distribution
 
 import (
+	"archive/tar"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/client"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/stargz"
 	"github.com/opencontainers/go-digest"
+	"golang.org/x/net/context"
 )
 
 // TestFixManifestLayers checks that fixManifestLayers removes a duplicate
@@ -181,3 +195,181 @@ func TestValidateManifest(t *testing.T) {
 		t.Fatal("expected validateManifest to fail with digest error")
 	}
 }
+
+// TestResumeStagedDownload checks that a partial download left in the staging
+// directory by an earlier pull is resumed with a range request, and verified
+// together with the rest of the blob.
+func TestResumeStagedDownload(t *testing.T) {
+	blob := []byte(strings.Repeat("layer data", 1000))
+	dgst := digest.FromBytes(blob)
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/busybox/blobs/"+dgst.String() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(blob))
+	}))
+	defer server.Close()
+
+	named, err := reference.WithName("busybox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := client.NewRepository(context.Background(), named, server.URL, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stagingDir, err := ioutil.TempDir("", "staged-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	partial := len(blob) / 2
+	if err := ioutil.WriteFile(stagingPath(stagingDir, dgst), blob[:partial], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ld := &v2LayerDescriptor{
+		digest:     dgst,
+		repo:       repo,
+		stagingDir: stagingDir,
+	}
+	if size := ld.PartialSize(); size != int64(partial) {
+		t.Fatalf("expected partial size %d, got %d", partial, size)
+	}
+
+	rc, _, err := ld.Download(context.Background(), progress.DiscardOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := ioutil.ReadAll(rc)
+	rc.Close()
+	ld.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, blob) {
+		t.Fatal("downloaded content does not match the blob")
+	}
+	if expected := fmt.Sprintf("bytes=%d-", partial); len(ranges) != 1 || ranges[0] != expected {
+		t.Fatalf("expected a single range request %q, got %v", expected, ranges)
+	}
+	if _, err := os.Stat(stagingPath(stagingDir, dgst)); !os.IsNotExist(err) {
+		t.Fatalf("expected the staging file to be removed, got %v", err)
+	}
+}
+
+// TestLazyBlob checks that a stargz layer can be pulled lazily, and that its
+// content is read with range requests.
+func TestLazyBlob(t *testing.T) {
+	tarBuf := new(bytes.Buffer)
+	tw := tar.NewWriter(tarBuf)
+	content := strings.Repeat("layer data", 100)
+	if err := tw.WriteHeader(&tar.Header{Name: "data", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	tw.Close()
+	buf := new(bytes.Buffer)
+	w := stargz.NewWriter(buf)
+	if err := w.AppendTar(tarBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	blobs := map[digest.Digest][]byte{
+		digest.FromBytes(buf.Bytes()):    buf.Bytes(),
+		digest.FromBytes(tarBuf.Bytes()): tarBuf.Bytes(),
+	}
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blob, ok := blobs[digest.Digest(strings.TrimPrefix(r.URL.Path, "/v2/busybox/blobs/"))]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(blob))
+	}))
+	defer server.Close()
+
+	named, err := reference.WithName("busybox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := client.NewRepository(context.Background(), named, server.URL, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for dgst, blob := range blobs {
+		ld := &v2LayerDescriptor{
+			digest: dgst,
+			repo:   repo,
+			src:    distribution.Descriptor{MediaType: schema2.MediaTypeLayer, Size: int64(len(blob)), Digest: dgst},
+			lazy:   true,
+		}
+		if _, err := ld.LazyBlob(context.Background()); err == nil {
+			t.Fatal("expected a layer without a table of contents digest not to be pulled lazily")
+		}
+		ld.tocDigest = w.TOCDigest()
+		lazyBlob, err := ld.LazyBlob(context.Background())
+		if !bytes.Equal(blob, buf.Bytes()) {
+			if err != stargz.ErrNotStargz {
+				t.Fatalf("expected %v for a blob which is not in the stargz format, got %v", stargz.ErrNotStargz, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ranges = nil
+		p := make([]byte, 100)
+		if _, err := lazyBlob.ReadAt(p, 10); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, blob[10:110]) {
+			t.Fatal("read content does not match the blob")
+		}
+		if expected := "bytes=10-"; len(ranges) != 1 || ranges[0] != expected {
+			t.Fatalf("expected a single range request %q, got %v", expected, ranges)
+		}
+		if n, err := lazyBlob.ReadAt(p, lazyBlob.Size()-10); n != 10 || err != io.EOF {
+			t.Fatalf("expected to read 10 bytes at the end of the blob, got %d: %v", n, err)
+		}
+	}
+}
+
+func TestLayerTOCDigests(t *testing.T) {
+	tocDigest := digest.FromString("table of contents")
+	payload := fmt.Sprintf(`{
+   "schemaVersion": 2,
+   "mediaType": "%s",
+   "config": {"mediaType": "%s", "size": 2, "digest": "%s"},
+   "layers": [
+      {"mediaType": "%s", "size": 10, "digest": "%s"},
+      {"mediaType": "%s", "size": 20, "digest": "%s", "annotations": {"%s": "%s"}}
+   ]
+}`, schema2.MediaTypeManifest, schema2.MediaTypeImageConfig, digest.FromString("{}"),
+		schema2.MediaTypeLayer, digest.FromString("first layer"),
+		schema2.MediaTypeLayer, digest.FromString("second layer"), stargz.TOCDigestAnnotation, tocDigest)
+	mfst := &schema2.DeserializedManifest{}
+	if err := mfst.UnmarshalJSON([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	tocDigests := layerTOCDigests(mfst)
+	if len(tocDigests) != 2 || tocDigests[0] != "" || tocDigests[1] != tocDigest {
+		t.Fatalf("expected the table of contents digest of the second layer only, got %v", tocDigests)
+	}
+}
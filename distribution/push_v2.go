@@ -21,6 +21,7 @@ import (
 	"github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/stringid"
@@ -144,6 +145,7 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		endpoint:          p.endpoint,
 		repo:              p.repo,
 		pushState:         &p.pushState,
+		compression:       p.config.LayerCompression,
 	}
 
 	// Loop bounds condition is to avoid pushing the base layer on Windows.
@@ -244,6 +246,7 @@ type v2PushDescriptor struct {
 	repo              distribution.Repository
 	pushState         *pushState
 	remoteDescriptor  distribution.Descriptor
+	compression       archive.Compression
 	// a set of digests whose presence has been checked in a target repository
 	checkedDigests map[digest.Digest]struct{}
 }
@@ -260,6 +263,15 @@ func (pd *v2PushDescriptor) DiffID() layer.DiffID {
 	return pd.layer.DiffID()
 }
 
+// layerMediaType returns the media type of the blob pushed for the layer.
+// Layers which are already gzip compressed are pushed as they are.
+func (pd *v2PushDescriptor) layerMediaType() string {
+	if pd.compression == archive.Zstd && pd.layer.MediaType() == schema2.MediaTypeUncompressedLayer {
+		return MediaTypeLayerZstd
+	}
+	return schema2.MediaTypeLayer
+}
+
 func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.Output) (distribution.Descriptor, error) {
 	// Skip foreign layers unless this registry allows nondistributable artifacts.
 	if !pd.endpoint.AllowNondistributableArtifacts {
@@ -288,6 +300,8 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 	// Do we have any metadata associated with this layer's DiffID?
 	v2Metadata, err := pd.v2MetadataService.GetMetadata(diffID)
 	if err == nil {
+		// only blobs compressed the way this push compresses the layer can be reused
+		v2Metadata = filterV2MetadataByMediaType(v2Metadata, v2MetadataMediaType(pd.layerMediaType()))
 		// check for blob existence in the target repository
 		descriptor, exists, err := pd.layerAlreadyExists(ctx, progressOutput, diffID, true, 1, v2Metadata)
 		if exists || err != nil {
@@ -340,7 +354,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 		case distribution.ErrBlobMounted:
 			progress.Updatef(progressOutput, pd.ID(), "Mounted from %s", err.From.Name())
 
-			err.Descriptor.MediaType = schema2.MediaTypeLayer
+			err.Descriptor.MediaType = pd.layerMediaType()
 
 			pd.pushState.Lock()
 			pd.pushState.confirmedV2 = true
@@ -351,6 +365,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 			if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 				Digest:           err.Descriptor.Digest,
 				SourceRepository: pd.repoInfo.Name(),
+				MediaType:        v2MetadataMediaType(err.Descriptor.MediaType),
 			}); err != nil {
 				return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 			}
@@ -430,7 +445,7 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 
 	switch m := pd.layer.MediaType(); m {
 	case schema2.MediaTypeUncompressedLayer:
-		compressedReader, compressionDone := compress(reader)
+		compressedReader, compressionDone := compress(reader, pd.compression)
 		defer func(closer io.Closer) {
 			closer.Close()
 			<-compressionDone
@@ -463,13 +478,14 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 	if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 		Digest:           pushDigest,
 		SourceRepository: pd.repoInfo.Name(),
+		MediaType:        v2MetadataMediaType(pd.layerMediaType()),
 	}); err != nil {
 		return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 	}
 
 	desc := distribution.Descriptor{
 		Digest:    pushDigest,
-		MediaType: schema2.MediaTypeLayer,
+		MediaType: pd.layerMediaType(),
 		Size:      nn,
 	}
 
@@ -538,11 +554,12 @@ attempts:
 				if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 					Digest:           desc.Digest,
 					SourceRepository: pd.repoInfo.Name(),
+					MediaType:        v2MetadataMediaType(pd.layerMediaType()),
 				}); err != nil {
 					return distribution.Descriptor{}, false, xfer.DoNotRetry{Err: err}
 				}
 			}
-			desc.MediaType = schema2.MediaTypeLayer
+			desc.MediaType = pd.layerMediaType()
 			exists = true
 			break attempts
 		case distribution.ErrBlobUnknown:
@@ -565,6 +582,18 @@ attempts:
 	return desc, exists, nil
 }
 
+// filterV2MetadataByMediaType returns the metadata entries recorded with the
+// given media type.
+func filterV2MetadataByMediaType(v2Metadata []metadata.V2Metadata, mediaType string) []metadata.V2Metadata {
+	filtered := []metadata.V2Metadata{}
+	for _, meta := range v2Metadata {
+		if meta.MediaType == mediaType {
+			filtered = append(filtered, meta)
+		}
+	}
+	return filtered
+}
+
 // getMaxMountAndExistenceCheckAttempts returns a maximum number of cross repository mount attempts from
 // source repositories of target registry, maximum number of layer existence checks performed on the target
 // repository and whether the check shall be done also with digests mapped to different repositories. The
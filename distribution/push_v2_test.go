@@ -11,6 +11,7 @@ import (
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/opencontainers/go-digest"
 )
@@ -152,6 +153,7 @@ func TestLayerAlreadyExists(t *testing.T) {
 		hmacKey                string
 		maxExistenceChecks     int
 		checkOtherRepositories bool
+		compression            archive.Compression
 		remoteBlobs            map[digest.Digest]distribution.Descriptor
 		remoteErrors           map[digest.Digest]error
 		expectedDescriptor     distribution.Descriptor
@@ -246,6 +248,24 @@ func TestLayerAlreadyExists(t *testing.T) {
 			expectedRequests:   []string{"apple"},
 			expectedAdditions:  []metadata.V2Metadata{taggedMetadata("key", "apple", "docker.io/library/busybox")},
 		},
+		{
+			name:               "zstd media type",
+			targetRepo:         "busybox",
+			metadata:           []metadata.V2Metadata{{Digest: digest.Digest("apple"), SourceRepository: "docker.io/library/busybox", MediaType: MediaTypeLayerZstd}},
+			hmacKey:            "key",
+			maxExistenceChecks: 3,
+			compression:        archive.Zstd,
+			remoteBlobs:        map[digest.Digest]distribution.Descriptor{digest.Digest("apple"): {Digest: digest.Digest("apple")}},
+			expectedDescriptor: distribution.Descriptor{Digest: digest.Digest("apple"), MediaType: MediaTypeLayerZstd},
+			expectedExists:     true,
+			expectedRequests:   []string{"apple"},
+			expectedAdditions: []metadata.V2Metadata{{
+				Digest:           digest.Digest("apple"),
+				SourceRepository: "docker.io/library/busybox",
+				HMAC:             metadata.ComputeV2MetadataHMAC([]byte("key"), &metadata.V2Metadata{Digest: digest.Digest("apple"), SourceRepository: "docker.io/library/busybox"}),
+				MediaType:        MediaTypeLayerZstd,
+			}},
+		},
 		{
 			name:       "find existing blob among many",
 			targetRepo: "127.0.0.1/myapp",
@@ -400,6 +420,7 @@ func TestLayerAlreadyExists(t *testing.T) {
 			v2MetadataService: ms,
 			pushState:         &pushState{remoteLayers: make(map[layer.DiffID]distribution.Descriptor)},
 			checkedDigests:    make(map[digest.Digest]struct{}),
+			compression:       tc.compression,
 		}
 
 		desc, exists, err := pd.layerAlreadyExists(ctx, &progressSink{t}, layer.EmptyLayer.DiffID(), tc.checkOtherRepositories, tc.maxExistenceChecks, tc.metadata)
@@ -461,6 +482,19 @@ func TestLayerAlreadyExists(t *testing.T) {
 	}
 }
 
+func TestFilterV2MetadataByMediaType(t *testing.T) {
+	gzipMeta := metadata.V2Metadata{Digest: digest.Digest("apple"), SourceRepository: "docker.io/library/busybox"}
+	zstdMeta := metadata.V2Metadata{Digest: digest.Digest("pear"), SourceRepository: "docker.io/library/busybox", MediaType: MediaTypeLayerZstd}
+	all := []metadata.V2Metadata{gzipMeta, zstdMeta}
+
+	if filtered := filterV2MetadataByMediaType(all, ""); !reflect.DeepEqual(filtered, []metadata.V2Metadata{gzipMeta}) {
+		t.Errorf("unexpected gzip metadata: %#+v", filtered)
+	}
+	if filtered := filterV2MetadataByMediaType(all, MediaTypeLayerZstd); !reflect.DeepEqual(filtered, []metadata.V2Metadata{zstdMeta}) {
+		t.Errorf("unexpected zstd metadata: %#+v", filtered)
+	}
+}
+
 func taggedMetadata(key string, dgst string, sourceRepo string) metadata.V2Metadata {
 	meta := metadata.V2Metadata{
 		Digest:           digest.Digest(dgst),
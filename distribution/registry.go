@@ -37,6 +37,19 @@ var PluginTypes = []string{
 	schema2.MediaTypePluginConfig,
 }
 
+// MediaTypeLayerZstd is the media type of zstd compressed layers.
+const MediaTypeLayerZstd = "application/vnd.oci.image.layer.v1.tar+zstd"
+
+// v2MetadataMediaType returns the media type recorded in the V2 metadata of a
+// layer blob. Gzip compressed blobs, which were the only kind of layer blob
+// before other compressions were supported, are recorded without one.
+func v2MetadataMediaType(mediaType string) string {
+	if mediaType == MediaTypeLayerZstd {
+		return mediaType
+	}
+	return ""
+}
+
 var mediaTypeClasses map[string]string
 
 func init() {
@@ -13,6 +13,7 @@ import (
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/go-units"
 	"golang.org/x/net/context"
 )
 
@@ -87,6 +88,29 @@ type DownloadDescriptorWithRegistered interface {
 	Registered(diffID layer.DiffID)
 }
 
+// DownloadDescriptorWithResume is a DownloadDescriptor that has an additional
+// PartialSize method, which returns the number of bytes of the layer that
+// were already downloaded by an earlier, interrupted download. The download
+// manager calls it before each download attempt to report resumed downloads.
+// This method is called if a cast to DownloadDescriptorWithResume is
+// successful.
+type DownloadDescriptorWithResume interface {
+	DownloadDescriptor
+	PartialSize() int64
+}
+
+// DownloadDescriptorWithLazy is a DownloadDescriptor that has an additional
+// LazyBlob method, which returns a blob the layer can be registered from
+// without downloading it: the content of its files is fetched when it is
+// first read. LazyBlob returns an error if the layer cannot be registered
+// lazily, in which case it is downloaded. This method is called if a cast to
+// DownloadDescriptorWithLazy is successful, the DiffID of the layer is known
+// and the layer store is a layer.LazyStore.
+type DownloadDescriptorWithLazy interface {
+	DownloadDescriptor
+	LazyBlob(ctx context.Context) (layer.LazyBlob, error)
+}
+
 // Download is a blocking function which ensures the requested layers are
 // present in the layer store. It uses the string returned by the Key method to
 // deduplicate downloads. If a given layer is not already known to present in
@@ -115,7 +139,9 @@ func (ldm *LayerDownloadManager) Download(ctx context.Context, initialRootFS ima
 			if err == nil {
 				getRootFS := rootFS
 				getRootFS.Append(diffID)
-				l, err := ldm.layerStore.Get(getRootFS.ChainID())
+				// lazy layers are not reused until their content is
+				// verified
+				l, err := layer.GetVerified(ldm.layerStore, getRootFS.ChainID())
 				if err == nil {
 					// Layer already exists.
 					logrus.Debugf("Layer already exists: %s", descriptor.ID())
@@ -256,7 +282,25 @@ func (ldm *LayerDownloadManager) makeDownloadFunc(descriptor DownloadDescriptor,
 
 			defer descriptor.Close()
 
+			if d.registerLazy(descriptor, parentLayer, parentDownload, progressOutput) {
+				close(inactive)
+				d.registered(descriptor)
+				return
+			}
+			select {
+			case <-d.Transfer.Context().Done():
+				d.err = errors.New("layer registration cancelled")
+				return
+			default:
+			}
+
 			for {
+				if resumable, ok := descriptor.(DownloadDescriptorWithResume); ok {
+					if partialSize := resumable.PartialSize(); partialSize > 0 {
+						progress.Updatef(progressOutput, descriptor.ID(), "Resuming download at %s", units.HumanSize(float64(partialSize)))
+					}
+				}
+
 				downloadReader, size, err = descriptor.Download(d.Transfer.Context(), progressOutput)
 				if err == nil {
 					break
@@ -350,25 +394,83 @@ func (ldm *LayerDownloadManager) makeDownloadFunc(descriptor DownloadDescriptor,
 			}
 
 			progress.Update(progressOutput, descriptor.ID(), "Pull complete")
-			withRegistered, hasRegistered := descriptor.(DownloadDescriptorWithRegistered)
-			if hasRegistered {
-				withRegistered.Registered(d.layer.DiffID())
-			}
-
-			// Doesn't actually need to be its own goroutine, but
-			// done like this so we can defer close(c).
-			go func() {
-				<-d.Transfer.Released()
-				if d.layer != nil {
-					layer.ReleaseAndLog(d.layerStore, d.layer)
-				}
-			}()
+			d.registered(descriptor)
 		}()
 
 		return d
 	}
 }
 
+// registerLazy registers the layer of descriptor from its lazy blob, once
+// parentDownload is complete. It returns false if the layer cannot be
+// registered lazily, and must be downloaded.
+func (d *downloadTransfer) registerLazy(descriptor DownloadDescriptor, parentLayer layer.ChainID, parentDownload *downloadTransfer, progressOutput progress.Output) bool {
+	lazyStore, ok := d.layerStore.(layer.LazyStore)
+	if !ok {
+		return false
+	}
+	lazyDescriptor, ok := descriptor.(DownloadDescriptorWithLazy)
+	if !ok {
+		return false
+	}
+	diffID, err := descriptor.DiffID()
+	if err != nil {
+		return false
+	}
+	blob, err := lazyDescriptor.LazyBlob(d.Transfer.Context())
+	if err != nil {
+		logrus.Debugf("Cannot pull layer %s lazily: %v", descriptor.ID(), err)
+		return false
+	}
+
+	if parentDownload != nil {
+		select {
+		case <-d.Transfer.Context().Done():
+			return false
+		case <-parentDownload.Done():
+		}
+		l, err := parentDownload.result()
+		if err != nil {
+			return false
+		}
+		parentLayer = l.ChainID()
+	}
+
+	progress.Update(progressOutput, descriptor.ID(), "Mounting lazily")
+	var src distribution.Descriptor
+	if fs, ok := descriptor.(distribution.Describable); ok {
+		src = fs.Descriptor()
+	}
+	d.layer, err = lazyStore.RegisterLazy(blob, parentLayer, diffID, src)
+	if err == layer.ErrLazyNotSupported {
+		logrus.Debugf("Cannot pull layer %s lazily: %v", descriptor.ID(), err)
+		return false
+	} else if err != nil {
+		logrus.Warnf("Failed to register layer %s lazily, downloading it: %v", descriptor.ID(), err)
+		return false
+	}
+	progress.Update(progressOutput, descriptor.ID(), "Pull complete")
+	return true
+}
+
+// registered notifies descriptor the layer of the transfer is registered,
+// and releases the layer once the transfer is released.
+func (d *downloadTransfer) registered(descriptor DownloadDescriptor) {
+	withRegistered, hasRegistered := descriptor.(DownloadDescriptorWithRegistered)
+	if hasRegistered {
+		withRegistered.Registered(d.layer.DiffID())
+	}
+
+	// Doesn't actually need to be its own goroutine, but
+	// done like this so we can defer close(c).
+	go func() {
+		<-d.Transfer.Released()
+		if d.layer != nil {
+			layer.ReleaseAndLog(d.layerStore, d.layer)
+		}
+	}()
+}
+
 // makeDownloadFuncFromDownload returns a function that performs the layer
 // registration when the layer data is coming from an existing download. It
 // waits for sourceDownload and parentDownload to complete, and then
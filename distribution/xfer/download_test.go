@@ -160,6 +160,7 @@ type mockDownloadDescriptor struct {
 	registeredDiffID layer.DiffID
 	expectedDiffID   layer.DiffID
 	simulateRetries  int
+	partialSize      int64
 }
 
 // Key returns the key used to deduplicate downloads.
@@ -182,6 +183,11 @@ func (d *mockDownloadDescriptor) DiffID() (layer.DiffID, error) {
 	return "", errors.New("no diffID available")
 }
 
+// PartialSize returns the size of a simulated partial download.
+func (d *mockDownloadDescriptor) PartialSize() int64 {
+	return d.partialSize
+}
+
 func (d *mockDownloadDescriptor) Registered(diffID layer.DiffID) {
 	d.registeredDiffID = diffID
 }
@@ -355,3 +361,169 @@ func TestCancelledDownload(t *testing.T) {
 	close(progressChan)
 	<-progressDone
 }
+
+func TestResumedDownload(t *testing.T) {
+	layerStore := &mockLayerStore{make(map[layer.ChainID]*mockLayer)}
+	ldm := NewLayerDownloadManager(layerStore, maxDownloadConcurrency, func(m *LayerDownloadManager) { m.waitDuration = time.Millisecond })
+
+	progressChan := make(chan progress.Progress)
+	progressDone := make(chan struct{})
+	var actions []string
+
+	go func() {
+		for p := range progressChan {
+			actions = append(actions, p.Action)
+		}
+		close(progressDone)
+	}()
+
+	descriptors := []DownloadDescriptor{
+		&mockDownloadDescriptor{
+			id:          "id1",
+			partialSize: 2048,
+		},
+	}
+
+	_, releaseFunc, err := ldm.Download(context.Background(), *image.NewRootFS(), descriptors, progress.ChanOutput(progressChan))
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	releaseFunc()
+
+	close(progressChan)
+	<-progressDone
+
+	for _, action := range actions {
+		if action == "Resuming download at 2.048kB" {
+			return
+		}
+	}
+	t.Fatalf("did not get 'Resuming download' message, got %v", actions)
+}
+
+type mockLazyLayerStore struct {
+	*mockLayerStore
+	unverified map[layer.ChainID]bool
+	registered map[layer.ChainID]int
+}
+
+func (ls *mockLazyLayerStore) RegisterLazy(blob layer.LazyBlob, parentID layer.ChainID, diffID layer.DiffID, _ distribution.Descriptor) (layer.Layer, error) {
+	ls.registered[createChainIDFromParent(parentID, diffID)]++
+	var parent layer.Layer
+	if parentID != "" {
+		var err error
+		if parent, err = ls.Get(parentID); err != nil {
+			return nil, err
+		}
+	}
+	l := &mockLayer{parent: parent, diffID: diffID}
+	l.chainID = createChainIDFromParent(parentID, l.diffID)
+	ls.layers[l.chainID] = l
+	return l, nil
+}
+
+func (ls *mockLazyLayerStore) Verified(l layer.Layer) bool {
+	return !ls.unverified[l.ChainID()]
+}
+
+type mockLazyBlob struct {
+	*bytes.Reader
+}
+
+func (b mockLazyBlob) Digest() digest.Digest {
+	return digest.FromString("lazy blob")
+}
+
+func (b mockLazyBlob) TOCDigest() digest.Digest {
+	return digest.FromString("table of contents")
+}
+
+type mockLazyDownloadDescriptor struct {
+	*mockDownloadDescriptor
+	lazyErr    error
+	downloaded bool
+}
+
+func (d *mockLazyDownloadDescriptor) LazyBlob(ctx context.Context) (layer.LazyBlob, error) {
+	if d.lazyErr != nil {
+		return nil, d.lazyErr
+	}
+	return mockLazyBlob{bytes.NewReader(nil)}, nil
+}
+
+func (d *mockLazyDownloadDescriptor) Download(ctx context.Context, progressOutput progress.Output) (io.ReadCloser, int64, error) {
+	d.downloaded = true
+	return d.mockDownloadDescriptor.Download(ctx, progressOutput)
+}
+
+func TestLazyDownload(t *testing.T) {
+	layerStore := &mockLazyLayerStore{
+		mockLayerStore: &mockLayerStore{make(map[layer.ChainID]*mockLayer)},
+		unverified:     make(map[layer.ChainID]bool),
+		registered:     make(map[layer.ChainID]int),
+	}
+	ldm := NewLayerDownloadManager(layerStore, maxDownloadConcurrency, func(m *LayerDownloadManager) { m.waitDuration = time.Millisecond })
+
+	progressChan := make(chan progress.Progress)
+	go func() {
+		for range progressChan {
+		}
+	}()
+	defer close(progressChan)
+
+	var descriptors []DownloadDescriptor
+	for i, d := range downloadDescriptors(nil)[:3] {
+		descriptor := &mockLazyDownloadDescriptor{mockDownloadDescriptor: d.(*mockDownloadDescriptor)}
+		if i == 1 {
+			// the second layer is not a stargz blob
+			descriptor.lazyErr = errors.New("not a stargz blob")
+		} else {
+			// the diff ID of a lazy layer comes from the image config
+			descriptor.diffID = descriptor.expectedDiffID
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+
+	rootFS, releaseFunc, err := ldm.Download(context.Background(), *image.NewRootFS(), descriptors, progress.ChanOutput(progressChan))
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	releaseFunc()
+
+	for i, d := range descriptors {
+		descriptor := d.(*mockLazyDownloadDescriptor)
+		if descriptor.downloaded != (descriptor.lazyErr != nil) {
+			t.Fatalf("expected only the layers which cannot be pulled lazily to be downloaded, %v downloaded: %v", d.ID(), descriptor.downloaded)
+		}
+		if rootFS.DiffIDs[i] != descriptor.expectedDiffID {
+			t.Fatalf("rootFS item %d has the wrong diffID (expected: %v got: %v)", i, descriptor.expectedDiffID, rootFS.DiffIDs[i])
+		}
+		if descriptor.registeredDiffID != rootFS.DiffIDs[i] {
+			t.Fatal("diffID mismatch between rootFS and Registered callback")
+		}
+	}
+
+	// a lazy layer is not reused until its content is verified, it is
+	// registered again instead
+	firstLayer := createChainIDFromParent("", rootFS.DiffIDs[0])
+	layerStore.unverified[firstLayer] = true
+	descriptors[1].(*mockLazyDownloadDescriptor).diffID = rootFS.DiffIDs[1]
+	_, releaseFunc, err = ldm.Download(context.Background(), *image.NewRootFS(), descriptors, progress.ChanOutput(progressChan))
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	releaseFunc()
+	if layerStore.registered[firstLayer] != 2 {
+		t.Fatalf("expected the lazy layer to be registered again, got %d registrations", layerStore.registered[firstLayer])
+	}
+
+	layerStore.unverified[firstLayer] = false
+	_, releaseFunc, err = ldm.Download(context.Background(), *image.NewRootFS(), descriptors, progress.ChanOutput(progressChan))
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	releaseFunc()
+	if layerStore.registered[firstLayer] != 2 {
+		t.Fatalf("expected the verified lazy layer to be reused, got %d registrations", layerStore.registered[firstLayer])
+	}
+}
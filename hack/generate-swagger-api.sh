@@ -22,6 +22,7 @@ swagger generate operation -f api/swagger.yaml \
     -n ContainerTop \
     -n ContainerUpdate \
     -n ContainerWait \
+    -n ImageDiff \
     -n ImageHistory \
     -n VolumesCreate \
     -n VolumesList
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/image"
+	"github.com/pkg/errors"
+)
+
+// ExportManifest returns the cache manifest of the build steps that created
+// the given images, following their parent chains. Source is recorded in
+// the entries as the image containing their layers, and layers is called to
+// get the blobs of the layers of an image in its repository. Layers can be
+// nil when there is no source.
+func ExportManifest(store image.Store, ids []image.ID, source string, layers func(*image.Image) ([]types.BuildCacheLayer, error)) (*types.BuildCacheManifest, error) {
+	m := &types.BuildCacheManifest{Entries: []types.BuildCacheManifestEntry{}}
+	seen := make(map[image.ID]bool)
+	for _, id := range ids {
+		for id != "" && !seen[id] {
+			seen[id] = true
+			img, err := store.Get(id)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to find image %v", id)
+			}
+			entry := types.BuildCacheManifestEntry{
+				Config: img.RawJSON(),
+				Source: source,
+			}
+			if layers != nil {
+				entry.Layers, err = layers(img)
+				if err != nil {
+					return nil, err
+				}
+			}
+			// the image at the top of the chain is recorded with no
+			// parent, as `FROM scratch` steps are
+			parent, _ := store.GetParent(id)
+			entry.Parent = parent.String()
+			m.Entries = append(m.Entries, entry)
+			id = parent
+		}
+	}
+	return m, nil
+}
+
+// NewManifestCache returns an image cache based on a cache manifest. The
+// images of the matching entries are created in store when they are used.
+// Fetch is called to make the layers of an entry available when they are
+// not in the layer store, the function it returns releases them once the
+// image is created.
+func NewManifestCache(store image.Store, m *types.BuildCacheManifest, fetch func(types.BuildCacheManifestEntry) (func(), error)) *ManifestCache {
+	return &ManifestCache{store: store, manifest: m, fetch: fetch}
+}
+
+// ManifestCache is cache based on a cache manifest.
+type ManifestCache struct {
+	store    image.Store
+	manifest *types.BuildCacheManifest
+	fetch    func(types.BuildCacheManifestEntry) (func(), error)
+}
+
+// GetCache returns the image id found in the cache
+func (mc *ManifestCache) GetCache(parentID string, cfg *containertypes.Config) (string, error) {
+	var (
+		match *image.Image
+		entry types.BuildCacheManifestEntry
+	)
+	for _, e := range mc.manifest.Entries {
+		if e.Parent != parentID {
+			continue
+		}
+		img, err := image.NewFromJSON(e.Config)
+		if err != nil {
+			logrus.Warnf("invalid image config in the build cache manifest: %v", err)
+			continue
+		}
+		if compare(&img.ContainerConfig, cfg) {
+			// check for the most up to date match
+			if match == nil || match.Created.Before(img.Created) {
+				match, entry = img, e
+			}
+		}
+	}
+	if match == nil {
+		return "", nil
+	}
+
+	id, err := mc.create(entry)
+	if err != nil {
+		// the cache manifest is only a hint, the step is run if the
+		// image cannot be created
+		logrus.Warnf("unable to use the build cache manifest entry for image %v: %v", match.ID(), err)
+		return "", nil
+	}
+	return id.String(), nil
+}
+
+func (mc *ManifestCache) create(entry types.BuildCacheManifestEntry) (image.ID, error) {
+	id, err := mc.store.Create(entry.Config)
+	if err != nil && mc.fetch != nil {
+		release, fetchErr := mc.fetch(entry)
+		if fetchErr != nil {
+			return "", errors.Wrap(fetchErr, "failed to fetch the layers of the image")
+		}
+		defer release()
+		id, err = mc.store.Create(entry.Config)
+	}
+	if err != nil {
+		return "", err
+	}
+	if entry.Parent != "" {
+		if err := mc.store.SetParent(id, image.ID(entry.Parent)); err != nil {
+			return "", errors.Wrapf(err, "failed to set parent for %v to %v", id, entry.Parent)
+		}
+	}
+	return id, nil
+}
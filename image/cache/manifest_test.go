@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLayerGetReleaser struct {
+	available map[layer.ChainID]bool
+}
+
+func (ls *mockLayerGetReleaser) Get(id layer.ChainID) (layer.Layer, error) {
+	if !ls.available[id] {
+		return nil, errors.New("layer does not exist")
+	}
+	return nil, nil
+}
+
+func (ls *mockLayerGetReleaser) Release(layer.Layer) ([]layer.Metadata, error) {
+	return nil, nil
+}
+
+func newTestImageStore(t *testing.T, ls image.LayerGetReleaser) (image.Store, func()) {
+	root, err := ioutil.TempDir("", "images-fs-store")
+	require.NoError(t, err)
+	fs, err := image.NewFSStoreBackend(root)
+	require.NoError(t, err)
+	store, err := image.NewImageStore(fs, ls)
+	require.NoError(t, err)
+	return store, func() { os.RemoveAll(root) }
+}
+
+const (
+	baseConfig  = `{"container_config":{"Cmd":["base"]},"rootfs":{"type":"layers"}}`
+	childConfig = `{"container_config":{"Cmd":["/bin/sh","-c","touch foo"]},"rootfs":{"type":"layers","diff_ids":["sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"]}}`
+)
+
+func TestManifestCache(t *testing.T) {
+	diffID := layer.DiffID("sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae")
+	chainID := layer.CreateChainID([]layer.DiffID{diffID})
+
+	store, cleanup := newTestImageStore(t, &mockLayerGetReleaser{available: map[layer.ChainID]bool{chainID: true}})
+	defer cleanup()
+	baseID, err := store.Create([]byte(baseConfig))
+	require.NoError(t, err)
+	childID, err := store.Create([]byte(childConfig))
+	require.NoError(t, err)
+	require.NoError(t, store.SetParent(childID, baseID))
+
+	blobs := map[layer.DiffID]types.BuildCacheLayer{
+		diffID: {Digest: "sha256:3c8d8ef4e2e7b5b1b5a0f2c2ba5f5e4be7e58aed2c5fcbd5aea8f00f4d6a5e3c"},
+	}
+	m, err := ExportManifest(store, []image.ID{childID}, "example.com/app:cache", func(img *image.Image) ([]types.BuildCacheLayer, error) {
+		var layers []types.BuildCacheLayer
+		for _, diffID := range img.RootFS.DiffIDs {
+			layers = append(layers, blobs[diffID])
+		}
+		return layers, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, m.Entries, 2)
+	assert.Equal(t, baseID.String(), m.Entries[0].Parent)
+	assert.Equal(t, "", m.Entries[1].Parent)
+	assert.Equal(t, "example.com/app:cache", m.Entries[0].Source)
+	assert.Equal(t, []types.BuildCacheLayer{blobs[diffID]}, m.Entries[0].Layers)
+	assert.Empty(t, m.Entries[1].Layers)
+
+	// a daemon that has the base image, but not the layers of the child
+	ls := &mockLayerGetReleaser{available: map[layer.ChainID]bool{}}
+	otherStore, otherCleanup := newTestImageStore(t, ls)
+	defer otherCleanup()
+	_, err = otherStore.Create([]byte(baseConfig))
+	require.NoError(t, err)
+
+	var (
+		fetched  []types.BuildCacheLayer
+		released bool
+	)
+	mc := NewManifestCache(otherStore, m, func(entry types.BuildCacheManifestEntry) (func(), error) {
+		fetched = append(fetched, entry.Layers...)
+		ls.available[chainID] = true
+		return func() { released = true }, nil
+	})
+
+	id, err := mc.GetCache(baseID.String(), &container.Config{Cmd: []string{"/bin/sh", "-c", "touch bar"}})
+	require.NoError(t, err)
+	assert.Equal(t, "", id)
+
+	id, err = mc.GetCache(baseID.String(), &container.Config{Cmd: []string{"/bin/sh", "-c", "touch foo"}})
+	require.NoError(t, err)
+	assert.Equal(t, childID.String(), id)
+	assert.Equal(t, []types.BuildCacheLayer{blobs[diffID]}, fetched)
+	assert.True(t, released)
+
+	parent, err := otherStore.GetParent(childID)
+	require.NoError(t, err)
+	assert.Equal(t, baseID, parent)
+}
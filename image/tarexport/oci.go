@@ -0,0 +1,395 @@
+package tarexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/progress"
+	"github.com/docker/docker/pkg/system"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+type ociSaveSession struct {
+	*tarexporter
+	outDir string
+	images map[image.ID]*imageDescriptor
+}
+
+func (l *tarexporter) SaveOCI(names []string, outStream io.Writer) error {
+	images, err := l.parseNames(names)
+	if err != nil {
+		return err
+	}
+
+	return (&ociSaveSession{tarexporter: l, images: images}).save(outStream)
+}
+
+func (s *ociSaveSession) save(outStream io.Writer) error {
+	tempDir, err := ioutil.TempDir("", "docker-export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	s.outDir = tempDir
+	if err := os.MkdirAll(filepath.Join(tempDir, ociBlobsDir, string(digest.Canonical)), 0755); err != nil {
+		return err
+	}
+
+	index := ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+	}
+	// the images are saved in a stable order so that the same images give
+	// the same index
+	ids := make([]string, 0, len(s.images))
+	for id := range s.images {
+		ids = append(ids, id.String())
+	}
+	sort.Strings(ids)
+	for _, i := range ids {
+		id := image.ID(i)
+		imageDescr := s.images[id]
+		desc, err := s.saveImage(id)
+		if err != nil {
+			return err
+		}
+
+		if len(imageDescr.refs) == 0 {
+			index.Manifests = append(index.Manifests, desc)
+		}
+		for _, ref := range imageDescr.refs {
+			refDesc := desc
+			refDesc.Annotations = map[string]string{
+				ociRefNameAnnotation:   ref.Tag(),
+				ociImageNameAnnotation: ref.String(),
+			}
+			index.Manifests = append(index.Manifests, refDesc)
+		}
+		s.tarexporter.loggerImgEvent.LogImageEvent(id.String(), id.String(), "save")
+	}
+
+	layout := ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion}
+	if err := writeJSONFile(filepath.Join(tempDir, ocispec.ImageLayoutFile), layout); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(tempDir, ociIndexFileName), index); err != nil {
+		return err
+	}
+
+	fs, err := archive.Tar(tempDir, archive.Uncompressed)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	_, err = io.Copy(outStream, fs)
+	return err
+}
+
+// saveImage writes the config, layers and manifest blobs of an image, and
+// returns the descriptor of its manifest.
+func (s *ociSaveSession) saveImage(id image.ID) (ocispec.Descriptor, error) {
+	img, err := s.is.Get(id)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if len(img.RootFS.DiffIDs) == 0 {
+		return ocispec.Descriptor{}, fmt.Errorf("empty export - not implemented")
+	}
+
+	configDesc, err := s.writeBlob(ocispec.MediaTypeImageConfig, img.RawJSON())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    configDesc,
+	}
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		layerDesc, err := s.saveLayer(rootFS.ChainID())
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		manifest.Layers = append(manifest.Layers, layerDesc)
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := s.writeBlob(ocispec.MediaTypeImageManifest, manifestJSON)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.Platform = &ocispec.Platform{
+		Architecture: img.Architecture,
+		OS:           img.OS,
+	}
+	return desc, nil
+}
+
+// saveLayer writes the uncompressed tar stream of a layer as a blob. The
+// digest of that stream is the layer's DiffID.
+func (s *ociSaveSession) saveLayer(id layer.ChainID) (ocispec.Descriptor, error) {
+	l, err := s.ls.Get(id)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer layer.ReleaseAndLog(s.ls, l)
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.Digest(l.DiffID()),
+	}
+	blobPath := filepath.Join(s.outDir, ociBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Hex())
+	if fi, err := os.Stat(blobPath); err == nil {
+		// already saved as part of another image
+		desc.Size = fi.Size()
+		return desc, nil
+	}
+
+	// Use system.CreateSequential rather than os.Create. This ensures sequential
+	// file access on Windows to avoid eating into MM standby list.
+	// On Linux, this equates to a regular os.Create.
+	tarFile, err := system.CreateSequential(blobPath)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer tarFile.Close()
+
+	arch, err := l.TarStream()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer arch.Close()
+
+	if desc.Size, err = io.Copy(tarFile, arch); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := system.Chtimes(blobPath, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+func (s *ociSaveSession) writeBlob(mediaType string, content []byte) (ocispec.Descriptor, error) {
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	blobPath := filepath.Join(s.outDir, ociBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Hex())
+	if err := ioutil.WriteFile(blobPath, content, 0644); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := system.Chtimes(blobPath, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	return system.Chtimes(path, time.Unix(0, 0), time.Unix(0, 0))
+}
+
+// ociLoad loads the images of an OCI image-layout archive extracted to
+// tmpDir. Images are tagged with the reference of their index entry, see
+// ociEntryReference.
+func (l *tarexporter) ociLoad(tmpDir string, outStream io.Writer, progressOutput progress.Output) error {
+	layoutPath, err := safePath(tmpDir, ocispec.ImageLayoutFile)
+	if err != nil {
+		return err
+	}
+	layoutJSON, err := ioutil.ReadFile(layoutPath)
+	if err != nil {
+		return err
+	}
+	var layout ocispec.ImageLayout
+	if err := json.Unmarshal(layoutJSON, &layout); err != nil {
+		return err
+	}
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return fmt.Errorf("unsupported OCI image layout version %q", layout.Version)
+	}
+
+	indexPath, err := safePath(tmpDir, ociIndexFileName)
+	if err != nil {
+		return err
+	}
+	indexJSON, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		return err
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(indexJSON, &index); err != nil {
+		return err
+	}
+
+	loaded := make(map[digest.Digest]image.ID)
+	var imageIDsStr string
+	var imageRefCount int
+
+	for _, desc := range index.Manifests {
+		if desc.MediaType != ocispec.MediaTypeImageManifest {
+			return fmt.Errorf("unsupported media type %q in OCI image index", desc.MediaType)
+		}
+
+		imgID, ok := loaded[desc.Digest]
+		if !ok {
+			imgID, err = l.ociLoadImage(tmpDir, desc, progressOutput)
+			if err != nil {
+				return err
+			}
+			loaded[desc.Digest] = imgID
+			imageIDsStr += fmt.Sprintf("Loaded image ID: %s\n", imgID)
+			l.loggerImgEvent.LogImageEvent(imgID.String(), imgID.String(), "load")
+		}
+
+		ref := ociEntryReference(desc)
+		if ref == nil {
+			continue
+		}
+		l.setLoadedTag(ref, imgID.Digest(), outStream)
+		outStream.Write([]byte(fmt.Sprintf("Loaded image: %s\n", reference.FamiliarString(ref))))
+		imageRefCount++
+	}
+
+	if imageRefCount == 0 {
+		outStream.Write([]byte(imageIDsStr))
+	}
+
+	return nil
+}
+
+func (l *tarexporter) ociLoadImage(tmpDir string, desc ocispec.Descriptor, progressOutput progress.Output) (image.ID, error) {
+	manifestJSON, err := readOCIBlob(tmpDir, desc.Digest)
+	if err != nil {
+		return "", err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return "", err
+	}
+
+	config, err := readOCIBlob(tmpDir, manifest.Config.Digest)
+	if err != nil {
+		return "", err
+	}
+	img, err := image.NewFromJSON(config)
+	if err != nil {
+		return "", err
+	}
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+
+	if expected, actual := len(manifest.Layers), len(img.RootFS.DiffIDs); expected != actual {
+		return "", fmt.Errorf("invalid manifest, layers length mismatch: expected %d, got %d", expected, actual)
+	}
+
+	for i, diffID := range img.RootFS.DiffIDs {
+		r := rootFS
+		r.Append(diffID)
+		newLayer, err := layer.GetVerified(l.ls, r.ChainID())
+		if err != nil {
+			layerPath, err := ociBlobPath(tmpDir, manifest.Layers[i].Digest)
+			if err != nil {
+				return "", err
+			}
+			newLayer, err = l.loadLayer(layerPath, rootFS, diffID.String(), distribution.Descriptor{}, progressOutput)
+			if err != nil {
+				return "", err
+			}
+		}
+		defer layer.ReleaseAndLog(l.ls, newLayer)
+		if expected, actual := diffID, newLayer.DiffID(); expected != actual {
+			return "", fmt.Errorf("invalid diffID for layer %d: expected %q, got %q", i, expected, actual)
+		}
+		rootFS.Append(diffID)
+	}
+
+	return l.is.Create(config)
+}
+
+func ociBlobPath(tmpDir string, dgst digest.Digest) (string, error) {
+	if err := dgst.Validate(); err != nil {
+		return "", err
+	}
+	return safePath(tmpDir, filepath.Join(ociBlobsDir, dgst.Algorithm().String(), dgst.Hex()))
+}
+
+// readOCIBlob reads a blob and verifies its content against its digest.
+func readOCIBlob(tmpDir string, dgst digest.Digest) ([]byte, error) {
+	blobPath, err := ociBlobPath(tmpDir, dgst)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(blobPath)
+	if err != nil {
+		return nil, err
+	}
+	if actual := dgst.Algorithm().FromBytes(content); actual != dgst {
+		return nil, errors.Errorf("invalid blob %s: content has digest %s", dgst, actual)
+	}
+	return content, nil
+}
+
+// ociEntryReference returns the reference to tag the image of an index entry
+// with. The repository is taken from the image name annotation, and the tag
+// from the ref name annotation. A ref name holding a full reference, as
+// written by other tools, is also accepted. Nil is returned when the entry
+// does not name a valid tagged reference.
+func ociEntryReference(desc ocispec.Descriptor) reference.NamedTagged {
+	refName := desc.Annotations[ociRefNameAnnotation]
+	name := desc.Annotations[ociImageNameAnnotation]
+	if name == "" {
+		if refName == "" {
+			return nil
+		}
+		name = refName
+	}
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		logrus.Warnf("skipping invalid reference %q of image manifest %s: %v", name, desc.Digest, err)
+		return nil
+	}
+	if tagged, ok := named.(reference.NamedTagged); ok {
+		return tagged
+	}
+	if refName == "" || refName == name {
+		logrus.Warnf("skipping reference %q of image manifest %s: no repository and tag to tag the image with", name, desc.Digest)
+		return nil
+	}
+	tagged, err := reference.WithTag(reference.TrimNamed(named), refName)
+	if err != nil {
+		logrus.Warnf("skipping invalid tag %q of image manifest %s: %v", refName, desc.Digest, err)
+		return nil
+	}
+	return tagged
+}
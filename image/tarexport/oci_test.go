@@ -0,0 +1,32 @@
+package tarexport
+
+import (
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOCIEntryReference(t *testing.T) {
+	cases := []struct {
+		annotations map[string]string
+		expected    string
+	}{
+		{map[string]string{ociRefNameAnnotation: "1.0", ociImageNameAnnotation: "docker.io/library/app:1.0"}, "docker.io/library/app:1.0"},
+		{map[string]string{ociRefNameAnnotation: "1.0", ociImageNameAnnotation: "example.com/app"}, "example.com/app:1.0"},
+		{map[string]string{ociRefNameAnnotation: "example.com/app:1.0"}, "example.com/app:1.0"},
+		{map[string]string{ociRefNameAnnotation: "1.0"}, ""},
+		{map[string]string{ociRefNameAnnotation: "Invalid:Ref"}, ""},
+		{nil, ""},
+	}
+	for _, c := range cases {
+		ref := ociEntryReference(ocispec.Descriptor{Annotations: c.annotations})
+		if c.expected == "" {
+			assert.Nil(t, ref, "annotations %v", c.annotations)
+			continue
+		}
+		if assert.NotNil(t, ref, "annotations %v", c.annotations) {
+			assert.Equal(t, c.expected, ref.String())
+		}
+	}
+}
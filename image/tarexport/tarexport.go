@@ -13,6 +13,14 @@ const (
 	legacyConfigFileName       = "json"
 	legacyVersionFileName      = "VERSION"
 	legacyRepositoriesFileName = "repositories"
+
+	ociIndexFileName     = "index.json"
+	ociBlobsDir          = "blobs"
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+	// ociImageNameAnnotation holds the full reference of a tagged image, the
+	// ref name annotation only holds its tag. It is the annotation used by
+	// containerd for the same purpose.
+	ociImageNameAnnotation = "io.containerd.image.name"
 )
 
 type manifestItem struct {
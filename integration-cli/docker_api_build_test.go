@@ -3,11 +3,13 @@ package main
 import (
 	"archive/tar"
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/integration-cli/checker"
 	"github.com/docker/docker/integration-cli/cli/build/fakecontext"
 	"github.com/docker/docker/integration-cli/cli/build/fakegit"
@@ -249,3 +251,53 @@ func (s *DockerSuite) TestBuildAPIUnnormalizedTarPaths(c *check.C) {
 
 	c.Assert(imageA, checker.Not(checker.Equals), imageB)
 }
+
+func (s *DockerSuite) TestBuildAPISession(c *check.C) {
+	buildWithDiff := func(files map[string]string) string {
+		buffer := new(bytes.Buffer)
+		tw := tar.NewWriter(buffer)
+		for name, content := range files {
+			err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+			c.Assert(err, checker.IsNil)
+			_, err = tw.Write([]byte(content))
+			c.Assert(err, checker.IsNil)
+		}
+		c.Assert(tw.Close(), checker.IsNil)
+
+		res, body, err := request.Post("/build?session=testsession", request.RawContent(ioutil.NopCloser(buffer)), request.ContentType("application/x-tar"))
+		c.Assert(err, checker.IsNil)
+		c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+		out, err := testutil.ReadBody(body)
+		c.Assert(err, checker.IsNil)
+		return string(out)
+	}
+	defer request.Delete("/build/sessions/testsession")
+
+	out := buildWithDiff(map[string]string{
+		"Dockerfile": "FROM busybox\nCOPY . /ctx\nRUN cat /ctx/a /ctx/b",
+		"a":          "first-a",
+		"b":          "first-b",
+	})
+	c.Assert(out, checker.Contains, "first-a")
+
+	// only the changed file is sent, and b is removed
+	out = buildWithDiff(map[string]string{
+		"Dockerfile": "FROM busybox\nCOPY . /ctx\nRUN cat /ctx/a && test ! -e /ctx/b",
+		"a":          "second-a",
+		".wh.b":      "",
+	})
+	c.Assert(out, checker.Contains, "second-a")
+	c.Assert(out, checker.Contains, "Successfully built")
+
+	res, body, err := request.Get("/build/sessions/testsession")
+	c.Assert(err, checker.IsNil)
+	c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+	var session types.BuildSession
+	c.Assert(json.NewDecoder(body).Decode(&session), checker.IsNil)
+	body.Close()
+	c.Assert(session.Files, checker.HasLen, 2)
+
+	res, _, err = request.Delete("/build/sessions/testsession")
+	c.Assert(err, checker.IsNil)
+	c.Assert(res.StatusCode, checker.Equals, http.StatusNoContent)
+}
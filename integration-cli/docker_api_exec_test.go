@@ -8,12 +8,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 	"github.com/docker/docker/integration-cli/checker"
 	"github.com/docker/docker/integration-cli/request"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/docker/pkg/testutil"
 	"github.com/go-check/check"
+	"golang.org/x/net/context"
 )
 
 // Regression test for #9414
@@ -186,6 +191,39 @@ func (s *DockerSuite) TestExecAPIStartInvalidCommand(c *check.C) {
 	c.Assert(inspectJSON.ExecIDs, checker.IsNil)
 }
 
+func (s *DockerSuite) TestExecAPIStartWithWorkingDirAndExitCodeTrailer(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	name := "exec_test"
+	dockerCmd(c, "run", "-d", "--name", name, "busybox", "top")
+
+	cli, err := client.NewEnvClient()
+	c.Assert(err, checker.IsNil)
+	defer cli.Close()
+
+	config := types.ExecConfig{
+		AttachStdout: true,
+		WorkingDir:   "/tmp",
+		Cmd:          []string{"sh", "-c", "pwd; exit 3"},
+	}
+	resp, err := cli.ContainerExecCreate(context.Background(), name, config)
+	c.Assert(err, checker.IsNil)
+
+	conn, br, err := request.SockRequestHijack("POST", "/exec/"+resp.ID+"/start", strings.NewReader(`{"ExitCodeTrailer": true}`), "application/json", daemonHost())
+	c.Assert(err, checker.IsNil)
+	defer conn.Close()
+
+	stdout := new(bytes.Buffer)
+	_, exitCode, err := stdcopy.StdCopyWithExitCode(stdout, ioutil.Discard, br)
+	c.Assert(err, checker.IsNil)
+	c.Assert(stdout.String(), checker.Equals, "/tmp\n")
+	c.Assert(exitCode, checker.Equals, 3)
+
+	config.WorkingDir = "/doesnotexist"
+	_, err = cli.ContainerExecCreate(context.Background(), name, config)
+	c.Assert(err, checker.NotNil)
+	c.Assert(err.Error(), checker.Contains, "does not exist in the container")
+}
+
 func createExec(c *check.C, name string) string {
 	return createExecCmd(c, name, "true")
 }
@@ -75,6 +75,35 @@ func (s *DockerSuite) TestAPIImagesSaveAndLoad(c *check.C) {
 	c.Assert(strings.TrimSpace(string(inspectOut)), checker.Equals, id, check.Commentf("load did not work properly"))
 }
 
+func (s *DockerSuite) TestAPIImagesSaveAndLoadOCI(c *check.C) {
+	testRequires(c, Network)
+	testRequires(c, DaemonIsLinux)
+	buildImageSuccessfully(c, "saveandloadoci", build.WithDockerfile("FROM busybox\nENV FOO bar"))
+	id := getIDByName(c, "saveandloadoci")
+
+	res, body, err := request.Get("/images/saveandloadoci/get?format=oci")
+	c.Assert(err, checker.IsNil)
+	defer body.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+
+	dockerCmd(c, "rmi", "saveandloadoci")
+
+	res, loadBody, err := request.Post("/images/load", request.RawContent(body), request.ContentType("application/x-tar"))
+	c.Assert(err, checker.IsNil)
+	defer loadBody.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusOK)
+
+	inspectOut := cli.InspectCmd(c, "saveandloadoci", cli.Format(".Id")).Combined()
+	c.Assert(strings.TrimSpace(string(inspectOut)), checker.Equals, id, check.Commentf("load did not work properly"))
+}
+
+func (s *DockerSuite) TestAPIImagesSaveInvalidFormat(c *check.C) {
+	res, body, err := request.Get("/images/busybox/get?format=invalid")
+	c.Assert(err, checker.IsNil)
+	defer body.Close()
+	c.Assert(res.StatusCode, checker.Equals, http.StatusBadRequest)
+}
+
 func (s *DockerSuite) TestAPIImagesDelete(c *check.C) {
 	if testEnv.DaemonPlatform() != "windows" {
 		testRequires(c, Network)
@@ -118,6 +147,39 @@ func (s *DockerSuite) TestAPIImagesHistory(c *check.C) {
 	c.Assert(historydata[0].Tags[0], checker.Equals, "test-api-images-history:latest")
 }
 
+func (s *DockerSuite) TestAPIImagesDiff(c *check.C) {
+	testRequires(c, DaemonIsLinux)
+	base := "test-api-images-diff-base"
+	buildImageSuccessfully(c, base, build.WithDockerfile("FROM busybox\nRUN echo -n foo > /foo && echo -n bar > /bar"))
+	name := "test-api-images-diff"
+	buildImageSuccessfully(c, name, build.WithDockerfile("FROM "+base+"\nRUN echo -n hello > /foo && rm /bar && echo -n hi > /baz"))
+
+	status, body, err := request.SockRequest("GET", "/images/"+name+"/diff?base="+base, nil, daemonHost())
+	c.Assert(err, checker.IsNil)
+	c.Assert(status, checker.Equals, http.StatusOK)
+
+	var diff []image.DiffResponseItem
+	err = json.Unmarshal(body, &diff)
+	c.Assert(err, checker.IsNil, check.Commentf("Error on unmarshal"))
+
+	expected := map[string]image.DiffResponseItem{
+		"/foo": {Path: "/foo", Kind: 0, Size: 5},
+		"/bar": {Path: "/bar", Kind: 2, Size: 3},
+		"/baz": {Path: "/baz", Kind: 1, Size: 2},
+	}
+	for _, change := range diff {
+		if e, ok := expected[change.Path]; ok {
+			c.Assert(change, checker.DeepEquals, e)
+			delete(expected, change.Path)
+		}
+	}
+	c.Assert(expected, checker.HasLen, 0)
+
+	status, _, err = request.SockRequest("GET", "/images/"+name+"/diff", nil, daemonHost())
+	c.Assert(err, checker.IsNil)
+	c.Assert(status, checker.Equals, http.StatusBadRequest)
+}
+
 func (s *DockerSuite) TestAPIImagesImportBadSrc(c *check.C) {
 	testRequires(c, Network)
 
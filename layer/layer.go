@@ -15,6 +15,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/distribution"
+	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/opencontainers/go-digest"
 )
@@ -54,6 +55,10 @@ var (
 	// ErrNotSupported is used when the action is not supported
 	// on the current platform
 	ErrNotSupported = errors.New("not support on this platform")
+
+	// ErrLazyNotSupported is used when a lazy layer is registered
+	// but the storage driver cannot mount lazy layers.
+	ErrLazyNotSupported = errors.New("storage driver does not support lazy layers")
 )
 
 // ChainID is the content-addressable ID of a layer.
@@ -138,6 +143,11 @@ type RWLayer interface {
 	// changed in the mutable layer.
 	Size() (int64, error)
 
+	// DiskQuota returns the size limit of the writable layer set with
+	// the size storage option, and the space used against it. The limit
+	// is 0 if the layer has no size limit.
+	DiskQuota() (limit, used int64, err error)
+
 	// Changes returns the set of changes for the mutable layer
 	// from the base layer.
 	Changes() ([]archive.Change, error)
@@ -200,6 +210,23 @@ type DescribableStore interface {
 	RegisterWithDescriptor(io.Reader, ChainID, distribution.Descriptor) (Layer, error)
 }
 
+// LazyBlob is a layer blob in the stargz format, which is read remotely.
+type LazyBlob interface {
+	graphdriver.LazyBlob
+}
+
+// LazyStore represents a layer store capable of registering layers
+// whose content is fetched when it is first read.
+type LazyStore interface {
+	// RegisterLazy registers the layer of the blob, which diff ID must be
+	// known as its content is not read.
+	RegisterLazy(blob LazyBlob, parent ChainID, diffID DiffID, descriptor distribution.Descriptor) (Layer, error)
+	// Verified returns whether the content of the layer is verified
+	// against its diff ID and the diff IDs of its parents, which is not
+	// the case of lazy layers until all their content is fetched.
+	Verified(Layer) bool
+}
+
 // MetadataTransaction represents functions for setting layer metadata
 // with a single transaction.
 type MetadataTransaction interface {
@@ -273,6 +300,22 @@ func ReleaseAndLog(ls Store, l Layer) {
 	LogReleaseMetadata(metadata)
 }
 
+// GetVerified returns the layer of the chain ID from the layer store, as
+// Get does, but returns ErrLayerDoesNotExist if its content, or the content
+// of its parents, is not verified yet, so that the layer is not reused in
+// place of verified content.
+func GetVerified(ls Store, chainID ChainID) (Layer, error) {
+	l, err := ls.Get(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if lazyStore, ok := ls.(LazyStore); ok && !lazyStore.Verified(l) {
+		ReleaseAndLog(ls, l)
+		return nil, ErrLayerDoesNotExist
+	}
+	return l, nil
+}
+
 // LogReleaseMetadata logs a metadata array, uses this to
 // ensure consistent logging for release metadata
 func LogReleaseMetadata(metadatas []Metadata) {
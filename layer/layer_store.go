@@ -30,7 +30,10 @@ type layerStore struct {
 	driver graphdriver.Driver
 
 	layerMap map[ChainID]*roLayer
-	layerL   sync.Mutex
+	// aliases are the chain IDs lazy layers, and the layers above them,
+	// have in images, mapped to the chain IDs they are stored under
+	aliases map[ChainID]ChainID
+	layerL  sync.Mutex
 
 	mounts map[string]*mountedLayer
 	mountL sync.Mutex
@@ -85,6 +88,7 @@ func NewStoreFromGraphDriver(store MetadataStore, driver graphdriver.Driver) (St
 		store:       store,
 		driver:      driver,
 		layerMap:    map[ChainID]*roLayer{},
+		aliases:     map[ChainID]ChainID{},
 		mounts:      map[string]*mountedLayer{},
 		useTarSplit: !caps.ReproducesExactDiffs,
 	}
@@ -164,6 +168,11 @@ func (ls *layerStore) loadLayer(layer ChainID) (*roLayer, error) {
 	}
 
 	ls.layerMap[cl.chainID] = cl
+	if cl.imageChainID = imageChainID(cl); cl.imageChainID != "" {
+		if _, ok := ls.aliases[cl.imageChainID]; !ok {
+			ls.aliases[cl.imageChainID] = cl.chainID
+		}
+	}
 
 	return cl, nil
 }
@@ -253,6 +262,112 @@ func (ls *layerStore) Register(ts io.Reader, parent ChainID) (Layer, error) {
 }
 
 func (ls *layerStore) registerWithDescriptor(ts io.Reader, parent ChainID, descriptor distribution.Descriptor) (Layer, error) {
+	return ls.register(parent, descriptor, func(tx MetadataTransaction, layer *roLayer, pid string) error {
+		return ls.applyTar(tx, ts, pid, layer)
+	})
+}
+
+// RegisterLazy registers the layer of blob, which content is fetched when
+// it is first read. As its content is not verified against diffID until all
+// of it is fetched, the layer is stored under a chain ID of its own, and is
+// only found with the chain ID of diffID through an alias. A layer
+// registered before from the same blob is returned, and fetches its missing
+// content from blob.
+func (ls *layerStore) RegisterLazy(blob LazyBlob, parent ChainID, diffID DiffID, descriptor distribution.Descriptor) (Layer, error) {
+	lazyDriver, ok := ls.driver.(graphdriver.LazyDriver)
+	if !ok {
+		return nil, ErrLazyNotSupported
+	}
+
+	ls.layerL.Lock()
+	var parentKey ChainID
+	if parent != "" {
+		p, ok := ls.layerMap[ls.resolveWithoutLock(parent)]
+		if !ok {
+			ls.layerL.Unlock()
+			return nil, ErrLayerDoesNotExist
+		}
+		parentKey = p.chainID
+	}
+	var ref Layer
+	existing := ls.getWithoutLock(createLazyChainID(parentKey, diffID, blob.Digest()))
+	if existing != nil {
+		ref = existing.getReference()
+	}
+	ls.layerL.Unlock()
+	if existing != nil {
+		if lazyDriver.IsLazy(existing.cacheID) && lazyDriver.LazyBlobMissing(existing.cacheID) {
+			if err := lazyDriver.AttachLazy(existing.cacheID, blob); err != nil {
+				ReleaseAndLog(ls, ref)
+				return nil, err
+			}
+		}
+		return ref, nil
+	}
+
+	return ls.register(parent, descriptor, func(tx MetadataTransaction, layer *roLayer, pid string) error {
+		size, err := lazyDriver.ApplyLazy(layer.cacheID, pid, blob)
+		if err != nil {
+			return err
+		}
+		layer.size = size
+		layer.diffID = diffID
+		var parentKey ChainID
+		if layer.parent != nil {
+			parentKey = layer.parent.chainID
+		}
+		layer.chainID = createLazyChainID(parentKey, diffID, blob.Digest())
+
+		logrus.Debugf("Mounted lazy layer %s of %s to %s, size: %d", layer.diffID, blob.Digest(), layer.cacheID, size)
+
+		return nil
+	})
+}
+
+// Verified returns whether the content of l is verified against the diff
+// IDs of l and its parents. This is not the case of lazy layers whose
+// content is not fetched yet, and of the layers above them.
+func (ls *layerStore) Verified(l Layer) bool {
+	lazyDriver, ok := ls.driver.(graphdriver.LazyDriver)
+	if !ok {
+		return true
+	}
+	// only the layers stored under a chain ID of their own may be lazy,
+	// and so are their children
+	for rl := roLayerOf(l); rl != nil && rl.imageChainID != ""; rl = rl.parent {
+		if !lazyDriver.IsLazy(rl.cacheID) {
+			continue
+		}
+		if diffID, ok := lazyDriver.LazyDiffID(rl.cacheID); !ok || DiffID(diffID) != rl.diffID {
+			return false
+		}
+	}
+	return true
+}
+
+// createLazyChainID returns the chain ID a lazy layer of diffID on parent is
+// stored under, which differs from the chain ID of the layers of diffID
+// whose content is verified.
+func createLazyChainID(parent ChainID, diffID DiffID, blob digest.Digest) ChainID {
+	return ChainID(digest.FromBytes([]byte("lazy " + string(createChainIDFromParent(parent, diffID)) + " " + string(blob))))
+}
+
+// imageChainID returns the chain ID of the diff IDs of layer, if it is
+// stored under another chain ID, which is the case of lazy layers and of the
+// layers above them.
+func imageChainID(layer *roLayer) ChainID {
+	var parent ChainID
+	if layer.parent != nil {
+		parent = layer.parent.ChainID()
+	}
+	if chainID := createChainIDFromParent(parent, layer.diffID); chainID != layer.chainID {
+		return chainID
+	}
+	return ""
+}
+
+// register creates a layer on parent, which content is set by apply.
+func (ls *layerStore) register(parent ChainID, descriptor distribution.Descriptor, apply func(tx MetadataTransaction, layer *roLayer, pid string) error) (Layer, error) {
 	// err is used to hold the error which will always trigger
 	// cleanup of creates sources but may not be an error returned
 	// to the caller (already exists).
@@ -310,15 +425,19 @@ func (ls *layerStore) registerWithDescriptor(ts io.Reader, parent ChainID, descr
 		}
 	}()
 
-	if err = ls.applyTar(tx, ts, pid, layer); err != nil {
+	if err = apply(tx, layer, pid); err != nil {
 		return nil, err
 	}
 
-	if layer.parent == nil {
-		layer.chainID = ChainID(layer.diffID)
-	} else {
-		layer.chainID = createChainIDFromParent(layer.parent.chainID, layer.diffID)
+	// lazy layers set the chain ID they are stored under
+	if layer.chainID == "" {
+		if layer.parent == nil {
+			layer.chainID = ChainID(layer.diffID)
+		} else {
+			layer.chainID = createChainIDFromParent(layer.parent.chainID, layer.diffID)
+		}
 	}
+	layer.imageChainID = imageChainID(layer)
 
 	if err = storeLayer(tx, layer); err != nil {
 		return nil, err
@@ -333,15 +452,36 @@ func (ls *layerStore) registerWithDescriptor(ts io.Reader, parent ChainID, descr
 		return existingLayer.getReference(), nil
 	}
 
+	if layer.imageChainID != "" {
+		if key, ok := ls.aliases[layer.imageChainID]; ok && key != layer.chainID {
+			err = fmt.Errorf("layer %s is already registered lazily from another blob", layer.imageChainID)
+			return nil, err
+		}
+	}
+
 	if err = tx.Commit(layer.chainID); err != nil {
 		return nil, err
 	}
 
 	ls.layerMap[layer.chainID] = layer
+	if layer.imageChainID != "" {
+		ls.aliases[layer.imageChainID] = layer.chainID
+	}
 
 	return layer.getReference(), nil
 }
 
+// resolveWithoutLock returns the chain ID the layer of chainID is stored
+// under, which is another chain ID for the layers with an alias.
+func (ls *layerStore) resolveWithoutLock(chainID ChainID) ChainID {
+	if _, ok := ls.layerMap[chainID]; !ok {
+		if key, ok := ls.aliases[chainID]; ok {
+			return key
+		}
+	}
+	return chainID
+}
+
 func (ls *layerStore) getWithoutLock(layer ChainID) *roLayer {
 	l, ok := ls.layerMap[layer]
 	if !ok {
@@ -356,14 +496,14 @@ func (ls *layerStore) getWithoutLock(layer ChainID) *roLayer {
 func (ls *layerStore) get(l ChainID) *roLayer {
 	ls.layerL.Lock()
 	defer ls.layerL.Unlock()
-	return ls.getWithoutLock(l)
+	return ls.getWithoutLock(ls.resolveWithoutLock(l))
 }
 
 func (ls *layerStore) Get(l ChainID) (Layer, error) {
 	ls.layerL.Lock()
 	defer ls.layerL.Unlock()
 
-	layer := ls.getWithoutLock(l)
+	layer := ls.getWithoutLock(ls.resolveWithoutLock(l))
 	if layer == nil {
 		return nil, ErrLayerDoesNotExist
 	}
@@ -395,7 +535,7 @@ func (ls *layerStore) deleteLayer(layer *roLayer, metadata *Metadata) error {
 		return err
 	}
 	metadata.DiffID = layer.diffID
-	metadata.ChainID = layer.chainID
+	metadata.ChainID = layer.ChainID()
 	metadata.Size, err = layer.Size()
 	if err != nil {
 		return err
@@ -429,6 +569,9 @@ func (ls *layerStore) releaseLayer(l *roLayer) ([]Metadata, error) {
 		}
 
 		delete(ls.layerMap, l.chainID)
+		if l.imageChainID != "" && ls.aliases[l.imageChainID] == l.chainID {
+			delete(ls.aliases, l.imageChainID)
+		}
 		removed = append(removed, metadata)
 
 		if l.parent == nil {
@@ -443,8 +586,10 @@ func (ls *layerStore) releaseLayer(l *roLayer) ([]Metadata, error) {
 func (ls *layerStore) Release(l Layer) ([]Metadata, error) {
 	ls.layerL.Lock()
 	defer ls.layerL.Unlock()
-	layer, ok := ls.layerMap[l.ChainID()]
-	if !ok {
+	// the layer may be stored under another chain ID than the one it has
+	// in images
+	layer := roLayerOf(l)
+	if layer == nil || ls.layerMap[layer.chainID] != layer {
 		return []Metadata{}, nil
 	}
 	if !layer.hasReference(l) {
@@ -652,7 +797,10 @@ func (ls *layerStore) initMount(graphID, parent, mountLabel string, initFunc Mou
 }
 
 func (ls *layerStore) getTarStream(rl *roLayer) (io.ReadCloser, error) {
-	if !ls.useTarSplit {
+	// the diff of lazy layers is the stream of their blob, no tar-split
+	// metadata is recorded
+	lazyDriver, isLazyDriver := ls.driver.(graphdriver.LazyDriver)
+	if !ls.useTarSplit || (isLazyDriver && lazyDriver.IsLazy(rl.cacheID)) {
 		var parentCacheID string
 		if rl.parent != nil {
 			parentCacheID = rl.parent.cacheID
@@ -0,0 +1,227 @@
+package layer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/opencontainers/go-digest"
+)
+
+// lazyTestDriver applies the blob of lazy layers as a regular diff.
+type lazyTestDriver struct {
+	graphdriver.Driver
+	blobs    map[string]graphdriver.LazyBlob
+	attached map[string]bool
+	diffIDs  map[string]digest.Digest
+}
+
+func (d *lazyTestDriver) ApplyLazy(id, parent string, blob graphdriver.LazyBlob) (int64, error) {
+	rc, err := gzip.NewReader(io.NewSectionReader(blob, 0, blob.Size()))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	size, err := d.Driver.ApplyDiff(id, parent, rc)
+	if err != nil {
+		return 0, err
+	}
+	d.blobs[id] = blob
+	d.attached[id] = true
+	return size, nil
+}
+
+func (d *lazyTestDriver) IsLazy(id string) bool {
+	_, ok := d.blobs[id]
+	return ok
+}
+
+func (d *lazyTestDriver) LazyBlobMissing(id string) bool {
+	return !d.attached[id]
+}
+
+func (d *lazyTestDriver) AttachLazy(id string, blob graphdriver.LazyBlob) error {
+	d.attached[id] = true
+	return nil
+}
+
+func (d *lazyTestDriver) LazyDiffID(id string) (digest.Digest, bool) {
+	diffID, ok := d.diffIDs[id]
+	return diffID, ok
+}
+
+func (d *lazyTestDriver) Diff(id, parent string) (io.ReadCloser, error) {
+	blob, ok := d.blobs[id]
+	if !ok {
+		return d.Driver.Diff(id, parent)
+	}
+	return gzip.NewReader(io.NewSectionReader(blob, 0, blob.Size()))
+}
+
+type testLazyBlob struct {
+	*bytes.Reader
+	digest digest.Digest
+}
+
+func (b testLazyBlob) Digest() digest.Digest {
+	return b.digest
+}
+
+func (b testLazyBlob) TOCDigest() digest.Digest {
+	return digest.FromString("table of contents")
+}
+
+func newLazyTestStore(t *testing.T) (Store, *lazyTestDriver, string, func()) {
+	td, err := ioutil.TempDir("", "layerstore-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, graphcleanup := newTestGraphDriver(t)
+	driver := &lazyTestDriver{
+		Driver:   graph,
+		blobs:    make(map[string]graphdriver.LazyBlob),
+		attached: make(map[string]bool),
+		diffIDs:  make(map[string]digest.Digest),
+	}
+	fms, err := NewFSMetadataStore(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := NewStoreFromGraphDriver(fms, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ls, driver, td, func() {
+		graphcleanup()
+		os.RemoveAll(td)
+	}
+}
+
+func newTestLazyBlob(t *testing.T, tarData []byte) testLazyBlob {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(tarData); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return testLazyBlob{bytes.NewReader(buf.Bytes()), digest.FromBytes(buf.Bytes())}
+}
+
+func TestRegisterLazy(t *testing.T) {
+	ls, driver, td, cleanup := newLazyTestStore(t)
+	defer cleanup()
+
+	tarData, err := tarFromFiles(newTestFile("/etc/hostname", []byte("lazy"), 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := newTestLazyBlob(t, tarData)
+	diffID := DiffID(digest.FromBytes(tarData))
+
+	layer, err := ls.(LazyStore).RegisterLazy(blob, "", diffID, distribution.Descriptor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Release(layer)
+	if layer.DiffID() != diffID {
+		t.Fatalf("Expected diff ID %s, got %s", diffID, layer.DiffID())
+	}
+	if layer.ChainID() != ChainID(diffID) {
+		t.Fatalf("Expected chain ID %s, got %s", diffID, layer.ChainID())
+	}
+	assertLayerDiff(t, tarData, layer)
+
+	// the layers above a lazy layer are not verified either
+	childTar, err := tarFromFiles(newTestFile("/etc/hosts", []byte("127.0.0.1 localhost"), 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := ls.Register(bytes.NewReader(childTar), layer.ChainID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Release(child)
+	childChainID := createChainIDFromParent(ChainID(diffID), DiffID(digest.FromBytes(childTar)))
+	if child.ChainID() != childChainID {
+		t.Fatalf("Expected chain ID %s, got %s", childChainID, child.ChainID())
+	}
+	if ls.(LazyStore).Verified(layer) || ls.(LazyStore).Verified(child) {
+		t.Fatal("Expected the lazy layer and its child not to be verified")
+	}
+	if _, err := GetVerified(ls, ChainID(diffID)); err != ErrLayerDoesNotExist {
+		t.Fatalf("Expected %v for a layer which is not verified, got %v", ErrLayerDoesNotExist, err)
+	}
+
+	// the layer is registered again from the same blob, and fetches its
+	// missing content from it
+	driver.attached[cacheID(layer)] = false
+	again, err := ls.(LazyStore).RegisterLazy(blob, "", diffID, distribution.Descriptor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Release(again)
+	if cacheID(again) != cacheID(layer) || !driver.attached[cacheID(layer)] {
+		t.Fatal("Expected the blob to be attached to the lazy layer")
+	}
+
+	// another blob cannot claim the same diff ID
+	other := newTestLazyBlob(t, append(tarData, 0))
+	if _, err := ls.(LazyStore).RegisterLazy(other, "", diffID, distribution.Descriptor{}); err == nil {
+		t.Fatal("Expected the lazy layer of another blob of the same diff ID to be refused")
+	}
+
+	// the content of the lazy layer is not reused for the same diff ID
+	verified, err := ls.Register(bytes.NewReader(tarData), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ls.Release(verified)
+	if cacheID(verified) == cacheID(layer) || verified.ChainID() != ChainID(diffID) {
+		t.Fatal("Expected the lazy layer not to be reused")
+	}
+	if l, err := GetVerified(ls, ChainID(diffID)); err != nil || cacheID(l) != cacheID(verified) {
+		t.Fatalf("Expected the verified layer, got %v", err)
+	} else {
+		ls.Release(l)
+	}
+
+	driver.diffIDs[cacheID(layer)] = digest.Digest(diffID)
+	if !ls.(LazyStore).Verified(layer) || !ls.(LazyStore).Verified(child) {
+		t.Fatal("Expected the lazy layer and its child to be verified")
+	}
+
+	// the layers are found with their chain IDs once the store is loaded
+	fms, err := NewFSMetadataStore(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls2, err := NewStoreFromGraphDriver(fms, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ls2.Get(childChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cacheID(l) != cacheID(child) || l.Parent().ChainID() != ChainID(diffID) {
+		t.Fatal("Expected the child of the lazy layer to be loaded")
+	}
+	ls2.Release(l)
+}
+
+func TestRegisterLazyNotSupported(t *testing.T) {
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+
+	blob := testLazyBlob{bytes.NewReader(nil), digest.FromBytes(nil)}
+	if _, err := ls.(LazyStore).RegisterLazy(blob, "", DiffID(digest.FromBytes(nil)), distribution.Descriptor{}); err != ErrLazyNotSupported {
+		t.Fatalf("Expected %v, got %v", ErrLazyNotSupported, err)
+	}
+}
@@ -21,7 +21,7 @@ func (ls *layerStore) CreateRWLayerByGraphID(name string, graphID string, parent
 	defer ls.mountL.Unlock()
 	m, ok := ls.mounts[name]
 	if ok {
-		if m.parent.chainID != parent {
+		if m.parent.ChainID() != parent {
 			return errors.New("name conflict, mismatched parent")
 		}
 		if m.mountID != graphID {
@@ -3,6 +3,7 @@ package layer
 import (
 	"io"
 
+	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
 )
 
@@ -49,6 +50,13 @@ func (ml *mountedLayer) Size() (int64, error) {
 	return ml.layerStore.driver.DiffSize(ml.mountID, ml.cacheParent())
 }
 
+func (ml *mountedLayer) DiskQuota() (int64, int64, error) {
+	if driver, ok := ml.layerStore.driver.(graphdriver.DiskQuotaDriver); ok {
+		return driver.DiskQuota(ml.mountID)
+	}
+	return 0, 0, nil
+}
+
 func (ml *mountedLayer) Changes() ([]archive.Change, error) {
 	return ml.layerStore.driver.Changes(ml.mountID, ml.cacheParent())
 }
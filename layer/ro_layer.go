@@ -19,6 +19,10 @@ type roLayer struct {
 
 	referenceCount int
 	references     map[Layer]struct{}
+
+	// imageChainID is the chain ID of the diff IDs of the layer, when it is
+	// stored under another chain ID
+	imageChainID ChainID
 }
 
 // TarStream for roLayer guarantees that the data that is produced is the exact
@@ -42,7 +46,7 @@ func (rl *roLayer) TarStream() (io.ReadCloser, error) {
 func (rl *roLayer) TarStreamFrom(parent ChainID) (io.ReadCloser, error) {
 	var parentCacheID string
 	for pl := rl.parent; pl != nil; pl = pl.parent {
-		if pl.chainID == parent {
+		if pl.ChainID() == parent {
 			parentCacheID = pl.cacheID
 			break
 		}
@@ -55,6 +59,9 @@ func (rl *roLayer) TarStreamFrom(parent ChainID) (io.ReadCloser, error) {
 }
 
 func (rl *roLayer) ChainID() ChainID {
+	if rl.imageChainID != "" {
+		return rl.imageChainID
+	}
 	return rl.chainID
 }
 
@@ -92,6 +99,17 @@ type referencedCacheLayer struct {
 	*roLayer
 }
 
+// roLayerOf returns the layer of a reference returned by the layer store.
+func roLayerOf(l Layer) *roLayer {
+	switch l := l.(type) {
+	case *referencedCacheLayer:
+		return l.roLayer
+	case *roLayer:
+		return l
+	}
+	return nil
+}
+
 func (rl *roLayer) getReference() Layer {
 	ref := &referencedCacheLayer{
 		roLayer: rl,
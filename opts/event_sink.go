@@ -0,0 +1,104 @@
+package opts
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// EventSinkOpt defines a list of event sinks
+type EventSinkOpt struct {
+	name   string
+	values *[]types.EventSink
+}
+
+// NewNamedEventSinkOpt creates a new EventSinkOpt
+func NewNamedEventSinkOpt(name string, ref *[]types.EventSink) *EventSinkOpt {
+	if ref == nil {
+		ref = &[]types.EventSink{}
+	}
+	return &EventSinkOpt{name: name, values: ref}
+}
+
+// Name returns the name of the EventSinkOpt in the configuration.
+func (o *EventSinkOpt) Name() string {
+	return o.name
+}
+
+// Set parses a sink given as a list of comma-separated key=value pairs, like
+// "type=webhook,address=https://example.com/events,filter=type=container",
+// and adds it to the list.
+func (o *EventSinkOpt) Set(val string) error {
+	fields, err := csv.NewReader(strings.NewReader(val)).Read()
+	if err != nil {
+		return fmt.Errorf("invalid event sink argument: %s", val)
+	}
+
+	sink := types.EventSink{}
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid field '%s' must be a key=value pair", field)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		switch key {
+		case "type":
+			sink.Type = value
+		case "address":
+			sink.Address = value
+		case "filter":
+			filter := strings.SplitN(value, "=", 2)
+			if len(filter) != 2 {
+				return fmt.Errorf("invalid filter '%s' must be a key=value pair", value)
+			}
+			if sink.Filters == nil {
+				sink.Filters = make(map[string][]string)
+			}
+			sink.Filters[filter[0]] = append(sink.Filters[filter[0]], filter[1])
+		case "batch-size":
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid batch-size '%s': %v", value, err)
+			}
+			sink.BatchSize = size
+		case "batch-timeout":
+			sink.BatchTimeout = value
+		case "max-retries":
+			retries, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid max-retries '%s': %v", value, err)
+			}
+			sink.MaxRetries = &retries
+		default:
+			return fmt.Errorf("unexpected key '%s' in '%s'", key, field)
+		}
+	}
+	if sink.Type == "" || sink.Address == "" {
+		return fmt.Errorf("invalid event sink argument: %s, type and address are required", val)
+	}
+
+	*o.values = append(*o.values, sink)
+	return nil
+}
+
+// String returns the addresses of the event sinks as a string.
+func (o *EventSinkOpt) String() string {
+	var out []string
+	for _, sink := range *o.values {
+		out = append(out, sink.Type+"="+sink.Address)
+	}
+	return fmt.Sprintf("%v", out)
+}
+
+// Value returns the list of event sinks
+func (o *EventSinkOpt) Value() []types.EventSink {
+	return *o.values
+}
+
+// Type returns the type of the option
+func (o *EventSinkOpt) Type() string {
+	return "event-sink"
+}
@@ -0,0 +1,45 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSinkOptSet(t *testing.T) {
+	var sinks []types.EventSink
+	o := NewNamedEventSinkOpt("event-sinks", &sinks)
+	assert.NoError(t, o.Set(`type=webhook,"address=https://example.com/events?a=1,b=2",filter=type=container,filter=event=die,batch-size=10,max-retries=0`))
+	assert.NoError(t, o.Set("type=unix,address=/run/docker-events.sock"))
+
+	retries := 0
+	expected := []types.EventSink{
+		{
+			Type:       "webhook",
+			Address:    "https://example.com/events?a=1,b=2",
+			Filters:    map[string][]string{"type": {"container"}, "event": {"die"}},
+			BatchSize:  10,
+			MaxRetries: &retries,
+		},
+		{Type: "unix", Address: "/run/docker-events.sock"},
+	}
+	assert.Equal(t, expected, sinks)
+	assert.Equal(t, "event-sinks", o.Name())
+	assert.Equal(t, "[webhook=https://example.com/events?a=1,b=2 unix=/run/docker-events.sock]", o.String())
+}
+
+func TestEventSinkOptSetInvalid(t *testing.T) {
+	for _, val := range []string{
+		"type=webhook",
+		"address=/run/docker-events.sock",
+		"type=webhook,address=http://example.com,filter=type",
+		"type=webhook,address=http://example.com,batch-size=many",
+		"type=webhook,address=http://example.com,foo=bar",
+		"type=webhook,address",
+	} {
+		o := NewNamedEventSinkOpt("event-sinks", nil)
+		assert.Error(t, o.Set(val), val)
+		assert.Len(t, o.Value(), 0)
+	}
+}
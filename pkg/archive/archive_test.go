@@ -145,6 +145,46 @@ func TestDecompressStreamXz(t *testing.T) {
 	testDecompressStream(t, "xz", "xz -f")
 }
 
+func TestDecompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	testDecompressStream(t, "zst", "zstd -f -q")
+}
+
+func TestCompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	var compressed bytes.Buffer
+	w, err := CompressStream(&compressed, Zstd)
+	if err != nil {
+		t.Fatalf("Failed to create the zstd stream: %v", err)
+	}
+	if _, err := w.Write([]byte("zstd content")); err != nil {
+		t.Fatalf("Failed to write to the zstd stream: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close the zstd stream: %v", err)
+	}
+	if compression := DetectCompression(compressed.Bytes()); compression != Zstd {
+		t.Fatalf("Expected zstd compression, got %s", (&compression).Extension())
+	}
+
+	r, err := DecompressStream(&compressed)
+	if err != nil {
+		t.Fatalf("Failed to decompress the zstd stream: %v", err)
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read the decompressed stream: %v", err)
+	}
+	if string(content) != "zstd content" {
+		t.Fatalf("Unexpected decompressed content: %q", content)
+	}
+}
+
 func TestCompressStreamXzUnsupported(t *testing.T) {
 	dest, err := os.Create(tmp + "dest")
 	if err != nil {
@@ -221,6 +261,14 @@ func TestExtensionXz(t *testing.T) {
 	}
 }
 
+func TestExtensionZstd(t *testing.T) {
+	compression := Zstd
+	output := compression.Extension()
+	if output != "tar.zst" {
+		t.Fatalf("The extension of a zstd archive should be 'tar.zst'")
+	}
+}
+
 func TestCmdStreamLargeStderr(t *testing.T) {
 	cmd := exec.Command("sh", "-c", "dd if=/dev/zero bs=1k count=1000 of=/dev/stderr; echo hello")
 	out, _, err := cmdStream(cmd, nil)
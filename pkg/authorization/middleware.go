@@ -14,6 +14,8 @@ import (
 type Middleware struct {
 	mu      sync.Mutex
 	plugins []Plugin
+	policy  *Policy
+	auditor func(Decision)
 }
 
 // NewMiddleware creates a new Middleware
@@ -46,14 +48,30 @@ func (m *Middleware) SetPlugins(names []string) {
 	m.mu.Unlock()
 }
 
+// SetPolicy sets the policy evaluated before the authorization plugins are
+// called. A nil policy allows all requests.
+func (m *Middleware) SetPolicy(policy *Policy) {
+	m.mu.Lock()
+	m.policy = policy
+	m.mu.Unlock()
+}
+
+// SetAuditor sets the function called with every decision of the policy.
+func (m *Middleware) SetAuditor(auditor func(Decision)) {
+	m.mu.Lock()
+	m.auditor = auditor
+	m.mu.Unlock()
+}
+
+func (m *Middleware) getPolicy() (*Policy, func(Decision)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy, m.auditor
+}
+
 // WrapHandler returns a new handler function wrapping the previous one in the request chain.
 func (m *Middleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-		plugins := m.GetAuthzPlugins()
-		if len(plugins) == 0 {
-			return handler(ctx, w, r, vars)
-		}
-
 		user := ""
 		userAuthNMethod := ""
 
@@ -66,6 +84,22 @@ func (m *Middleware) WrapHandler(handler func(ctx context.Context, w http.Respon
 			userAuthNMethod = "TLS"
 		}
 
+		if policy, auditor := m.getPolicy(); policy != nil {
+			d := policy.Evaluate(user, r.Method, r.URL.Path)
+			if auditor != nil && policy.Audited(d) {
+				auditor(d)
+			}
+			if !d.Allow {
+				logrus.Debugf("Authorization policy denied %s %s for user %q", r.Method, r.RequestURI, user)
+				return newPolicyError(d)
+			}
+		}
+
+		plugins := m.GetAuthzPlugins()
+		if len(plugins) == 0 {
+			return handler(ctx, w, r, vars)
+		}
+
 		authCtx := NewCtx(plugins, user, userAuthNMethod, r.Method, r.RequestURI)
 
 		if err := authCtx.AuthZRequest(w, r); err != nil {
@@ -0,0 +1,205 @@
+package authorization
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// PolicyAllow is the action of a policy rule allowing requests
+	PolicyAllow = "allow"
+	// PolicyDeny is the action of a policy rule denying requests
+	PolicyDeny = "deny"
+
+	// AuditAll audits all the decisions of a policy
+	AuditAll = "all"
+	// AuditMutating audits the denied requests, and the allowed requests
+	// which may change the state of the daemon, that is all the requests
+	// but the GET, HEAD and OPTIONS ones
+	AuditMutating = "mutating"
+	// AuditDeny audits the denied requests
+	AuditDeny = "deny"
+	// AuditNone audits no decisions
+	AuditNone = "none"
+
+	policyWildcard = "*"
+)
+
+// versionPrefix matches the API version prefix of a request path
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// PolicyRule maps the requests of a set of users to a set of routes to an
+// action. Empty lists, or lists holding "*", match any value.
+type PolicyRule struct {
+	// Users holds the users the rule applies to, as given by the common name
+	// of their TLS client certificate
+	Users []string `json:"users,omitempty"`
+	// Methods holds the HTTP methods the rule applies to
+	Methods []string `json:"methods,omitempty"`
+	// Routes holds the patterns of the request paths the rule applies to,
+	// without the API version prefix. Patterns use the syntax of path.Match,
+	// and a pattern ending with "/**" also matches all the paths below it.
+	Routes []string `json:"routes,omitempty"`
+	// Action is either "allow" or "deny"
+	Action string `json:"action"`
+}
+
+// Policy is a list of rules evaluated against every API request. The first
+// matching rule decides whether the request is allowed, and the default
+// action applies to requests matching no rule.
+type Policy struct {
+	// Default is the action for requests matching no rule. It defaults to
+	// "deny".
+	Default string       `json:"default,omitempty"`
+	Rules   []PolicyRule `json:"rules"`
+	// Audit is the set of decisions which are audited, one of "all",
+	// "mutating", "deny" or "none". It defaults to "mutating".
+	Audit string `json:"audit,omitempty"`
+}
+
+// Decision is the outcome of evaluating a request against a policy.
+type Decision struct {
+	User   string
+	Method string
+	Path   string
+	Allow  bool
+	// Rule is the index of the matching rule, or -1 if the default action
+	// applied
+	Rule int
+}
+
+// LoadPolicy reads and validates the policy file at path.
+func LoadPolicy(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening authorization policy: %v", err)
+	}
+	defer f.Close()
+
+	var p Policy
+	if err := json.NewDecoder(f).Decode(&p); err != nil {
+		return nil, fmt.Errorf("error parsing authorization policy %s: %v", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid authorization policy %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks the actions and route patterns of the policy.
+func (p *Policy) Validate() error {
+	if p.Default == "" {
+		p.Default = PolicyDeny
+	}
+	if !validAction(p.Default) {
+		return fmt.Errorf("invalid default action %q", p.Default)
+	}
+	switch p.Audit {
+	case "":
+		p.Audit = AuditMutating
+	case AuditAll, AuditMutating, AuditDeny, AuditNone:
+	default:
+		return fmt.Errorf("invalid audit %q", p.Audit)
+	}
+	for i, rule := range p.Rules {
+		if !validAction(rule.Action) {
+			return fmt.Errorf("invalid action %q in rule %d", rule.Action, i)
+		}
+		for _, route := range rule.Routes {
+			if _, err := path.Match(route, ""); err != nil {
+				return fmt.Errorf("invalid route %q in rule %d: %v", route, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validAction(action string) bool {
+	return action == PolicyAllow || action == PolicyDeny
+}
+
+// Evaluate returns the decision of the policy for a request of user with
+// the given method to the given URL path.
+func (p *Policy) Evaluate(user, method, urlPath string) Decision {
+	urlPath = path.Clean("/" + versionPrefix.ReplaceAllString(urlPath, ""))
+	d := Decision{
+		User:   user,
+		Method: method,
+		Path:   urlPath,
+		Allow:  p.Default == PolicyAllow,
+		Rule:   -1,
+	}
+	for i, rule := range p.Rules {
+		if rule.matches(user, method, urlPath) {
+			d.Allow = rule.Action == PolicyAllow
+			d.Rule = i
+			break
+		}
+	}
+	return d
+}
+
+// Audited returns whether the decision d of the policy is audited.
+func (p *Policy) Audited(d Decision) bool {
+	switch p.Audit {
+	case AuditAll:
+		return true
+	case AuditDeny:
+		return !d.Allow
+	case AuditNone:
+		return false
+	}
+	// the requests which only read the state of the daemon, such as the
+	// pings and the events stream, are the most frequent
+	switch strings.ToUpper(d.Method) {
+	case "GET", "HEAD", "OPTIONS":
+		return !d.Allow
+	}
+	return true
+}
+
+func (r *PolicyRule) matches(user, method, urlPath string) bool {
+	return matchAny(r.Users, func(u string) bool { return u == user }) &&
+		matchAny(r.Methods, func(m string) bool { return strings.EqualFold(m, method) }) &&
+		matchAny(r.Routes, func(route string) bool { return matchRoute(route, urlPath) })
+}
+
+// matchAny returns whether one of the values matches, treating an empty
+// list or the wildcard as matching anything.
+func matchAny(values []string, match func(string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == policyWildcard || match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRoute(pattern, urlPath string) bool {
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "/**")
+		if ok, _ := path.Match(prefix, urlPath); ok {
+			return true
+		}
+		// match the parents of urlPath against the prefix
+		for dir := path.Dir(urlPath); dir != "/"; dir = path.Dir(dir) {
+			if ok, _ := path.Match(prefix, dir); ok {
+				return true
+			}
+		}
+		return false
+	}
+	ok, _ := path.Match(pattern, urlPath)
+	return ok
+}
+
+func newPolicyError(d Decision) authorizationError {
+	return authorizationError{error: fmt.Errorf("authorization denied by policy: %s %s is not allowed for user %q", d.Method, d.Path, d.User)}
+}
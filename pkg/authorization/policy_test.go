@@ -0,0 +1,115 @@
+package authorization
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	p := &Policy{
+		Rules: []PolicyRule{
+			{Users: []string{"alice"}, Action: PolicyAllow},
+			{Users: []string{"bob"}, Methods: []string{"POST"}, Routes: []string{"/containers/*/exec"}, Action: PolicyDeny},
+			{Users: []string{"bob"}, Routes: []string{"/containers/**"}, Action: PolicyAllow},
+			{Methods: []string{"get"}, Routes: []string{"/_ping", "/version"}, Action: PolicyAllow},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user, method, path string
+		allow              bool
+		rule               int
+	}{
+		{user: "alice", method: "DELETE", path: "/v1.30/images/busybox", allow: true, rule: 0},
+		{user: "bob", method: "POST", path: "/v1.30/containers/foo/exec", allow: false, rule: 1},
+		{user: "bob", method: "POST", path: "/containers/foo/start", allow: true, rule: 2},
+		{user: "bob", method: "GET", path: "/v1.30/containers/json", allow: true, rule: 2},
+		{user: "bob", method: "GET", path: "/containers", allow: true, rule: 2},
+		{user: "bob", method: "GET", path: "/volumes", allow: false, rule: -1},
+		{user: "", method: "GET", path: "/_ping", allow: true, rule: 3},
+		{user: "", method: "HEAD", path: "/_ping", allow: false, rule: -1},
+	} {
+		d := p.Evaluate(tc.user, tc.method, tc.path)
+		if d.Allow != tc.allow || d.Rule != tc.rule {
+			t.Fatalf("%s %s for %q: expected allow=%t rule=%d, got allow=%t rule=%d", tc.method, tc.path, tc.user, tc.allow, tc.rule, d.Allow, d.Rule)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authz-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "policy.json")
+	for _, tc := range []struct {
+		content string
+		valid   bool
+	}{
+		{content: `{"default": "allow", "rules": [{"users": ["bob"], "methods": ["DELETE"], "action": "deny"}]}`, valid: true},
+		{content: `{"rules": []}`, valid: true},
+		{content: `{"default": "maybe", "rules": []}`},
+		{content: `{"rules": [{"users": ["bob"], "action": "block"}]}`},
+		{content: `{"rules": [{"routes": ["/containers/["], "action": "deny"}]}`},
+		{content: `{"rules": [], "audit": "deny"}`, valid: true},
+		{content: `{"rules": [], "audit": "some"}`},
+		{content: `{"rules": `},
+	} {
+		if err := ioutil.WriteFile(file, []byte(tc.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		p, err := LoadPolicy(file)
+		if tc.valid && err != nil {
+			t.Fatalf("unexpected error for %s: %v", tc.content, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("expected an error for %s", tc.content)
+		}
+		if tc.valid && (p.Default == "" || p.Audit == "") {
+			t.Fatalf("expected a default action and audit for %s", tc.content)
+		}
+	}
+}
+
+func TestPolicyAudited(t *testing.T) {
+	allowed := []Decision{
+		{Method: "GET", Path: "/_ping", Allow: true},
+		{Method: "POST", Path: "/containers/create", Allow: true},
+	}
+	denied := []Decision{
+		{Method: "GET", Path: "/events", Allow: false},
+		{Method: "DELETE", Path: "/images/busybox", Allow: false},
+	}
+	for _, tc := range []struct {
+		audit   string
+		allowed []bool
+		denied  bool
+	}{
+		{audit: "", allowed: []bool{false, true}, denied: true},
+		{audit: AuditAll, allowed: []bool{true, true}, denied: true},
+		{audit: AuditDeny, allowed: []bool{false, false}, denied: true},
+		{audit: AuditNone, allowed: []bool{false, false}, denied: false},
+	} {
+		p := &Policy{Audit: tc.audit}
+		if err := p.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		for i, d := range allowed {
+			if p.Audited(d) != tc.allowed[i] {
+				t.Fatalf("audit %q: expected audited=%t for %s %s", tc.audit, tc.allowed[i], d.Method, d.Path)
+			}
+		}
+		for _, d := range denied {
+			if p.Audited(d) != tc.denied {
+				t.Fatalf("audit %q: expected audited=%t for the denied %s %s", tc.audit, tc.denied, d.Method, d.Path)
+			}
+		}
+	}
+}
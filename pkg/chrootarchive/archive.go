@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/idtools"
@@ -28,6 +29,23 @@ func UntarUncompressed(tarArchive io.Reader, dest string, options *archive.TarOp
 	return untarHandler(tarArchive, dest, options, false)
 }
 
+// Tar archives srcPath while chrooted to root, so that the symlinks found
+// while walking srcPath, including the ones swapped in while it is archived,
+// cannot point out of root. srcPath must be inside root.
+func Tar(srcPath string, options *archive.TarOptions, root string) (io.ReadCloser, error) {
+	if options == nil {
+		options = &archive.TarOptions{}
+	}
+	relSrc, err := filepath.Rel(root, srcPath)
+	if err != nil {
+		return nil, err
+	}
+	if relSrc == ".." || strings.HasPrefix(relSrc, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is not inside %s", srcPath, root)
+	}
+	return invokePack(relSrc, options, root)
+}
+
 // Handler for teasing out the automatic decompression
 func untarHandler(tarArchive io.Reader, dest string, options *archive.TarOptions, decompress bool) error {
 
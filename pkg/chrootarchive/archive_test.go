@@ -51,6 +51,56 @@ func TestChrootTarUntar(t *testing.T) {
 	}
 }
 
+func TestChrootTar(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "docker-TestChrootTar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	src := filepath.Join(tmpdir, "src")
+	if err := system.MkdirAll(filepath.Join(src, "dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "dir", "toto"), []byte("hello toto"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the symlink is resolved in the chroot of src, where tmpdir/outside
+	// doesn't exist
+	outside := filepath.Join(tmpdir, "outside")
+	if err := system.MkdirAll(outside, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outside, "lolo"), []byte("hello lolo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := Tar(src, &archive.TarOptions{IncludeFiles: []string{"link/", "dir"}}, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(tmpdir, "dest")
+	if err := system.MkdirAll(dest, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Untar(stream, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if err := compareFiles(filepath.Join(src, "dir", "toto"), filepath.Join(dest, "dir", "toto")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "link", "lolo")); !os.IsNotExist(err) {
+		t.Fatalf("expected the symlink to be resolved in the chroot, got %v", err)
+	}
+
+	if _, err := Tar(tmpdir, nil, src); err == nil {
+		t.Fatal("expected an error archiving a path out of the root")
+	}
+}
+
 // gh#10426: Verify the fix for having a huge excludes list (like on `docker load` with large # of
 // local images)
 func TestChrootUntarWithHugeExcludesList(t *testing.T) {
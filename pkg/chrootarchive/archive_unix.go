@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 
 	"github.com/docker/docker/pkg/archive"
@@ -84,3 +85,73 @@ func invokeUnpack(decompressedArchive io.Reader, dest string, options *archive.T
 	}
 	return nil
 }
+
+// tar is the entry-point for docker-tar on re-exec. It archives its first
+// argument, relative to its second one, which it chroots to.
+func tar() {
+	runtime.LockOSThread()
+	flag.Parse()
+
+	var options *archive.TarOptions
+
+	//read the options from stdin, before the archive is written to stdout
+	if err := json.NewDecoder(os.Stdin).Decode(&options); err != nil {
+		fatal(err)
+	}
+
+	// the archive is walked by other goroutines, and so threads, so the
+	// root of the process is changed, instead of pivoting the root of the
+	// mount namespace of this thread
+	if err := realChroot(flag.Arg(1)); err != nil {
+		fatal(err)
+	}
+
+	rdr, err := archive.TarWithOptions(filepath.Join("/", flag.Arg(0)), options)
+	if err != nil {
+		fatal(err)
+	}
+	if _, err := io.Copy(os.Stdout, rdr); err != nil {
+		fatal(err)
+	}
+
+	os.Exit(0)
+}
+
+func invokePack(relSrc string, options *archive.TarOptions, root string) (io.ReadCloser, error) {
+	cmd := reexec.Command("docker-tar", relSrc, root)
+	errBuff := bytes.NewBuffer(nil)
+	cmd.Stderr = errBuff
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Tar pipe failure: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Tar pipe failure: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Tar error on re-exec cmd: %v", err)
+	}
+	//write the options for the tar exec to read
+	err = json.NewEncoder(stdin).Encode(options)
+	stdin.Close()
+	if err != nil {
+		stdout.Close()
+		cmd.Wait()
+		return nil, fmt.Errorf("Tar json encode to pipe failed: %v", err)
+	}
+
+	tarR, tarW := io.Pipe()
+	go func() {
+		_, err := io.Copy(tarW, stdout)
+		// stop docker-tar if the archive is not fully read
+		stdout.Close()
+		if waitErr := cmd.Wait(); waitErr != nil {
+			err = fmt.Errorf("Error processing tar file(%v): %s", waitErr, errBuff)
+		}
+		tarW.CloseWithError(err)
+	}()
+	return tarR, nil
+}
@@ -2,6 +2,7 @@ package chrootarchive
 
 import (
 	"io"
+	"path/filepath"
 
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/longpath"
@@ -20,3 +21,9 @@ func invokeUnpack(decompressedArchive io.ReadCloser,
 	// do the unpack. We call inline instead within the daemon process.
 	return archive.Unpack(decompressedArchive, longpath.AddPrefix(dest), options)
 }
+
+func invokePack(relSrc string, options *archive.TarOptions, root string) (io.ReadCloser, error) {
+	// Windows does not support chroot, so the path is archived inline
+	// within the daemon process, as it is unpacked
+	return archive.TarWithOptions(longpath.AddPrefix(filepath.Join(root, relSrc)), options)
+}
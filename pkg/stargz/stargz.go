@@ -0,0 +1,424 @@
+// Package stargz reads and writes layer blobs in the seekable tar.gz format
+// (stargz), so that the files of a layer can be read without fetching and
+// decompressing the whole blob.
+//
+// A stargz blob is a regular gzip compressed tar stream, made of several
+// gzip members: the content of every regular file, or every chunk of the
+// content of large files, starts a new member. The last entry of the tar
+// stream is a table of contents, the "stargz.index.json" file, listing the
+// entries of the layer and the offsets in the blob of the members holding
+// their content. The blob ends with a gzip member of empty content whose
+// header records the offset of the table of contents.
+//
+// As the uncompressed stream is the tar stream of the layer, plus the table
+// of contents, stargz blobs are pulled like any other gzip compressed layer.
+package stargz
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	// TOCTarName is the name of the table of contents in the tar stream.
+	TOCTarName = "stargz.index.json"
+
+	// FooterSize is the size of the footer of a stargz blob.
+	FooterSize = 47
+
+	// PrefetchLandmark is the name of the file marking the end of the files
+	// to fetch before the layer is used. The files which are likely to be
+	// read, placed before it, are fetched eagerly.
+	PrefetchLandmark = ".prefetch.landmark"
+
+	// NoPrefetchLandmark is the name of the file marking that no files of
+	// the layer need to be fetched eagerly.
+	NoPrefetchLandmark = ".no.prefetch.landmark"
+
+	// TOCDigestAnnotation is the annotation of the layer descriptors of
+	// manifests recording the digest of the table of contents of a stargz
+	// blob, which the chunks of its files are verified against.
+	TOCDigestAnnotation = "containerd.io/snapshot/stargz/toc.digest"
+)
+
+// ErrNotStargz is returned when opening a blob which is not in the stargz
+// format.
+var ErrNotStargz = errors.New("not a stargz blob")
+
+// TOC is the table of contents of a stargz blob.
+type TOC struct {
+	Version int         `json:"version"`
+	Entries []*TOCEntry `json:"entries"`
+}
+
+// TOCEntry is an entry of the table of contents. There is an entry for every
+// tar entry, and an additional "chunk" entry for every chunk but the first
+// of the content of large regular files.
+type TOCEntry struct {
+	// Name is the cleaned name of the tar entry, without a leading "./"
+	// or "/".
+	Name string `json:"name"`
+
+	// Type is one of "dir", "reg", "symlink", "hardlink", "char",
+	// "block", "fifo" or "chunk".
+	Type string `json:"type"`
+
+	// Size is the size of the content of regular files.
+	Size int64 `json:"size,omitempty"`
+
+	// ModTime3339 is the modification time of the entry, in the RFC 3339
+	// format.
+	ModTime3339 string `json:"modtime,omitempty"`
+
+	// LinkName is the target of symlinks and hardlinks.
+	LinkName string `json:"linkName,omitempty"`
+
+	// Mode is the permission and mode bits of the tar header.
+	Mode int64 `json:"mode,omitempty"`
+
+	UID   int    `json:"uid,omitempty"`
+	GID   int    `json:"gid,omitempty"`
+	Uname string `json:"userName,omitempty"`
+	Gname string `json:"groupName,omitempty"`
+
+	// Offset is the offset in the blob of the gzip member holding the
+	// content of a regular file or a chunk, it is 0 for other entries.
+	Offset int64 `json:"offset,omitempty"`
+
+	DevMajor int `json:"devMajor,omitempty"`
+	DevMinor int `json:"devMinor,omitempty"`
+
+	Xattrs map[string][]byte `json:"xattrs,omitempty"`
+
+	// Digest is the digest of the content of a regular file.
+	Digest string `json:"digest,omitempty"`
+
+	// ChunkOffset is the offset in the content of the file of a chunk.
+	ChunkOffset int64 `json:"chunkOffset,omitempty"`
+	// ChunkSize is the size of a chunk, it is 0 for the last chunk which
+	// ends at the end of the file.
+	ChunkSize int64 `json:"chunkSize,omitempty"`
+	// ChunkDigest is the digest of the content of a chunk.
+	ChunkDigest string `json:"chunkDigest,omitempty"`
+}
+
+// ModTime returns the modification time of the entry.
+func (e *TOCEntry) ModTime() time.Time {
+	t, _ := time.Parse(time.RFC3339, e.ModTime3339)
+	return t
+}
+
+// Reader reads the entries of a stargz blob and the content of its files.
+type Reader struct {
+	r         io.ReaderAt
+	size      int64
+	tocOffset int64
+	tocDigest digest.Digest
+	toc       *TOC
+
+	// entries are the entries of the table of contents, but the chunks
+	entries []*TOCEntry
+	m       map[string]*TOCEntry
+	// chunks are the chunks of regular files, the first one being the
+	// entry of the file
+	chunks map[string][]*TOCEntry
+	// offsets are the sorted offsets of the gzip members of the blob
+	// holding content, and of the table of contents
+	offsets []int64
+}
+
+// Open reads the table of contents of the stargz blob of the given size
+// read from r. ErrNotStargz is returned if the blob has no stargz footer.
+func Open(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < FooterSize {
+		return nil, ErrNotStargz
+	}
+	footer := make([]byte, FooterSize)
+	if _, err := readFullAt(r, footer, size-FooterSize); err != nil {
+		return nil, err
+	}
+	tocOffset, err := ParseFooter(footer)
+	if err != nil {
+		return nil, err
+	}
+	if tocOffset >= size-FooterSize {
+		return nil, fmt.Errorf("invalid stargz table of contents offset %d", tocOffset)
+	}
+
+	tocBuf := make([]byte, size-FooterSize-tocOffset)
+	if _, err := readFullAt(r, tocBuf, tocOffset); err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(tocBuf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stargz table of contents: %v", err)
+	}
+	tr := tar.NewReader(zr)
+	h, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stargz table of contents: %v", err)
+	}
+	if h.Name != TOCTarName {
+		return nil, fmt.Errorf("unexpected tar entry %q instead of the stargz table of contents", h.Name)
+	}
+	tocJSON, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stargz table of contents: %v", err)
+	}
+	toc := &TOC{}
+	if err := json.Unmarshal(tocJSON, toc); err != nil {
+		return nil, fmt.Errorf("failed to decode the stargz table of contents: %v", err)
+	}
+
+	sr := &Reader{
+		r:         r,
+		size:      size,
+		tocOffset: tocOffset,
+		tocDigest: digest.FromBytes(tocJSON),
+		toc:       toc,
+		m:         make(map[string]*TOCEntry),
+		chunks:    make(map[string][]*TOCEntry),
+	}
+	if err := sr.index(); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func (r *Reader) index() error {
+	offsets := map[int64]struct{}{r.tocOffset: {}}
+	for _, e := range r.toc.Entries {
+		e.Name = cleanEntryName(e.Name)
+		switch e.Type {
+		case "chunk":
+			chunks, ok := r.chunks[e.Name]
+			if !ok {
+				return fmt.Errorf("stargz chunk of unknown file %q", e.Name)
+			}
+			if e.ChunkOffset <= chunks[len(chunks)-1].ChunkOffset || e.ChunkOffset >= chunks[0].Size {
+				return fmt.Errorf("invalid offset %d of a stargz chunk of %q", e.ChunkOffset, e.Name)
+			}
+			r.chunks[e.Name] = append(chunks, e)
+		case "reg":
+			if e.Size < 0 {
+				return fmt.Errorf("invalid size %d of %q", e.Size, e.Name)
+			}
+			r.chunks[e.Name] = []*TOCEntry{e}
+			fallthrough
+		default:
+			r.entries = append(r.entries, e)
+			r.m[e.Name] = e
+		}
+		if e.Type == "hardlink" {
+			e.LinkName = cleanEntryName(e.LinkName)
+		}
+		if (e.Type == "reg" && e.Size > 0) || e.Type == "chunk" {
+			if e.Offset <= 0 || e.Offset >= r.tocOffset {
+				return fmt.Errorf("invalid offset %d of %q in the stargz blob", e.Offset, e.Name)
+			}
+			// the content is only read once it is verified
+			if _, err := digest.Parse(e.ChunkDigest); err != nil {
+				return fmt.Errorf("invalid digest of a chunk of %q in the stargz blob: %v", e.Name, err)
+			}
+			offsets[e.Offset] = struct{}{}
+		}
+	}
+	for o := range offsets {
+		r.offsets = append(r.offsets, o)
+	}
+	sort.Slice(r.offsets, func(i, j int) bool { return r.offsets[i] < r.offsets[j] })
+	return nil
+}
+
+// Entries returns the entries of the blob, but the chunks, in the order of
+// the tar stream.
+func (r *Reader) Entries() []*TOCEntry {
+	return r.entries
+}
+
+// Lookup returns the entry with the given name.
+func (r *Reader) Lookup(name string) (*TOCEntry, bool) {
+	e, ok := r.m[cleanEntryName(name)]
+	return e, ok
+}
+
+// TOCDigest returns the digest of the table of contents, the JSON content
+// of its tar entry.
+func (r *Reader) TOCDigest() digest.Digest {
+	return r.tocDigest
+}
+
+// TOCOffset returns the offset of the table of contents in the blob. The
+// content of the files is before it.
+func (r *Reader) TOCOffset() int64 {
+	return r.tocOffset
+}
+
+// extentEnd returns the end of the gzip member starting at offset.
+func (r *Reader) extentEnd(offset int64) int64 {
+	i := sort.Search(len(r.offsets), func(i int) bool { return r.offsets[i] > offset })
+	if i == len(r.offsets) {
+		return r.size
+	}
+	return r.offsets[i]
+}
+
+// OpenFile returns a reader of the content of a regular file.
+func (r *Reader) OpenFile(name string) (*FileReader, error) {
+	e, ok := r.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("no file %q in the stargz blob", name)
+	}
+	if e.Type != "reg" {
+		return nil, fmt.Errorf("%q is not a regular file", name)
+	}
+	return &FileReader{r: r, entry: e, chunks: r.chunks[e.Name]}, nil
+}
+
+// FileReader reads the content of a regular file of a stargz blob. The
+// chunk read last is kept in memory, so that sequential reads decompress
+// every chunk once.
+type FileReader struct {
+	r      *Reader
+	entry  *TOCEntry
+	chunks []*TOCEntry
+
+	mu        sync.Mutex
+	lastChunk int
+	lastData  []byte
+}
+
+// Size returns the size of the file.
+func (f *FileReader) Size() int64 {
+	return f.entry.Size
+}
+
+// ReadAt reads the content of the file at the given offset.
+func (f *FileReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	n := 0
+	for n < len(p) && off < f.entry.Size {
+		i := sort.Search(len(f.chunks), func(i int) bool { return f.chunks[i].ChunkOffset > off }) - 1
+		data, err := f.chunk(i)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], data[off-f.chunks[i].ChunkOffset:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *FileReader) chunk(i int) ([]byte, error) {
+	f.mu.Lock()
+	if f.lastData != nil && f.lastChunk == i {
+		data := f.lastData
+		f.mu.Unlock()
+		return data, nil
+	}
+	f.mu.Unlock()
+
+	end := f.entry.Size
+	if i+1 < len(f.chunks) {
+		end = f.chunks[i+1].ChunkOffset
+	}
+	data, err := f.r.readChunk(f.chunks[i], end-f.chunks[i].ChunkOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.lastChunk, f.lastData = i, data
+	f.mu.Unlock()
+	return data, nil
+}
+
+// readChunk reads and decompresses the gzip member holding a chunk, in a
+// single read of the underlying reader.
+func (r *Reader) readChunk(e *TOCEntry, size int64) ([]byte, error) {
+	buf := make([]byte, r.extentEnd(e.Offset)-e.Offset)
+	if _, err := readFullAt(r.r, buf, e.Offset); err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q at %d: %v", e.Name, e.ChunkOffset, err)
+	}
+	zr.Multistream(false)
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, fmt.Errorf("failed to read %q at %d: %v", e.Name, e.ChunkOffset, err)
+	}
+	// the digest is checked when the table of contents is indexed
+	dgst := digest.Digest(e.ChunkDigest)
+	if dgst.Algorithm().FromBytes(data) != dgst {
+		return nil, fmt.Errorf("content of %q at %d does not match the digest %s", e.Name, e.ChunkOffset, dgst)
+	}
+	return data, nil
+}
+
+// readFullAt reads len(p) bytes at off, an io.EOF at the end of the read is
+// not an error.
+func readFullAt(r io.ReaderAt, p []byte, off int64) (int, error) {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return n, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// ParseFooter returns the offset of the table of contents recorded in the
+// extra field of the gzip header of footer, the last FooterSize bytes of a
+// blob. ErrNotStargz is returned if it is not the footer of a stargz blob.
+func ParseFooter(footer []byte) (int64, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(footer))
+	if err != nil {
+		return 0, ErrNotStargz
+	}
+	defer zr.Close()
+	if _, err := io.Copy(ioutil.Discard, zr); err != nil {
+		return 0, ErrNotStargz
+	}
+	extra := zr.Header.Extra
+	// the offset may also be stored in a "SG" subfield
+	if len(extra) == 26 && extra[0] == 'S' && extra[1] == 'G' {
+		extra = extra[4:]
+	}
+	if len(extra) != 22 || !bytes.HasSuffix(extra, []byte("STARGZ")) {
+		return 0, ErrNotStargz
+	}
+	tocOffset, err := strconv.ParseInt(string(extra[:16]), 16, 64)
+	if err != nil {
+		return 0, ErrNotStargz
+	}
+	return tocOffset, nil
+}
+
+func cleanEntryName(name string) string {
+	// path.Clean removes the trailing slashes and the ".." escaping the
+	// root, once the name is made absolute
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,243 @@
+package stargz
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func makeTar(t *testing.T, entries []testEntry) []byte {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		h := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0644,
+			Size:     int64(len(e.content)),
+			ModTime:  time.Unix(1500000000, 0),
+		}
+		if e.typeflag == tar.TypeDir {
+			h.Mode = 0755
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeStargz(t *testing.T, tarData []byte, chunkSize int) ([]byte, digest.Digest) {
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	w.ChunkSize = chunkSize
+	if err := w.AppendTar(bytes.NewReader(tarData)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), w.DiffID()
+}
+
+var testEntries = []testEntry{
+	{name: "dir/", typeflag: tar.TypeDir},
+	{name: "dir/small", typeflag: tar.TypeReg, content: "small file"},
+	{name: "dir/empty", typeflag: tar.TypeReg},
+	{name: "./large", typeflag: tar.TypeReg, content: strings.Repeat("0123456789", 100)},
+	{name: "link", typeflag: tar.TypeSymlink, linkname: "dir/small"},
+	{name: "hardlink", typeflag: tar.TypeLink, linkname: "./large"},
+}
+
+func TestWriterKeepsTarStream(t *testing.T) {
+	blob, diffID := makeStargz(t, makeTar(t, testEntries), 64)
+
+	zr, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digester := digest.Canonical.Digester()
+	tr := tar.NewReader(io.TeeReader(zr, digester.Hash()))
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+	}
+	io.Copy(ioutil.Discard, zr)
+
+	expected := "dir/ dir/small dir/empty ./large link hardlink " + TOCTarName
+	if got := strings.Join(names, " "); got != expected {
+		t.Fatalf("expected the entries %q, got %q", expected, got)
+	}
+	if digester.Digest() != diffID {
+		t.Fatalf("expected the diff ID %s of the uncompressed stream, got %s", digester.Digest(), diffID)
+	}
+}
+
+func TestReaderReadsFiles(t *testing.T) {
+	tarData := makeTar(t, testEntries)
+	blob, _ := makeStargz(t, tarData, 64)
+
+	r, err := Open(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range r.Entries() {
+		names = append(names, e.Type+":"+e.Name)
+	}
+	expected := "dir:dir reg:dir/small reg:dir/empty reg:large symlink:link hardlink:hardlink"
+	if got := strings.Join(names, " "); got != expected {
+		t.Fatalf("expected the entries %q, got %q", expected, got)
+	}
+	if e, ok := r.Lookup("hardlink"); !ok || e.LinkName != "large" {
+		t.Fatalf("expected the hardlink to large, got %+v", e)
+	}
+	if e, _ := r.Lookup("dir/small"); !e.ModTime().Equal(time.Unix(1500000000, 0)) {
+		t.Fatalf("unexpected modification time %s", e.ModTime())
+	}
+
+	for _, e := range testEntries {
+		if e.typeflag != tar.TypeReg {
+			continue
+		}
+		f, err := r.OpenFile(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Size() != int64(len(e.content)) {
+			t.Fatalf("expected the size %d of %s, got %d", len(e.content), e.name, f.Size())
+		}
+		// reads across chunks
+		for _, off := range []int64{0, 10, 60, 500} {
+			if off > f.Size() {
+				continue
+			}
+			p := make([]byte, 100)
+			n, err := f.ReadAt(p, off)
+			if err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+			end := off + 100
+			if end > f.Size() {
+				end = f.Size()
+			}
+			if string(p[:n]) != e.content[off:end] {
+				t.Fatalf("unexpected content of %s at %d: %q", e.name, off, p[:n])
+			}
+		}
+	}
+}
+
+func TestReaderVerifiesChunks(t *testing.T) {
+	blob, _ := makeStargz(t, makeTar(t, []testEntry{{name: "file", typeflag: tar.TypeReg, content: "content"}}), 0)
+
+	r, err := Open(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := r.Lookup("file")
+	e.ChunkDigest = digest.FromString("other content").String()
+
+	f, err := r.OpenFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadAt(make([]byte, 7), 0); err == nil || !strings.Contains(err.Error(), "does not match the digest") {
+		t.Fatalf("expected a digest mismatch error, got %v", err)
+	}
+}
+
+func TestReaderRequiresChunkDigests(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	if err := w.AppendTar(bytes.NewReader(makeTar(t, []testEntry{{name: "file", typeflag: tar.TypeReg, content: "content"}}))); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range w.toc.Entries {
+		e.ChunkDigest = ""
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil || !strings.Contains(err.Error(), "invalid digest") {
+		t.Fatalf("expected an invalid digest error, got %v", err)
+	}
+}
+
+func TestTOCDigest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	if err := w.AppendTar(bytes.NewReader(makeTar(t, testEntries))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.TOCDigest() == "" || r.TOCDigest() != w.TOCDigest() {
+		t.Fatalf("expected the table of contents digest %s, got %s", w.TOCDigest(), r.TOCDigest())
+	}
+}
+
+func TestOpenNotStargz(t *testing.T) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	gz.Write(makeTar(t, testEntries))
+	gz.Close()
+
+	if _, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != ErrNotStargz {
+		t.Fatalf("expected %v, got %v", ErrNotStargz, err)
+	}
+}
+
+func TestFooterSize(t *testing.T) {
+	blob, _ := makeStargz(t, makeTar(t, testEntries), 0)
+	footer := blob[len(blob)-FooterSize:]
+	if footer[0] != 0x1f || footer[1] != 0x8b {
+		t.Fatalf("expected the footer to be a gzip member of %d bytes", FooterSize)
+	}
+	tocOffset, err := ParseFooter(footer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TOCOffset() != tocOffset {
+		t.Fatalf("expected the table of contents at %d, got %d", tocOffset, r.TOCOffset())
+	}
+}
@@ -0,0 +1,276 @@
+package stargz
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// defaultChunkSize is the default size of the chunks the content of large
+// files is split in.
+const defaultChunkSize = 4 << 20
+
+// Writer converts a tar stream to a stargz blob.
+type Writer struct {
+	// ChunkSize is the size of the chunks the content of large files is
+	// split in, 4MiB if not set.
+	ChunkSize int
+
+	bw *bufio.Writer
+	cw *countWriter
+	gz *gzip.Writer
+
+	toc       TOC
+	tocDigest digest.Digest
+	diffID    digest.Digester
+	closed    bool
+}
+
+// NewWriter returns a writer of a stargz blob to w. Close must be called to
+// write the table of contents once the tar streams are appended.
+func NewWriter(w io.Writer) *Writer {
+	bw := bufio.NewWriter(w)
+	sw := &Writer{
+		bw:     bw,
+		cw:     &countWriter{w: bw},
+		toc:    TOC{Version: 1},
+		diffID: digest.Canonical.Digester(),
+	}
+	return sw
+}
+
+// currentGzipWriter writes to the gzip member being written, starting a new
+// one if needed.
+type currentGzipWriter struct{ w *Writer }
+
+func (cgw currentGzipWriter) Write(p []byte) (int, error) {
+	if err := cgw.w.condOpenGz(); err != nil {
+		return 0, err
+	}
+	cgw.w.diffID.Hash().Write(p)
+	return cgw.w.gz.Write(p)
+}
+
+func (w *Writer) condOpenGz() error {
+	if w.gz != nil {
+		return nil
+	}
+	gz, err := gzip.NewWriterLevel(w.cw, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	w.gz = gz
+	return nil
+}
+
+func (w *Writer) closeGz() error {
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	w.gz = nil
+	return err
+}
+
+func (w *Writer) chunkSize() int64 {
+	if w.ChunkSize <= 0 {
+		return defaultChunkSize
+	}
+	return int64(w.ChunkSize)
+}
+
+// AppendTar appends the entries of the uncompressed tar stream r.
+func (w *Writer) AppendTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the tar stream: %v", err)
+		}
+		if cleanEntryName(h.Name) == TOCTarName {
+			// a table of contents is written when the writer is closed
+			continue
+		}
+
+		ent := &TOCEntry{
+			Name:  cleanEntryName(h.Name),
+			Mode:  h.Mode,
+			UID:   h.Uid,
+			GID:   h.Gid,
+			Uname: h.Uname,
+			Gname: h.Gname,
+		}
+		if !h.ModTime.IsZero() {
+			ent.ModTime3339 = h.ModTime.UTC().Format(time.RFC3339)
+		}
+		if len(h.Xattrs) > 0 {
+			ent.Xattrs = make(map[string][]byte)
+			for k, v := range h.Xattrs {
+				ent.Xattrs[k] = []byte(v)
+			}
+		}
+		switch h.Typeflag {
+		case tar.TypeLink:
+			ent.Type = "hardlink"
+			ent.LinkName = cleanEntryName(h.Linkname)
+		case tar.TypeSymlink:
+			ent.Type = "symlink"
+			ent.LinkName = h.Linkname
+		case tar.TypeDir:
+			ent.Type = "dir"
+		case tar.TypeReg, tar.TypeRegA:
+			ent.Type = "reg"
+			ent.Size = h.Size
+		case tar.TypeChar:
+			ent.Type = "char"
+			ent.DevMajor, ent.DevMinor = int(h.Devmajor), int(h.Devminor)
+		case tar.TypeBlock:
+			ent.Type = "block"
+			ent.DevMajor, ent.DevMinor = int(h.Devmajor), int(h.Devminor)
+		case tar.TypeFifo:
+			ent.Type = "fifo"
+		default:
+			return fmt.Errorf("unsupported type %q of tar entry %q", h.Typeflag, h.Name)
+		}
+
+		// the padding of every entry is flushed after it, so that the
+		// gzip members of the content only hold tar data of their entry
+		tw := tar.NewWriter(currentGzipWriter{w})
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if ent.Type != "reg" || ent.Size == 0 {
+			w.toc.Entries = append(w.toc.Entries, ent)
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// every chunk of the content starts a new gzip member, so that it
+		// can be decompressed on its own
+		fileDigest := digest.Canonical.Digester()
+		content := io.TeeReader(tr, fileDigest.Hash())
+		fileEnt := ent
+		for written := int64(0); written < h.Size; {
+			if err := w.closeGz(); err != nil {
+				return err
+			}
+			size := w.chunkSize()
+			if remain := h.Size - written; remain < size {
+				size = remain
+			} else {
+				ent.ChunkSize = size
+			}
+			ent.Offset = w.cw.n
+			ent.ChunkOffset = written
+
+			chunkDigest := digest.Canonical.Digester()
+			if _, err := io.CopyN(tw, io.TeeReader(content, chunkDigest.Hash()), size); err != nil {
+				return fmt.Errorf("failed to copy %q: %v", h.Name, err)
+			}
+			ent.ChunkDigest = chunkDigest.Digest().String()
+			w.toc.Entries = append(w.toc.Entries, ent)
+			written += size
+			ent = &TOCEntry{Name: fileEnt.Name, Type: "chunk"}
+		}
+		fileEnt.Digest = fileDigest.Digest().String()
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// Close writes the table of contents and the footer of the blob.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.closeGz(); err != nil {
+		return err
+	}
+	tocOffset := w.cw.n
+	tocJSON, err := json.MarshalIndent(w.toc, "", "\t")
+	if err != nil {
+		return err
+	}
+	w.tocDigest = digest.FromBytes(tocJSON)
+	tw := tar.NewWriter(currentGzipWriter{w})
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     TOCTarName,
+		Mode:     0444,
+		Size:     int64(len(tocJSON)),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(tocJSON); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := w.closeGz(); err != nil {
+		return err
+	}
+
+	if _, err := w.cw.Write(footerBytes(tocOffset)); err != nil {
+		return err
+	}
+	return w.bw.Flush()
+}
+
+// DiffID returns the digest of the uncompressed tar stream of the blob, it
+// is only valid once the writer is closed.
+func (w *Writer) DiffID() digest.Digest {
+	return w.diffID.Digest()
+}
+
+// TOCDigest returns the digest of the table of contents of the blob, to
+// record in the TOCDigestAnnotation of its layer descriptor. It is only
+// valid once the writer is closed.
+func (w *Writer) TOCDigest() digest.Digest {
+	return w.tocDigest
+}
+
+// footerBytes returns the footer of a blob, a gzip member of empty content
+// recording the offset of the table of contents in the extra field of its
+// header. It is made of a stored deflate block, so that its size is fixed.
+func footerBytes(tocOffset int64) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, FooterSize))
+	// magic, deflate method, FEXTRA flag, no modification time, unknown OS
+	buf.Write([]byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff})
+	extra := fmt.Sprintf("%016xSTARGZ", tocOffset)
+	binary.Write(buf, binary.LittleEndian, uint16(len(extra)))
+	buf.WriteString(extra)
+	// final stored block of length 0
+	buf.Write([]byte{1, 0, 0, 0xff, 0xff})
+	// crc32 and size of the empty content
+	buf.Write(make([]byte, 8))
+	return buf.Bytes()
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
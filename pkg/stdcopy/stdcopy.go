@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"sync"
 )
 
@@ -23,6 +24,9 @@ const (
 	// Systemerr represents errors originating from the system that make it
 	// into the the multiplexed stream.
 	Systemerr
+	// ExitCode represents the exit code of a process, sent as the last frame
+	// of the multiplexed stream. The frame holds the code in decimal.
+	ExitCode
 
 	stdWriterPrefixLen = 8
 	stdWriterFdIndex   = 0
@@ -92,6 +96,18 @@ func NewStdWriter(w io.Writer, t StdType) io.Writer {
 //
 // `written` will hold the total number of bytes written to `dstout` and `dsterr`.
 func StdCopy(dstout, dsterr io.Writer, src io.Reader) (written int64, err error) {
+	return stdCopy(dstout, dsterr, src, nil)
+}
+
+// StdCopyWithExitCode is like StdCopy, but also returns the exit code sent in
+// an ExitCode frame. `exitCode` is -1 if the stream holds no such frame.
+func StdCopyWithExitCode(dstout, dsterr io.Writer, src io.Reader) (written int64, exitCode int, err error) {
+	exitCode = -1
+	written, err = stdCopy(dstout, dsterr, src, &exitCode)
+	return written, exitCode, err
+}
+
+func stdCopy(dstout, dsterr io.Writer, src io.Reader, exitCode *int) (written int64, err error) {
 	var (
 		buf       = make([]byte, startingBufLen)
 		bufLen    = len(buf)
@@ -129,10 +145,10 @@ func StdCopy(dstout, dsterr io.Writer, src io.Reader) (written int64, err error)
 		case Stderr:
 			// Write on stderr
 			out = dsterr
-		case Systemerr:
-			// If we're on Systemerr, we won't write anywhere.
+		case Systemerr, ExitCode:
+			// If we're on Systemerr or ExitCode, we won't write anywhere.
 			// NB: if this code changes later, make sure you don't try to write
-			// to outstream if Systemerr is the stream
+			// to outstream if Systemerr or ExitCode is the stream
 			out = nil
 		default:
 			return 0, fmt.Errorf("Unrecognized input header: %d", buf[stdWriterFdIndex])
@@ -170,6 +186,19 @@ func StdCopy(dstout, dsterr io.Writer, src io.Reader) (written int64, err error)
 			return written, fmt.Errorf("error from daemon in stream: %s", string(buf[stdWriterPrefixLen:frameSize+stdWriterPrefixLen]))
 		}
 
+		if stream == ExitCode {
+			if exitCode != nil {
+				code, err := strconv.Atoi(string(buf[stdWriterPrefixLen : frameSize+stdWriterPrefixLen]))
+				if err != nil {
+					return written, fmt.Errorf("invalid exit code in stream: %v", err)
+				}
+				*exitCode = code
+			}
+			copy(buf, buf[frameSize+stdWriterPrefixLen:])
+			nr -= frameSize + stdWriterPrefixLen
+			continue
+		}
+
 		// Write the retrieved frame (without header)
 		nw, ew = out.Write(buf[stdWriterPrefixLen : frameSize+stdWriterPrefixLen])
 		if ew != nil {
@@ -275,6 +275,56 @@ func TestStdCopyReturnsErrorFromSystem(t *testing.T) {
 	}
 }
 
+func TestStdCopyWithExitCode(t *testing.T) {
+	stdOutBytes := []byte(strings.Repeat("o", startingBufLen))
+	stdErrBytes := []byte(strings.Repeat("e", startingBufLen))
+	buffer, err := getSrcBuffer(stdOutBytes, stdErrBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStdWriter(buffer, ExitCode).Write([]byte("42")); err != nil {
+		t.Fatal(err)
+	}
+	src := buffer.Bytes()
+
+	dstOut := new(bytes.Buffer)
+	dstErr := new(bytes.Buffer)
+	written, exitCode, err := StdCopyWithExitCode(dstOut, dstErr, bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := int64(len(stdOutBytes) + len(stdErrBytes)); written != expected {
+		t.Fatalf("Expected to have total of %d bytes written, got %d", expected, written)
+	}
+	if exitCode != 42 {
+		t.Fatalf("Expected exit code 42, got %d", exitCode)
+	}
+	if !bytes.Equal(dstOut.Bytes(), stdOutBytes) || !bytes.Equal(dstErr.Bytes(), stdErrBytes) {
+		t.Fatal("Expected the exit code frame not to be written to stdout or stderr")
+	}
+
+	// StdCopy skips the exit code frame
+	dstOut.Reset()
+	if _, err := StdCopy(dstOut, ioutil.Discard, bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dstOut.Bytes(), stdOutBytes) {
+		t.Fatal("Expected the exit code frame not to be written to stdout")
+	}
+
+	// no exit code frame
+	buffer, err = getSrcBuffer(stdOutBytes, stdErrBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exitCode, err = StdCopyWithExitCode(ioutil.Discard, ioutil.Discard, buffer); err != nil {
+		t.Fatal(err)
+	}
+	if exitCode != -1 {
+		t.Fatalf("Expected exit code -1 without an exit code frame, got %d", exitCode)
+	}
+}
+
 func BenchmarkWrite(b *testing.B) {
 	w := NewStdWriter(ioutil.Discard, Stdout)
 	data := []byte("Test line for testing stdwriter performance\n")
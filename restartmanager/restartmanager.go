@@ -23,6 +23,8 @@ var ErrRestartCanceled = errors.New("restart canceled")
 type RestartManager interface {
 	Cancel() error
 	ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error)
+	ShouldRestartUnhealthy(unhealthyCount int) bool
+	SetHealthy()
 }
 
 type restartManager struct {
@@ -34,6 +36,8 @@ type restartManager struct {
 	active       bool
 	cancel       chan struct{}
 	canceled     bool
+	unhealthy    bool
+	healthy      bool
 }
 
 // New returns a new restartManager based on a policy.
@@ -47,10 +51,31 @@ func (rm *restartManager) SetPolicy(policy container.RestartPolicy) {
 	rm.Unlock()
 }
 
-func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error) {
-	if rm.policy.IsNone() {
-		return false, nil, nil
+// ShouldRestartUnhealthy decides whether a container should be restarted
+// after unhealthyCount consecutive unhealthy health check results. If so,
+// the next exit of the container is handled as a restart, whatever the
+// policy and exit code.
+func (rm *restartManager) ShouldRestartUnhealthy(unhealthyCount int) bool {
+	rm.Lock()
+	defer rm.Unlock()
+
+	threshold := rm.policy.UnhealthyThreshold
+	if threshold <= 0 || unhealthyCount < threshold || rm.canceled || rm.active || rm.unhealthy {
+		return false
 	}
+	rm.unhealthy = true
+	return true
+}
+
+// SetHealthy records that the container passed its health check, so that the
+// backoff of its next restart after failed health checks is reset.
+func (rm *restartManager) SetHealthy() {
+	rm.Lock()
+	rm.healthy = true
+	rm.Unlock()
+}
+
+func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped bool, executionDuration time.Duration) (bool, chan error, error) {
 	rm.Lock()
 	unlockOnExit := true
 	defer func() {
@@ -59,6 +84,12 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 		}
 	}()
 
+	unhealthy, healthy := rm.unhealthy, rm.healthy
+	rm.unhealthy, rm.healthy = false, false
+	if rm.policy.IsNone() && !unhealthy {
+		return false, nil, nil
+	}
+
 	if rm.canceled {
 		return false, nil, ErrRestartCanceled
 	}
@@ -67,8 +98,10 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 		return false, nil, fmt.Errorf("invalid call on an active restart manager")
 	}
 	// if the container ran for more than 10s, regardless of status and policy reset the
-	// the timeout back to the default.
-	if executionDuration.Seconds() >= 10 {
+	// the timeout back to the default. Containers restarted after failed health checks
+	// usually run for longer than that, their timeout is only reset if they were healthy
+	// since their last restart.
+	if (!unhealthy && executionDuration.Seconds() >= 10) || (unhealthy && healthy) {
 		rm.timeout = 0
 	}
 	switch {
@@ -83,6 +116,11 @@ func (rm *restartManager) ShouldRestart(exitCode uint32, hasBeenManuallyStopped
 
 	var restart bool
 	switch {
+	case unhealthy:
+		// the default value of 0 for MaximumRetryCount means that we will not enforce a maximum count
+		if max := rm.policy.MaximumRetryCount; max == 0 || rm.restartCount < max {
+			restart = !hasBeenManuallyStopped
+		}
 	case rm.policy.IsAlways():
 		restart = true
 	case rm.policy.IsUnlessStopped() && !hasBeenManuallyStopped:
@@ -34,3 +34,85 @@ func TestRestartManagerTimeoutReset(t *testing.T) {
 		t.Fatalf("restart manager should have a timeout of 100 ms but has %s", rm.timeout)
 	}
 }
+
+func TestRestartManagerUnhealthy(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "no", UnhealthyThreshold: 2}, 0).(*restartManager)
+	if rm.ShouldRestartUnhealthy(1) {
+		t.Fatal("container should not be restarted below the threshold")
+	}
+	if !rm.ShouldRestartUnhealthy(2) {
+		t.Fatal("container should be restarted at the threshold")
+	}
+	if rm.ShouldRestartUnhealthy(3) {
+		t.Fatal("container should be restarted only once")
+	}
+
+	rm.timeout = 5 * time.Second
+	should, _, err := rm.ShouldRestart(137, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("unhealthy container should be restarted")
+	}
+	if rm.timeout != 10*time.Second {
+		t.Fatalf("restart manager should back off to 10s but has %s", rm.timeout)
+	}
+	rm.active = false
+
+	// unhealthy containers run for longer than 10s, they keep backing off
+	if !rm.ShouldRestartUnhealthy(2) {
+		t.Fatal("container should be restarted at the threshold")
+	}
+	should, _, err = rm.ShouldRestart(137, false, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("unhealthy container should be restarted")
+	}
+	if rm.timeout != 20*time.Second {
+		t.Fatalf("restart manager should back off to 20s but has %s", rm.timeout)
+	}
+	rm.active = false
+
+	// the backoff is reset once the container was healthy
+	rm.SetHealthy()
+	if !rm.ShouldRestartUnhealthy(2) {
+		t.Fatal("container should be restarted at the threshold")
+	}
+	should, _, err = rm.ShouldRestart(137, false, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !should {
+		t.Fatal("unhealthy container should be restarted")
+	}
+	if rm.timeout != defaultTimeout {
+		t.Fatalf("restart manager should have a timeout of 100 ms but has %s", rm.timeout)
+	}
+	rm.active = false
+
+	// the restart was consumed, the "no" policy applies again
+	should, _, err = rm.ShouldRestart(137, false, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if should {
+		t.Fatal("container should not be restarted")
+	}
+}
+
+func TestRestartManagerUnhealthyMaximumRetryCount(t *testing.T) {
+	rm := New(container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 1, UnhealthyThreshold: 1}, 1).(*restartManager)
+	if !rm.ShouldRestartUnhealthy(1) {
+		t.Fatal("container should be restarted at the threshold")
+	}
+	should, _, err := rm.ShouldRestart(137, false, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if should {
+		t.Fatal("container should not be restarted past the maximum retry count")
+	}
+}
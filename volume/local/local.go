@@ -17,6 +17,8 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api"
+	"github.com/docker/docker/daemon/graphdriver/quota"
+	"github.com/docker/docker/pkg/directory"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/volume"
@@ -55,7 +57,12 @@ type activeMount struct {
 // New instantiates a new Root instance with the provided scope. Scope
 // is the base path that the Root instance uses to store its
 // volumes. The base path is created here if it does not exist.
-func New(scope string, rootUID, rootGID int) (*Root, error) {
+// The ID mappings are the user namespace remapping of the daemon.
+func New(scope string, uidMaps, gidMaps []idtools.IDMap) (*Root, error) {
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
 	rootDirectory := filepath.Join(scope, volumesPathName)
 
 	if err := idtools.MkdirAllAs(rootDirectory, 0700, rootUID, rootGID); err != nil {
@@ -68,6 +75,8 @@ func New(scope string, rootUID, rootGID int) (*Root, error) {
 		volumes: make(map[string]*localVolume),
 		rootUID: rootUID,
 		rootGID: rootGID,
+		uidMaps: uidMaps,
+		gidMaps: gidMaps,
 	}
 
 	dirs, err := ioutil.ReadDir(rootDirectory)
@@ -112,6 +121,7 @@ func New(scope string, rootUID, rootGID int) (*Root, error) {
 				}
 			}
 		}
+		r.restoreQuota(v)
 	}
 
 	return r, nil
@@ -127,6 +137,13 @@ type Root struct {
 	volumes map[string]*localVolume
 	rootUID int
 	rootGID int
+	uidMaps []idtools.IDMap
+	gidMaps []idtools.IDMap
+	// quotaCtl assigns a project quota to the volumes that are not
+	// mounted, limited to the size of the volumes created with one. It is
+	// initialized when first needed, quotaErr records why it could not be.
+	quotaCtl *quota.Control
+	quotaErr error
 }
 
 // List lists all the volumes
@@ -167,11 +184,11 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 	}
 
 	path := r.DataPath(name)
-	if err := idtools.MkdirAllAs(path, 0755, r.rootUID, r.rootGID); err != nil {
+	if err := idtools.MkdirAllAs(filepath.Dir(path), 0755, r.rootUID, r.rootGID); err != nil {
 		if os.IsExist(err) {
 			return nil, fmt.Errorf("volume already exists under %s", filepath.Dir(path))
 		}
-		return nil, errors.Wrapf(err, "error while creating volume path '%s'", path)
+		return nil, errors.Wrapf(err, "error while creating volume path '%s'", filepath.Dir(path))
 	}
 
 	var err error
@@ -200,6 +217,18 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 			return nil, errors.Wrap(err, "error while persisting volume options")
 		}
 	}
+	// the project id of the quota is only inherited by the directories
+	// created after it is set, so the data directory is created last
+	if err = r.setQuota(v); err != nil {
+		return nil, err
+	}
+	if err = idtools.MkdirAllAs(path, 0755, r.rootUID, r.rootGID); err != nil {
+		err = errors.Wrapf(err, "error while creating volume path '%s'", path)
+		return nil, err
+	}
+	if err = r.setOwnership(v); err != nil {
+		return nil, err
+	}
 
 	r.volumes[name] = v
 	return v, nil
@@ -296,6 +325,9 @@ type localVolume struct {
 	opts *optsConfig
 	// active refcounts the active mounts
 	active activeMount
+	// quotaCtl is set when the volume has a project quota, and reports the
+	// space it uses
+	quotaCtl *quota.Control
 }
 
 // Name returns the name of the given Volume.
@@ -318,7 +350,7 @@ func (v *localVolume) Path() string {
 func (v *localVolume) Mount(id string) (string, error) {
 	v.m.Lock()
 	defer v.m.Unlock()
-	if v.opts != nil {
+	if v.needsMount() {
 		if !v.active.mounted {
 			if err := v.mount(); err != nil {
 				return "", err
@@ -340,7 +372,7 @@ func (v *localVolume) Unmount(id string) error {
 	// Essentially docker doesn't care if this fails, it will send an error, but
 	// ultimately there's nothing that can be done. If we don't decrement the count
 	// this volume can never be removed until a daemon restart occurs.
-	if v.opts != nil {
+	if v.needsMount() {
 		v.active.count--
 	}
 
@@ -352,7 +384,7 @@ func (v *localVolume) Unmount(id string) error {
 }
 
 func (v *localVolume) unmount() error {
-	if v.opts != nil {
+	if v.needsMount() {
 		if err := mount.Unmount(v.path); err != nil {
 			if mounted, mErr := mount.Mounted(v.path); mounted || mErr != nil {
 				return errors.Wrapf(err, "error while unmounting volume path '%s'", v.path)
@@ -363,6 +395,31 @@ func (v *localVolume) unmount() error {
 	return nil
 }
 
+// needsMount returns whether resources are mounted for the volume.
+func (v *localVolume) needsMount() bool {
+	return v.opts != nil && v.opts.needsMount()
+}
+
+// KeepOwnership returns whether the ownership or the mode of the volume
+// were set when it was created, in which case they are not copied from the
+// image when the volume is populated.
+func (v *localVolume) KeepOwnership() bool {
+	return v.opts != nil && v.opts.hasOwnership()
+}
+
+// Usage returns the space used by the volume, in bytes. It is read from the
+// quota counters of the volumes that have a project quota, the data of the
+// others is walked.
+func (v *localVolume) Usage() (int64, error) {
+	if v.quotaCtl != nil {
+		var q quota.Quota
+		if err := v.quotaCtl.GetQuota(filepath.Dir(v.path), &q); err == nil {
+			return int64(q.Used), nil
+		}
+	}
+	return directory.Size(v.path)
+}
+
 func validateOpts(opts map[string]string) error {
 	for opt := range opts {
 		if !validOpts[opt] {
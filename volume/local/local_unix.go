@@ -10,13 +10,18 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/graphdriver/quota"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/go-units"
 )
 
 var (
@@ -26,6 +31,10 @@ var (
 		"type":   true, // specify the filesystem type for mount, e.g. nfs
 		"o":      true, // generic mount options
 		"device": true, // device to mount from
+		"size":   true, // hard limit of the space used by the volume, e.g. 10G
+		"uid":    true, // owner of the volume directory, in the containers
+		"gid":    true, // group of the volume directory, in the containers
+		"mode":   true, // permissions of the volume directory, in octal
 	}
 )
 
@@ -33,6 +42,23 @@ type optsConfig struct {
 	MountType   string
 	MountOpts   string
 	MountDevice string
+	Size        uint64  `json:",omitempty"`
+	UID         *int    `json:",omitempty"`
+	GID         *int    `json:",omitempty"`
+	Mode        *uint32 `json:",omitempty"`
+}
+
+// needsMount returns whether the options describe a filesystem to mount.
+// Volumes with a size, or with only their ownership set, are plain
+// directories.
+func (o *optsConfig) needsMount() bool {
+	return o.MountType != "" || o.MountOpts != "" || o.MountDevice != ""
+}
+
+// hasOwnership returns whether the options set the ownership or the mode of
+// the volume directory.
+func (o *optsConfig) hasOwnership() bool {
+	return o.UID != nil || o.GID != nil || o.Mode != nil
 }
 
 func (o *optsConfig) String() string {
@@ -68,9 +94,148 @@ func setOpts(v *localVolume, opts map[string]string) error {
 		MountOpts:   opts["o"],
 		MountDevice: opts["device"],
 	}
+	if size, ok := opts["size"]; ok {
+		if v.opts.MountType != "" || v.opts.MountOpts != "" || v.opts.MountDevice != "" {
+			return validationError{fmt.Errorf("the size option cannot be combined with the type, o and device options")}
+		}
+		sz, err := units.RAMInBytes(size)
+		if err != nil || sz <= 0 {
+			return validationError{fmt.Errorf("invalid size for volume: %q", size)}
+		}
+		v.opts.Size = uint64(sz)
+	}
+	for _, opt := range []struct {
+		key string
+		id  **int
+	}{{"uid", &v.opts.UID}, {"gid", &v.opts.GID}} {
+		value, ok := opts[opt.key]
+		if !ok {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			return validationError{fmt.Errorf("invalid %s for volume: %q", opt.key, value)}
+		}
+		*opt.id = &id
+	}
+	if value, ok := opts["mode"]; ok {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || mode > 07777 {
+			return validationError{fmt.Errorf("invalid mode for volume: %q", value)}
+		}
+		m := uint32(mode)
+		v.opts.Mode = &m
+	}
+	if v.opts.hasOwnership() && v.opts.needsMount() {
+		return validationError{fmt.Errorf("the uid, gid and mode options cannot be combined with the type, o and device options")}
+	}
+	return nil
+}
+
+// setOwnership sets the ownership and the mode of the data directory of a
+// volume from its options. The uid and gid are IDs in the containers, they
+// are mapped to the IDs on the host under user namespace remapping.
+func (r *Root) setOwnership(v *localVolume) error {
+	if v.opts == nil || !v.opts.hasOwnership() {
+		return nil
+	}
+	if v.opts.UID != nil || v.opts.GID != nil {
+		uid, gid := r.rootUID, r.rootGID
+		if v.opts.UID != nil {
+			hostUID, err := idtools.ToHost(*v.opts.UID, r.uidMaps)
+			if err != nil {
+				return validationError{errors.Wrapf(err, "invalid uid for volume")}
+			}
+			uid = hostUID
+		}
+		if v.opts.GID != nil {
+			hostGID, err := idtools.ToHost(*v.opts.GID, r.gidMaps)
+			if err != nil {
+				return validationError{errors.Wrapf(err, "invalid gid for volume")}
+			}
+			gid = hostGID
+		}
+		if err := os.Chown(v.path, uid, gid); err != nil {
+			return errors.Wrap(err, "error while setting the owner of the volume")
+		}
+	}
+	if v.opts.Mode != nil {
+		// os.Chmod takes the setuid, setgid and sticky bits as os.FileMode
+		// flags, not as the bits of the octal mode
+		mode := os.FileMode(*v.opts.Mode) & os.ModePerm
+		if *v.opts.Mode&syscall.S_ISUID != 0 {
+			mode |= os.ModeSetuid
+		}
+		if *v.opts.Mode&syscall.S_ISGID != 0 {
+			mode |= os.ModeSetgid
+		}
+		if *v.opts.Mode&syscall.S_ISVTX != 0 {
+			mode |= os.ModeSticky
+		}
+		if err := os.Chmod(v.path, mode); err != nil {
+			return errors.Wrap(err, "error while setting the mode of the volume")
+		}
+	}
 	return nil
 }
 
+// quotaControl returns the project quota control of the volumes directory,
+// it is called with the lock held.
+func (r *Root) quotaControl() (*quota.Control, error) {
+	if r.quotaCtl == nil && r.quotaErr == nil {
+		r.quotaCtl, r.quotaErr = quota.NewControl(r.path)
+	}
+	return r.quotaCtl, r.quotaErr
+}
+
+// setQuota assigns a project quota to a volume that is not mounted, so that
+// the space it uses is read from the quota counters. The quota is limited
+// to the size of the volumes created with one, which requires project
+// quotas; the other volumes are only assigned one when they are supported.
+// It is called with the lock held.
+func (r *Root) setQuota(v *localVolume) error {
+	var size uint64
+	if v.opts != nil {
+		if v.opts.needsMount() {
+			return nil
+		}
+		size = v.opts.Size
+	}
+	ctl, err := r.quotaControl()
+	if err != nil {
+		if size == 0 {
+			return nil
+		}
+		return errors.Wrapf(err, "the size option requires project quotas on the filesystem of %s", r.path)
+	}
+	if err := ctl.SetQuota(filepath.Dir(v.path), quota.Quota{Size: size}); err != nil {
+		if size == 0 {
+			logrus.Debugf("error while setting the project quota of volume %s: %v", v.name, err)
+			return nil
+		}
+		return errors.Wrap(err, "error while setting the size of the volume")
+	}
+	v.quotaCtl = ctl
+	return nil
+}
+
+// restoreQuota makes the quota counters of a volume available after a
+// restart. The quota itself is kept by the filesystem, volumes created
+// without one have their usage computed by walking their data.
+func (r *Root) restoreQuota(v *localVolume) {
+	if v.opts != nil && v.opts.needsMount() {
+		return
+	}
+	ctl, err := r.quotaControl()
+	if err != nil {
+		if v.opts != nil && v.opts.Size != 0 {
+			logrus.Warnf("error while restoring the quota of volume %s: %v", v.name, err)
+		}
+		return
+	}
+	v.quotaCtl = ctl
+}
+
 func (v *localVolume) mount() error {
 	if v.opts.MountDevice == "" {
 		return fmt.Errorf("missing device in volume options")
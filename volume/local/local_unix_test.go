@@ -0,0 +1,136 @@
+// +build linux
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/pkg/idtools"
+)
+
+func TestCreateWithOwnership(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("chown requires root")
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	idMaps := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+	r, err := New(rootDir, idMaps, idMaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []map[string]string{
+		{"uid": "-1"},
+		{"gid": "root"},
+		{"mode": "0999"},
+		{"mode": "17777"},
+		{"uid": "1000", "type": "tmpfs", "device": "tmpfs"},
+		{"uid": "70000"},
+	} {
+		if _, err := r.Create("invalid", opts); err == nil {
+			t.Fatalf("expected options %v to cause error", opts)
+		}
+	}
+
+	vol, err := r.Create("test", map[string]string{"uid": "1000", "gid": "1001", "mode": "2770"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vol.(*localVolume)
+	if !v.KeepOwnership() {
+		t.Fatal("expected the ownership of the volume to be kept")
+	}
+
+	fi, err := os.Stat(v.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := fi.Sys().(*syscall.Stat_t)
+	if stat.Uid != 101000 || stat.Gid != 101001 {
+		t.Fatalf("expected volume to be owned by 101000:101001, got %d:%d", stat.Uid, stat.Gid)
+	}
+	if fi.Mode() != os.ModeDir|os.ModeSetgid|0770 {
+		t.Fatalf("expected volume mode to be %v, got %v", os.ModeDir|os.ModeSetgid|0770, fi.Mode())
+	}
+
+	// volumes with only their ownership set are not mounted
+	if _, err := v.Mount("1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Unmount("1234"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSizeOpt(t *testing.T) {
+	for _, opts := range []map[string]string{
+		{"size": "big"},
+		{"size": "0"},
+		{"size": "10m", "type": "tmpfs", "device": "tmpfs"},
+	} {
+		if err := setOpts(&localVolume{}, opts); err == nil {
+			t.Fatalf("expected options %v to cause error", opts)
+		}
+	}
+
+	v := &localVolume{}
+	if err := setOpts(v, map[string]string{"size": "10m"}); err != nil {
+		t.Fatal(err)
+	}
+	if v.opts.Size != 10*1024*1024 {
+		t.Fatalf("expected size to be 10m, got %d", v.opts.Size)
+	}
+	if v.needsMount() {
+		t.Fatal("expected volume with a size not to be mounted")
+	}
+}
+
+func TestSizeQuota(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("project quotas require root")
+	}
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vol, err := r.Create("test", map[string]string{"size": "1m"})
+	if err != nil {
+		if strings.Contains(err.Error(), "requires project quotas") {
+			t.Skipf("project quotas are not supported on %s", rootDir)
+		}
+		t.Fatal(err)
+	}
+
+	// the data directory inherits the project id of the volume, so the
+	// files written to it are accounted for and limited by its quota
+	data := make([]byte, 512*1024)
+	if err := ioutil.WriteFile(filepath.Join(vol.Path(), "small"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := vol.(*localVolume).Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage < int64(len(data)) {
+		t.Fatalf("expected volume usage to be at least %d, got %d", len(data), usage)
+	}
+	if err := ioutil.WriteFile(filepath.Join(vol.Path(), "big"), append(data, data...), 0644); err == nil {
+		t.Fatal("expected writing past the size of the volume to fail")
+	}
+}
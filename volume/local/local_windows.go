@@ -14,6 +14,14 @@ import (
 
 type optsConfig struct{}
 
+func (o *optsConfig) needsMount() bool {
+	return true
+}
+
+func (o *optsConfig) hasOwnership() bool {
+	return false
+}
+
 var validOpts map[string]bool
 
 // scopedPath verifies that the path where the volume is located
@@ -32,6 +40,16 @@ func setOpts(v *localVolume, opts map[string]string) error {
 	return nil
 }
 
+func (r *Root) setQuota(v *localVolume) error {
+	return nil
+}
+
+func (r *Root) restoreQuota(v *localVolume) {}
+
+func (r *Root) setOwnership(v *localVolume) error {
+	return nil
+}
+
 func (v *localVolume) mount() error {
 	return nil
 }
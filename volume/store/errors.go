@@ -15,6 +15,8 @@ var (
 	errInvalidName = errors.New("volume name is not valid on this platform")
 	// errNameConflict is a typed error returned on create when a volume exists with the given name, but for a different driver
 	errNameConflict = errors.New("volume name must be unique")
+	// errVolumeExists is a typed error returned when creating a volume that must not exist yet
+	errVolumeExists = errors.New("volume already exists")
 )
 
 // OpErr is the error type returned by functions in the store package. It describes
@@ -64,6 +66,12 @@ func IsNameConflict(err error) bool {
 	return isErr(err, errNameConflict)
 }
 
+// IsExists returns a boolean indicating whether the error indicates that a
+// volume already exists
+func IsExists(err error) bool {
+	return isErr(err, errVolumeExists)
+}
+
 func isErr(err error, expected error) bool {
 	err = errors.Cause(err)
 	switch pe := err.(type) {
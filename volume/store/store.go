@@ -11,6 +11,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/boltdb/bolt"
+	"github.com/docker/docker/pkg/directory"
 	"github.com/docker/docker/pkg/locker"
 	"github.com/docker/docker/volume"
 	"github.com/docker/docker/volume/drivers"
@@ -52,6 +53,24 @@ func (v volumeWrapper) CachedPath() string {
 	return v.Volume.Path()
 }
 
+func (v volumeWrapper) KeepOwnership() bool {
+	if vv, ok := v.Volume.(interface {
+		KeepOwnership() bool
+	}); ok {
+		return vv.KeepOwnership()
+	}
+	return false
+}
+
+func (v volumeWrapper) Usage() (int64, error) {
+	if vv, ok := v.Volume.(interface {
+		Usage() (int64, error)
+	}); ok {
+		return vv.Usage()
+	}
+	return directory.Size(v.Volume.Path())
+}
+
 // New initializes a VolumeStore to keep
 // reference counting of volumes in the system.
 func New(rootPath string) (*VolumeStore, error) {
@@ -285,6 +304,29 @@ func (s *VolumeStore) CreateWithRef(name, driverName, ref string, opts, labels m
 	return v, nil
 }
 
+// CreateNewWithRef is like CreateWithRef, but it fails with an exists error
+// if a volume with the given name is already known to the store or to one of
+// the drivers, instead of returning the existing volume.
+func (s *VolumeStore) CreateNewWithRef(name, driverName, ref string, opts, labels map[string]string) (volume.Volume, error) {
+	name = normaliseVolumeName(name)
+	s.locks.Lock(name)
+	defer s.locks.Unlock(name)
+
+	if v, _ := s.getVolume(name); v != nil {
+		return nil, &OpErr{Err: errVolumeExists, Name: name, Op: "create"}
+	}
+	v, err := s.create(name, driverName, opts, labels)
+	if err != nil {
+		if _, ok := err.(*OpErr); ok {
+			return nil, err
+		}
+		return nil, &OpErr{Err: err, Name: name, Op: "create"}
+	}
+
+	s.setNamed(v, ref)
+	return v, nil
+}
+
 // Create creates a volume with the given name and driver.
 // This is just like CreateWithRef() except we don't store a reference while holding the lock.
 func (s *VolumeStore) Create(name, driverName string, opts, labels map[string]string) (volume.Volume, error) {
@@ -436,6 +478,25 @@ func (s *VolumeStore) GetWithRef(name, driverName, ref string) (volume.Volume, e
 	s.locks.Lock(name)
 	defer s.locks.Unlock(name)
 
+	return s.getWithRef(name, driverName, ref)
+}
+
+// GetUnusedWithRef is like GetWithRef, but the reference is only stored if
+// the volume has no other references. An in use error is returned otherwise.
+func (s *VolumeStore) GetUnusedWithRef(name, driverName, ref string) (volume.Volume, error) {
+	name = normaliseVolumeName(name)
+	s.locks.Lock(name)
+	defer s.locks.Unlock(name)
+
+	if s.hasRef(name) {
+		return nil, &OpErr{Err: errVolumeInUse, Name: name, Op: "get", Refs: s.getRefs(name)}
+	}
+	return s.getWithRef(name, driverName, ref)
+}
+
+// getWithRef gets a volume and stores the ref, it is called with the lock of
+// the volume name held.
+func (s *VolumeStore) getWithRef(name, driverName, ref string) (volume.Volume, error) {
 	vd, err := volumedrivers.GetDriver(driverName)
 	if err != nil {
 		return nil, &OpErr{Err: err, Name: name, Op: "get"}
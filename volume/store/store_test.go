@@ -232,3 +232,65 @@ func TestDerefMultipleOfSameRef(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGetUnusedWithRef(t *testing.T) {
+	volumedrivers.Register(volumetestutils.NewFakeDriver("fake"), "fake")
+	defer volumedrivers.Unregister("fake")
+	dir, err := ioutil.TempDir("", "test-get-unused-with-ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.CreateWithRef("fake1", "fake", "fake", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUnusedWithRef("fake1", "fake", "import"); !IsInUse(err) {
+		t.Fatalf("Expected in use error, got %v", err)
+	}
+
+	s.Dereference(v, "fake")
+	if _, err := s.GetUnusedWithRef("fake1", "fake", "import"); err != nil {
+		t.Fatal(err)
+	}
+	if refs := s.Refs(v); len(refs) != 1 || refs[0] != "import" {
+		t.Fatalf("Expected the import reference to be stored, got %v", refs)
+	}
+}
+
+func TestCreateNewWithRef(t *testing.T) {
+	volumedrivers.Register(volumetestutils.NewFakeDriver("fake"), "fake")
+	defer volumedrivers.Unregister("fake")
+	dir, err := ioutil.TempDir("", "test-create-new-with-ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.CreateNewWithRef("fake1", "fake", "clone", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs := s.Refs(v); len(refs) != 1 || refs[0] != "clone" {
+		t.Fatalf("Expected the clone reference to be stored, got %v", refs)
+	}
+
+	if _, err := s.CreateNewWithRef("fake1", "fake", "clone2", nil, nil); !IsExists(err) {
+		t.Fatalf("Expected exists error, got %v", err)
+	}
+	if refs := s.Refs(v); len(refs) != 1 {
+		t.Fatalf("Expected no reference to be added to the existing volume, got %v", refs)
+	}
+}